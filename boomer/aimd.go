@@ -0,0 +1,138 @@
+package boomer
+
+import (
+	"sync"
+	"time"
+)
+
+// controller adapts the offered rate while Boomer runs. The trigger loop
+// starts its schedule over, at scale times the configured rate and not
+// before until, every time epoch changes.
+type controller interface {
+	state(now time.Time) (epoch uint64, scale float64, until time.Time)
+}
+
+// AIMDStats describes how the AIMD controller moved the offered rate, all
+// rates are in requests per second.
+type AIMDStats struct {
+	Rate, Peak float64
+	// Decreases is how many windows had breaches.
+	Decreases int
+	// Capacity is the average rate of the windows with breaches, an
+	// estimate of what the target can sustain.
+	Capacity float64
+}
+
+// aimd increases the offered rate by step requests every window without
+// breaches and multiplies it by factor after windows with them, like TCP
+// congestion control does. A breach is a failure, a 429 response, or a
+// latency above the limit, if any.
+type aimd struct {
+	lock     sync.Mutex
+	step     float64
+	factor   float64
+	latency  time.Duration
+	window   time.Duration
+	base     float64
+	rate     float64
+	breached bool
+	last     time.Time
+	epoch    uint64
+	stats    AIMDStats
+	breaches float64
+}
+
+// WithAIMD makes Boomer search for the target's capacity, starting at the
+// configured rate limit it adds step requests per rate unit every unit
+// without breaches and multiplies the rate by factor (0 to 1) after every
+// unit with them. Responses slower than latency are breaches too, 0 only
+// counts failures and 429 responses. A step of 0 disables it.
+func (b *Boomer) WithAIMD(step, factor float64, latency time.Duration) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.aimd = nil
+	if step > 0 {
+		b.aimd = &aimd{step: step, factor: factor, latency: latency}
+	}
+	return b
+}
+
+// AIMDStats returns how the AIMD controller moved the rate so far, zero
+// when disabled.
+func (b *Boomer) AIMDStats() AIMDStats {
+	if b.aimd == nil {
+		return AIMDStats{}
+	}
+	b.aimd.lock.Lock()
+	defer b.aimd.lock.Unlock()
+	stats := b.aimd.stats
+	stats.Rate = b.aimd.perSecond(b.aimd.rate)
+	if stats.Decreases > 0 {
+		stats.Capacity = b.aimd.perSecond(b.aimd.breaches / float64(stats.Decreases))
+	}
+	return stats
+}
+
+func (c *aimd) init(now time.Time, n uint, window time.Duration) {
+	c.base = float64(n)
+	c.rate = c.base
+	c.window = window
+	c.last = now
+	c.stats.Peak = c.perSecond(c.rate)
+}
+
+func (c *aimd) perSecond(rate float64) float64 {
+	return rate * float64(time.Second) / float64(c.window)
+}
+
+func (c *aimd) record(res Result) {
+	breach := failed(res) || res.StatusCode == 429 ||
+		(c.latency > 0 && res.Duration > c.latency)
+	if !breach {
+		return
+	}
+	c.lock.Lock()
+	c.breached = true
+	c.lock.Unlock()
+}
+
+func (c *aimd) state(now time.Time) (uint64, float64, time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if now.Sub(c.last) >= c.window {
+		if c.breached {
+			c.stats.Decreases++
+			c.breaches += c.rate
+			c.rate *= c.factor
+			if c.rate < 1 {
+				c.rate = 1
+			}
+		} else {
+			c.rate += c.step
+			if rate := c.perSecond(c.rate); rate > c.stats.Peak {
+				c.stats.Peak = rate
+			}
+		}
+		c.breached = false
+		c.last = now
+		c.epoch++
+	}
+	return c.epoch, c.rate / c.base, time.Time{}
+}
+
+// reschedule returns when a schedule at scale times the configured rate
+// starts over, keeping the spacing from the last request, if any, and not
+// before until.
+func (b *Boomer) reschedule(now, last time.Time, scale float64, until time.Time) time.Time {
+	start := now
+	if !last.IsZero() && b.RateInterval > 0 {
+		if next := last.Add(time.Duration(float64(b.RateInterval) / scale)); next.After(start) {
+			start = next
+		}
+	}
+	if until.After(start) {
+		start = until
+	}
+	return start
+}
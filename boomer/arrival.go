@@ -0,0 +1,32 @@
+package boomer
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// WithVUArrival makes workers, virtual users, join the test gradually at
+// perSecond, instead of all at once, up to the concurrency, as real user
+// populations grow.
+func (b *Boomer) WithVUArrival(perSecond float64) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.VUArrivalRate = perSecond
+	return b
+}
+
+// arriveWorkers starts the workers one at a time at VUArrivalRate, until
+// all of them run or there is nothing left for them to send.
+func (b *Boomer) arriveWorkers(start time.Time) {
+	defer b.wg.Done()
+	var i uint
+	for i = 0; i < b.C; i++ {
+		arrival := start.Add(time.Duration(float64(i) * float64(time.Second) / b.VUArrivalRate))
+		if !b.waitUntil(arrival) || atomic.LoadInt32(&b.state) != stateRunning {
+			return
+		}
+		b.wg.Add(1)
+		go b.runWorker(int(i)+1, arrival)
+	}
+}
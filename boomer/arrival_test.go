@@ -0,0 +1,55 @@
+package boomer_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mercadolibre/pla/boomer"
+	"github.com/mercadolibre/pla/boomertest"
+	"github.com/valyala/fasthttp"
+)
+
+func TestVUArrival(t *testing.T) {
+	clock := newClock()
+	start := clock.Now()
+	var lock sync.Mutex
+	arrived := make(map[int]time.Duration)
+	b := boomer.NewBoomer("example.com:80", newRequest()).
+		WithAmount(6).
+		WithConcurrency(3).
+		WithRateLimit(2, time.Second).
+		WithVUArrival(1).
+		WithClock(clock).
+		WithDoer(&boomertest.Doer{}).
+		WithRequestHook(func(vu int, req *fasthttp.Request) {
+			lock.Lock()
+			defer lock.Unlock()
+			if _, ok := arrived[vu]; !ok {
+				arrived[vu] = clock.Now().Sub(start)
+			}
+		})
+	go func() {
+		for range b.Results() {
+		}
+	}()
+	b.Run()
+	for clock.Now().Before(start.Add(2500 * time.Millisecond)) {
+		for clock.Waiters() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		clock.Advance(500 * time.Millisecond)
+	}
+	b.Wait()
+	lock.Lock()
+	defer lock.Unlock()
+	// A worker joins every second.
+	for vu, at := range arrived {
+		if at < time.Duration(vu-1)*time.Second {
+			t.Errorf("Expected worker %d to join after %v, it sent a request at %v", vu, time.Duration(vu-1)*time.Second, at)
+		}
+	}
+	if _, ok := arrived[1]; !ok {
+		t.Errorf("Expected the first worker to send requests, found %v", arrived)
+	}
+}
@@ -0,0 +1,81 @@
+package boomer
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// backoff keeps track of consecutive connect failures per address, shared
+// by every dial function of Boomer.
+type backoff struct {
+	lock     sync.Mutex
+	failures map[string]uint
+	until    map[string]time.Time
+}
+
+// WithConnectBackoff makes Boomer wait before connecting again to an address
+// which failed consecutive connection attempts, starting at min and doubling
+// up to max, instead of generating errors as fast as possible. 0 disables it.
+func (b *Boomer) WithConnectBackoff(min, max time.Duration) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	if max < min {
+		max = min
+	}
+	b.BackoffMin = min
+	b.BackoffMax = max
+	return b
+}
+
+func newBackoff() backoff {
+	return backoff{failures: make(map[string]uint), until: make(map[string]time.Time)}
+}
+
+// dialBackoff wraps dial so it backs off addresses failing to connect.
+func (b *Boomer) dialBackoff(dial func(addr string) (net.Conn, error)) func(addr string) (net.Conn, error) {
+	return func(addr string) (net.Conn, error) {
+		b.backoff.lock.Lock()
+		wait := b.backoff.until[addr].Sub(b.clock.Now())
+		b.backoff.lock.Unlock()
+		if wait > 0 {
+			timer := b.clock.NewTimer(wait)
+			select {
+			case <-b.stop:
+			case <-timer.Chan():
+			}
+			timer.Stop()
+		}
+
+		conn, err := dial(addr)
+
+		b.backoff.lock.Lock()
+		defer b.backoff.lock.Unlock()
+		if err == nil {
+			delete(b.backoff.failures, addr)
+			delete(b.backoff.until, addr)
+			return conn, nil
+		}
+		if b.clock.Now().Before(b.backoff.until[addr]) {
+			// Another worker already backed off this address.
+			return nil, err
+		}
+		n := b.backoff.failures[addr]
+		b.backoff.failures[addr] = n + 1
+		d := b.BackoffMax
+		if n < 32 && b.BackoffMin<<n < b.BackoffMax {
+			d = b.BackoffMin << n
+		}
+		b.backoff.until[addr] = b.clock.Now().Add(d)
+		b.dials.recordBackoff(d)
+		return nil, err
+	}
+}
+
+func (r *dialRecorder) recordBackoff(d time.Duration) {
+	r.lock.Lock()
+	r.stats.Backoffs++
+	r.stats.BackoffTime += d
+	r.lock.Unlock()
+}
@@ -0,0 +1,30 @@
+package boomer
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestConnectBackoff(t *testing.T) {
+	b := NewBoomer("127.0.0.1:1", nil).
+		WithConnectBackoff(20*time.Millisecond, 40*time.Millisecond)
+	var attempts []time.Time
+	dial := b.dialBackoff(func(addr string) (net.Conn, error) {
+		attempts = append(attempts, time.Now())
+		return nil, errors.New("connection refused")
+	})
+	for i := 0; i < 4; i++ {
+		dial("127.0.0.1:1")
+	}
+	// Waits are 20ms, 40ms and then capped at 40ms.
+	for i, min := range []time.Duration{20, 40, 40} {
+		if d := attempts[i+1].Sub(attempts[i]); d < min*time.Millisecond {
+			t.Errorf("Expected attempt %d to wait at least %vms, waited %v", i+2, min, d)
+		}
+	}
+	if stats := b.DialStats(); stats.Backoffs != 4 {
+		t.Errorf("Expected 4 backoffs, found %d", stats.Backoffs)
+	}
+}
@@ -13,18 +13,24 @@
 // limitations under the License.
 
 // Package boomer provides commands to run load tests and display results.
+//
+// Every Boomer has its own clients, connections and statistics, so several
+// of them can run at the same time in a single process, against different
+// targets or with different settings.
 package boomer
 
 import (
 	"crypto/tls"
+	"fmt"
 	"math"
+	"math/rand"
 	"net"
+	"net/http"
 	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/Clever/leakybucket"
-	"github.com/Clever/leakybucket/memory"
 	"github.com/valyala/fasthttp"
 )
 
@@ -34,8 +40,98 @@ type Result struct {
 	StatusCode    int
 	Duration      time.Duration
 	ContentLength int
+
+	// Label is the label of the mixed request which produced this result.
+	Label string
+
+	// Scenario is the name of the scenario of the request, Step its
+	// position in it, starting at 1, and OverBudget tells whether it took
+	// longer than its Budget. They are only set in scenario mode.
+	Scenario   string
+	Step       int
+	OverBudget bool
+
+	// Transaction is the duration of a whole scenario iteration, and
+	// TransactionFailed whether any of its steps failed, only set on the
+	// result of its last step.
+	Transaction       time.Duration
+	TransactionFailed bool
+
+	// Events and FirstEvent are only set in SSE mode, Duration is then the
+	// lifetime of the stream.
+	Events     int
+	FirstEvent time.Duration
+
+	// FirstByte is only set in streaming mode.
+	FirstByte time.Duration
+
+	// Backend is the backend which served the request and AffinityBroken
+	// tells whether it differs from the one which served the previous
+	// request of the same worker, only set when tracking affinity.
+	Backend        string
+	AffinityBroken bool
+
+	// Addr is the resolved address which served the request, only set in
+	// DNS fan-out mode.
+	Addr string
+
+	// Attempts is the amount of times the request was sent, more than one
+	// when it was retried.
+	Attempts int
+
+	// Start is when the request was sent.
+	Start time.Time
+
+	// RequestSize is the length of the request body.
+	RequestSize int
+
+	// Lag is how late the request was sent compared to when the rate limit
+	// scheduled it, negative when early, only set when rate limited.
+	Lag time.Duration
+
+	// Header holds the raw response headers, only set when KeepHeaders is.
+	Header []byte
+
+	// Tenant is the value of the TenantHeader of the request, when set.
+	Tenant string
+
+	// CacheStatus tells whether the response was a cache hit, only set
+	// when CacheStatus is.
+	CacheStatus string
+
+	// Validated tells whether the request carried the validators of a
+	// previous response, only set when Conditional is.
+	Validated bool
+
+	// Preflight is the latency of the CORS preflight sent before the
+	// request, if any, PreflightErr why it failed.
+	Preflight    time.Duration
+	PreflightErr error
+
+	// ServerTimings are the phases the server reported in Server-Timing
+	// headers, if any.
+	ServerTimings []ServerTiming
+
+	// TraceID is the trace the request started, only set when
+	// TracePropagation is.
+	TraceID string
+
+	// Shadow is how the shadow target responded, only set in shadow mode.
+	Shadow *ShadowResult
 }
 
+// Assertion validates a response, returning an error marks the request as
+// failed. Errors are grouped by message, so it should not vary per request.
+type Assertion func(resp *fasthttp.Response) error
+
+// RequestHook modifies a request right before it is sent. vu identifies the
+// virtual user sending it, which is stable per worker and starts at 1.
+type RequestHook func(vu int, req *fasthttp.Request)
+
+// RequestFilter is a RequestHook which can fail the request, returning an
+// error reports it as the result of the request without sending it.
+type RequestFilter func(vu int, req *fasthttp.Request) error
+
 // Boomer is the structure responsible for performing requests.
 type Boomer struct {
 	// Request is the request to be made.
@@ -60,14 +156,185 @@ type Boomer struct {
 	// Duration is the amount of time the test should run.
 	Duration time.Duration
 
-	bucket   leakybucket.Bucket
+	// RateInterval is the intended time between requests when rate
+	// limited, 0 otherwise. Pacing determines how they are spread, and
+	// RateBurst how many can be sent at once. With RatePerWorker it is
+	// the interval of each worker instead of the aggregate one.
+	RateInterval  time.Duration
+	Pacing        Pacing
+	RateBurst     uint
+	RatePerWorker bool
+
+	// Pipeline is the amount of requests in flight per connection when
+	// HTTP pipelining is enabled, 0 disables pipelining.
+	Pipeline uint
+
+	// SSE makes every request open a Server-Sent Events stream which is
+	// consumed until the server closes it or the test finishes.
+	SSE bool
+
+	// Stream makes response bodies be consumed as they arrive instead of
+	// being buffered, so time to first byte can be told apart.
+	Stream bool
+
+	// AffinityCookie and AffinityHeader name the cookie or header used to
+	// track which backend serves each worker.
+	AffinityCookie string
+	AffinityHeader string
+
+	// DNSFanout spreads requests across every address the host resolves
+	// to, which are resolved again every DNSRefresh.
+	DNSFanout  bool
+	DNSRefresh time.Duration
+
+	// HappyEyeballs races IPv6 and IPv4 connection attempts.
+	HappyEyeballs bool
+
+	// ProxyProtocol is the version of the PROXY protocol header sent on
+	// every new connection, 0 disables it. ProxySources are the source
+	// addresses announced in it.
+	ProxyProtocol int
+	ProxySources  []*net.TCPAddr
+
+	// BackoffMin and BackoffMax bound the wait before connecting again to
+	// an address after consecutive connect failures, 0 disables it.
+	BackoffMin time.Duration
+	BackoffMax time.Duration
+
+	// SimulatedLatency and SimulatedJitter delay every write to the target.
+	SimulatedLatency time.Duration
+	SimulatedJitter  time.Duration
+
+	// Retries is the amount of times failed requests are sent again, with
+	// the same key in the IdempotencyHeader when set.
+	Retries           uint
+	IdempotencyHeader string
+
+	// CurvePreferences are the curves offered on TLS handshakes, nil
+	// offers the defaults.
+	CurvePreferences []tls.CurveID
+
+	// TLSConfig is the base of every TLS configuration, Dialer replaces
+	// TCP dialing and ClientOptions tune the HTTP clients.
+	TLSConfig     *tls.Config
+	Dialer        func(addr string) (net.Conn, error)
+	ClientOptions ClientOptions
+
+	// ConnLifetime and RequestsPerConn deliberately cycle connections after
+	// they have been open for a while or served some amount of requests.
+	ConnLifetime    time.Duration
+	RequestsPerConn uint
+
+	// KeepHeaders makes results carry the raw response headers.
+	KeepHeaders bool
+
+	// TenantHeader is the request header results take their Tenant from.
+	TenantHeader string
+
+	// CacheStatus makes results report whether responses were cache hits.
+	CacheStatus bool
+
+	// Conditional makes workers send back the validators of responses.
+	Conditional bool
+
+	// TracePropagation makes every request start a trace.
+	TracePropagation bool
+
+	// IterationPacing is how often every worker starts an iteration of the
+	// scenario, 0 starts them back to back.
+	IterationPacing time.Duration
+
+	// VUArrivalRate is how many workers join the test per second, 0 starts
+	// them all at once.
+	VUArrivalRate float64
+
+	// ResultsPolicy determines what workers do with results the consumer
+	// is not ready to receive.
+	ResultsPolicy ResultsPolicy
+
+	// ResultsBuffer is how many results Results buffers, set with
+	// WithResultsBuffer, 0 buffers one per worker.
+	ResultsBuffer uint
+
+	// ShadowAddr, when set, receives a copy of every request with
+	// ShadowHost as its Host header, ShadowBodies compares response bodies.
+	ShadowAddr   string
+	ShadowHost   string
+	ShadowBodies bool
+
+	assertions []Assertion
+	hooks      []RequestFilter
+
+	mix      []*WeightedRequest
+	mixTotal uint
+	rand     *rand.Rand
+	seed     int64
+	ready    []bool
+	allowed  []time.Time
+
+	scenariosTotal uint
+
+	rateN      uint
+	rateWindow time.Duration
+
+	sent    uint64
+	started atomic.Value
+	state   int32
+	dropped uint64
+	blocked int64
+	spill   *spill
+	panics  uint64
+
 	results  chan Result
 	stop     chan struct{}
 	stopLock sync.Mutex
-	jobs     chan *fasthttp.Request
+	jobs     chan job
 	running  bool
 	wg       *sync.WaitGroup
-	client   *fasthttp.HostClient
+	client   Doer
+	clock    Clock
+
+	streamClient *http.Client
+	endpoints    atomic.Value
+	endpointSeq  uint64
+	dials        dialRecorder
+	conns        connTracker
+	live         live
+	backoff      backoff
+	breaker      *breaker
+	quota        *quota
+	aimd         *aimd
+	control      controller
+	trace        *trace
+	shadowClient Doer
+	samples      *samples
+	capture      *capture
+	lifecycle    *lifecycle
+	preflight    *preflight
+	scenarios    []*Scenario
+	factory      RequestFactory
+	prepared     map[string]chan preparedConn
+	proxySeq     uint64
+	tlsInfo      *TLSInfo
+	tlsErr       error
+}
+
+// job is a request for a worker to send, or an iteration of a scenario,
+// due is when the rate limit scheduled it. step is the position of w in the
+// iteration it belongs to, if any.
+type job struct {
+	w         *WeightedRequest
+	due       time.Time
+	scenario  *Scenario
+	step      int
+	iteration *iteration
+}
+
+// Doer sends requests, it is implemented by both fasthttp.HostClient and
+// fasthttp.PipelineClient.
+type Doer interface {
+	Do(req *fasthttp.Request, resp *fasthttp.Response) error
+	DoTimeout(req *fasthttp.Request, resp *fasthttp.Response, timeout time.Duration) error
 }
 
 // NewBoomer returns a new instance of Boomer for the specified request.
@@ -78,8 +345,10 @@ func NewBoomer(addr string, req *fasthttp.Request) *Boomer {
 		Request: req,
 		results: make(chan Result),
 		stop:    make(chan struct{}),
-		jobs:    make(chan *fasthttp.Request),
+		clock:   realClock{},
+		jobs:    make(chan job),
 		wg:      &sync.WaitGroup{},
+		backoff: newBackoff(),
 	}
 }
 
@@ -112,8 +381,9 @@ func (b *Boomer) WithDuration(d time.Duration) *Boomer {
 
 // WithRateLimit configures Boomer to never overpass a certain rate.
 func (b *Boomer) WithRateLimit(n uint, rate time.Duration) *Boomer {
+	b.rateN, b.rateWindow, b.RateInterval = n, rate, 0
 	if n > 0 {
-		b.bucket, _ = memory.New().Create("pla", n-1, rate)
+		b.RateInterval = rate / time.Duration(n)
 	}
 	return b
 }
@@ -128,7 +398,9 @@ func (b *Boomer) WithConcurrency(c uint) *Boomer {
 		c = uint(runtime.NumCPU())
 	}
 	b.C = c
-	b.results = make(chan Result, c)
+	if b.ResultsBuffer == 0 {
+		b.results = make(chan Result, c)
+	}
 	return b
 }
 
@@ -142,6 +414,90 @@ func (b *Boomer) WithAbortionOnFailure(f bool) *Boomer {
 	return b
 }
 
+// WithPipelining enables HTTP/1.1 pipelining, keeping up to n requests in
+// flight per connection. Latencies measured this way include the time spent
+// queued behind other requests of the same connection.
+func (b *Boomer) WithPipelining(n uint) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.Pipeline = n
+	return b
+}
+
+// WithAssertion adds an assertion every response must pass.
+func (b *Boomer) WithAssertion(a Assertion) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.assertions = append(b.assertions, a)
+	return b
+}
+
+// WithRequestHook adds a hook called on every request before it is sent.
+func (b *Boomer) WithRequestHook(h RequestHook) *Boomer {
+	return b.WithRequestFilter(func(vu int, req *fasthttp.Request) error {
+		h(vu, req)
+		return nil
+	})
+}
+
+// WithRequestFilter adds a filter called on every request before it is
+// sent, in the same order as hooks.
+func (b *Boomer) WithRequestFilter(f RequestFilter) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.hooks = append(b.hooks, f)
+	return b
+}
+
+// WithConnectionChurn closes connections once they have been open for
+// lifetime, or after they served requests requests, simulating a population
+// of clients instead of a few long lived connections. 0 disables either.
+// Requests are counted per worker, not per connection, so a connection
+// closed early by the server serves fewer. They are not counted with
+// pipelining, which shares connections among workers.
+func (b *Boomer) WithConnectionChurn(lifetime time.Duration, requests uint) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.ConnLifetime = lifetime
+	b.RequestsPerConn = requests
+	return b
+}
+
+// WithDoer makes Boomer send requests with d instead of connecting to Addr,
+// which is useful to test code built on Boomer without a network. SSE and
+// streaming modes don't use it.
+func (b *Boomer) WithDoer(d Doer) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.client = d
+	return b
+}
+
+// WithResponseHeaders makes results carry the raw response headers, for
+// investigating individual requests.
+func (b *Boomer) WithResponseHeaders(keep bool) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.KeepHeaders = keep
+	return b
+}
+
+// WithTenantHeader makes results carry the value of the request header
+// name, which identifies the tenant of multi-tenant tests.
+func (b *Boomer) WithTenantHeader(name string) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.TenantHeader = name
+	return b
+}
+
 // Results returns receive-only channel of results
 func (b *Boomer) Results() <-chan Result {
 	return b.results
@@ -162,6 +518,11 @@ func (b *Boomer) Stop() {
 // Wait blocks until Boomer successfully finished or is fully stopped
 func (b *Boomer) Wait() {
 	b.wg.Wait()
+	b.runTeardown()
+	if b.spill != nil {
+		b.spill.finish()
+	}
+	atomic.StoreInt32(&b.state, stateDone)
 	close(b.results)
 }
 
@@ -171,110 +532,448 @@ func (b *Boomer) Run() {
 	if b.running {
 		return
 	}
-	b.client = &fasthttp.HostClient{
-		Addr: b.Addr,
-		Dial: func(addr string) (net.Conn, error) {
-			return fasthttp.DialTimeout(addr, b.ConnectTimeout)
-		},
-		TLSConfig: &tls.Config{
-			InsecureSkipVerify: true,
-		},
-		MaxConns:     math.MaxInt32,
-		ReadTimeout:  b.ReadTimeout,
-		WriteTimeout: b.WriteTimeout,
+	if b.TLS() && b.client == nil {
+		b.probeTLS()
+	}
+	switch {
+	case b.SSE || b.Stream:
+		b.streamClient = b.newStreamClient()
+	case b.client != nil:
+		// Set with WithDoer.
+	case b.DNSFanout:
+		b.initFanout()
+	default:
+		b.client = b.newClient(b.Addr, "")
+	}
+	b.initMix()
+	if b.ShadowAddr != "" {
+		b.initShadow()
+	}
+	if b.ResultsPolicy == ResultsSpill {
+		b.initSpill()
+	}
+	switch {
+	case b.aimd != nil && b.rateN > 0:
+		b.aimd.init(b.clock.Now(), b.rateN, b.rateWindow)
+		b.control = b.aimd
+	case b.quota != nil:
+		b.quota.init(b.rateWindow)
+		b.control = b.quota
+	}
+	if !b.runSetup() {
+		return
 	}
 	b.running = true
+	// Kept as a time.Time for its monotonic reading, so elapsed times
+	// aren't thrown off by wall clock adjustments.
+	b.started.Store(b.clock.Now())
+	atomic.StoreInt32(&b.state, stateRunning)
 	if b.Duration > 0 {
-		time.AfterFunc(b.Duration, func() {
+		// Wait on the clock right away, so advancing a fake one right after
+		// Run already counts.
+		end := b.clock.After(b.Duration)
+		go func() {
+			<-end
 			b.Stop()
-		})
+		}()
 	}
 	b.runWorkers()
 }
 
-func (b *Boomer) runWorkers() {
-	b.wg.Add(int(b.C))
+// newClient returns a client for addr, serverName is used for TLS when addr
+// is a resolved address of the target.
+func (b *Boomer) newClient(addr, serverName string) Doer {
+	dial := b.dialer()
+	tlsConfig := b.newTLSConfig(serverName)
+	isTLS := b.TLS()
+	if b.prepared != nil {
+		// Connections are handshaked by the dialer, so prepared and new
+		// ones look the same to the client.
+		if serverName == "" {
+			serverName, _, _ = net.SplitHostPort(b.Addr)
+		}
+		dial = b.dialPrepared(b.dialTLS(dial, serverName))
+		isTLS = false
+	}
+	if b.Pipeline > 0 {
+		// Spread the workers over enough connections so that each one
+		// carries at most Pipeline requests at a time.
+		conns := (b.C + b.Pipeline - 1) / b.Pipeline
+		return &fasthttp.PipelineClient{
+			Addr:                addr,
+			Dial:                dial,
+			IsTLS:               isTLS,
+			TLSConfig:           tlsConfig,
+			MaxConns:            int(conns),
+			MaxPendingRequests:  int(b.C),
+			MaxIdleConnDuration: b.ClientOptions.MaxIdleConnDuration,
+			ReadBufferSize:      b.ClientOptions.ReadBufferSize,
+			WriteBufferSize:     b.ClientOptions.WriteBufferSize,
+			ReadTimeout:         b.ReadTimeout,
+			WriteTimeout:        b.WriteTimeout,
+		}
+	}
+	return &fasthttp.HostClient{
+		Addr:                          addr,
+		Name:                          b.ClientOptions.Name,
+		Dial:                          dial,
+		IsTLS:                         isTLS,
+		TLSConfig:                     tlsConfig,
+		MaxConns:                      math.MaxInt32,
+		MaxConnDuration:               b.ConnLifetime,
+		MaxIdleConnDuration:           b.ClientOptions.MaxIdleConnDuration,
+		ReadBufferSize:                b.ClientOptions.ReadBufferSize,
+		WriteBufferSize:               b.ClientOptions.WriteBufferSize,
+		MaxResponseBodySize:           b.ClientOptions.MaxResponseBodySize,
+		DisableHeaderNamesNormalizing: b.ClientOptions.DisableHeaderNamesNormalizing,
+		ReadTimeout:                   b.ReadTimeout,
+		WriteTimeout:                  b.WriteTimeout,
+	}
+}
 
-	var i uint
-	for i = 0; i < b.C; i++ {
-		go b.runWorker()
+// dialer returns the function every client opens connections with.
+func (b *Boomer) dialer() func(addr string) (net.Conn, error) {
+	dial := func(addr string) (net.Conn, error) {
+		return fasthttp.DialTimeout(addr, b.ConnectTimeout)
+	}
+	switch {
+	case b.Dialer != nil:
+		dial = b.Dialer
+	case b.HappyEyeballs:
+		dial = b.dialDualStack
+	}
+	if b.ProxyProtocol > 0 {
+		dial = b.dialProxyProtocol(dial)
+	}
+	if b.BackoffMin > 0 {
+		dial = b.dialBackoff(dial)
+	}
+	if b.SimulatedLatency > 0 || b.SimulatedJitter > 0 {
+		dial = b.dialLatency(dial)
+	}
+	return b.dialTracked(dial)
+}
+
+func (b *Boomer) runWorkers() {
+	start := b.clock.Now()
+	if b.VUArrivalRate > 0 {
+		b.wg.Add(1)
+		go b.arriveWorkers(start)
+	} else {
+		b.wg.Add(int(b.C))
+		var i uint
+		for i = 0; i < b.C; i++ {
+			go b.runWorker(int(i)+1, start)
+		}
 	}
 
 	b.wg.Add(1)
 	go b.triggerLoop()
 }
 
-func (b *Boomer) runWorker() {
+func (b *Boomer) runWorker(vu int, start time.Time) {
+	factory := b.requestFactory()
 	resp := fasthttp.AcquireResponse()
-	req := fasthttp.AcquireRequest()
-	for r := range b.jobs {
-		req.Reset()
-		resp.Reset()
-		r.CopyTo(req)
-		s := time.Now()
-
-		var code int
-		var size int
-
-		var err error
-		if b.Timeout > 0 {
-			err = b.client.DoTimeout(req, resp, b.Timeout)
-		} else {
-			err = b.client.Do(req, resp)
+	var sess session
+	var n uint
+	for j := range b.jobs {
+		switch {
+		case b.RatePerWorker && b.RateInterval > 0:
+			j.due = b.workerDue(start, vu, n)
+			n++
+			if !b.waitUntil(j.due) {
+				continue
+			}
+		case j.scenario != nil && b.IterationPacing > 0:
+			j.due = b.iterationDue(start, b.clock.Now(), vu, &n)
+			if !b.waitUntil(j.due) {
+				continue
+			}
 		}
-		if err == nil {
-			size = resp.Header.ContentLength()
-			code = resp.Header.StatusCode()
+		run := b.runJob
+		if j.scenario != nil {
+			run = b.runIteration
+		}
+		if !run(vu, j, factory, resp, &sess) {
+			// A panic may leave them inconsistent, start over with new ones
+			// so the pool stays at full strength.
+			resp = fasthttp.AcquireResponse()
+			sess = session{}
 		}
-
-		b.notifyResult(code, size, err, time.Now().Sub(s))
 	}
 	fasthttp.ReleaseResponse(resp)
-	fasthttp.ReleaseRequest(req)
 	b.wg.Done()
 }
 
-func (b *Boomer) notifyResult(code int, size int, err error, d time.Duration) {
-	b.results <- Result{
+// runJob sends the request of j and notifies its result. A panic, in a hook
+// or the client, is reported as the result of the request and makes it
+// return false.
+func (b *Boomer) runJob(vu int, j job, factory RequestFactory, resp *fasthttp.Response, sess *session) (ok bool) {
+	w := j.w
+	start := b.clock.Now()
+	// admitted tells whether the breaker let the request through. Its
+	// outcome is then recorded even on a panic, or a half-open breaker
+	// would wait for its probe forever.
+	var admitted bool
+	defer func() {
+		if r := recover(); r != nil {
+			atomic.AddUint64(&b.panics, 1)
+			if admitted {
+				b.breaker.record(b.clock.Now(), true)
+			}
+			b.notifyUnsent(vu, j, start, fmt.Errorf("worker panic: %v", r))
+		}
+	}()
+	req := factory.New(vu, w)
+	defer factory.Release(req)
+	if w.Prepare != nil {
+		if err := w.Prepare(req); err != nil {
+			b.notifyUnsent(vu, j, start, err)
+			return true
+		}
+	}
+	if b.preflight != nil {
+		req.Header.Set("Origin", b.preflight.origin)
+	}
+	var traceID string
+	if b.TracePropagation {
+		traceID = propagateTrace(req)
+	}
+	for _, h := range b.hooks {
+		if err := h(vu, req); err != nil {
+			b.notifyUnsent(vu, j, start, err)
+			return true
+		}
+	}
+	var res Result
+	start = b.clock.Now()
+	if b.breaker != nil {
+		if !b.breaker.allow(start) {
+			b.notifyUnsent(vu, j, start, ErrCircuitOpen)
+			return true
+		}
+		admitted = true
+	}
+	switch {
+	case b.SSE:
+		res = b.doSSE(req)
+	case b.Stream:
+		res = b.doStream(req)
+	default:
+		var pre time.Duration
+		var preErr error
+		if b.preflight != nil && b.preflight.due() {
+			pre, preErr = b.preflight.send(b, req, resp)
+		}
+		sess.prepare(b, req)
+		var shadow <-chan shadowReply
+		if b.shadowClient != nil {
+			shadow = b.startShadow(req)
+		}
+		res = b.doWithRetries(req, resp)
+		res.Preflight, res.PreflightErr = pre, preErr
+		if shadow != nil {
+			res.Shadow = b.finishShadow(shadow, res, resp)
+		}
+		if b.quota != nil && res.StatusCode != 0 {
+			b.quota.record(b.clock.Now(), resp)
+		}
+		if res.Err == nil {
+			sess.observe(b, req, resp, &res)
+			if w.Capture != nil {
+				w.Capture(req, resp)
+			}
+		}
+		if b.capture != nil {
+			b.capture.save(req, resp, res)
+		}
+		if b.samples != nil && res.StatusCode != 0 && failed(res) {
+			b.samples.save(req, resp, res)
+		}
+		if b.KeepHeaders && res.StatusCode != 0 {
+			res.Header = append([]byte(nil), resp.Header.Header()...)
+		}
+		if b.CacheStatus && res.StatusCode != 0 {
+			res.CacheStatus = cacheStatus(resp)
+		}
+		if res.StatusCode != 0 {
+			res.ServerTimings = serverTimings(resp)
+		}
+	}
+	res.Label = w.Label
+	res.TraceID = traceID
+	res.Step = j.step
+	if j.scenario != nil {
+		res.Scenario = j.scenario.Name
+	}
+	res.Start = start
+	res.OverBudget = w.Budget > 0 && res.Err == nil && res.Duration > w.Budget
+	if j.iteration != nil {
+		j.iteration.record(b, w, j.step, &res)
+	}
+	res.RequestSize = len(req.Body())
+	if b.TenantHeader != "" {
+		res.Tenant = string(req.Header.Peek(b.TenantHeader))
+	}
+	if !j.due.IsZero() {
+		res.Lag = start.Sub(j.due)
+	}
+	if b.breaker != nil {
+		admitted = false
+		b.breaker.record(b.clock.Now(), failed(res))
+	}
+	if b.aimd != nil {
+		b.aimd.record(res)
+	}
+	b.notifyResult(vu, res)
+	return true
+}
+
+// notifyUnsent reports the request worker vu made for j as failed with err
+// without being sent. Its iteration, if any, moves on as with any other
+// failed step.
+func (b *Boomer) notifyUnsent(vu int, j job, start time.Time, err error) {
+	res := Result{Err: err, Label: j.w.Label, Step: j.step, Start: start}
+	if j.iteration != nil {
+		j.iteration.record(b, j.w, j.step, &res)
+	}
+	b.notifyResult(vu, res)
+}
+
+// WorkerPanics returns the amount of requests whose worker panicked.
+func (b *Boomer) WorkerPanics() uint64 {
+	return atomic.LoadUint64(&b.panics)
+}
+
+func (b *Boomer) do(req *fasthttp.Request, resp *fasthttp.Response) Result {
+	resp.Reset()
+	c, addr := b.client, ""
+	if b.DNSFanout && b.endpoints.Load() != nil {
+		e := b.nextEndpoint()
+		c, addr = e.client, e.addr
+	}
+	s := b.clock.Now()
+
+	var code int
+	var size int
+
+	var err error
+	if b.Timeout > 0 {
+		err = c.DoTimeout(req, resp, b.Timeout)
+	} else {
+		err = c.Do(req, resp)
+	}
+	if err == nil {
+		size = resp.Header.ContentLength()
+		if size < 0 {
+			// Chunked, the body was read as sent.
+			size = len(resp.Body())
+		}
+		code = resp.Header.StatusCode()
+		err = b.assert(resp)
+	}
+
+	return Result{
 		StatusCode:    code,
-		Duration:      d,
+		Duration:      b.clock.Now().Sub(s),
 		Err:           err,
 		ContentLength: size,
+		Addr:          addr,
 	}
+}
+
+func (b *Boomer) assert(resp *fasthttp.Response) error {
+	for _, a := range b.assertions {
+		if err := a(resp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ResponseBody returns the body of resp, decoded according to its
+// Content-Encoding.
+func ResponseBody(resp *fasthttp.Response) ([]byte, error) {
+	switch string(resp.Header.Peek("Content-Encoding")) {
+	case "gzip":
+		return resp.BodyGunzip()
+	case "deflate":
+		return resp.BodyInflate()
+	default:
+		return resp.Body(), nil
+	}
+}
+
+func (b *Boomer) notifyResult(vu int, res Result) {
+	b.live.record(vu, res)
+	b.deliver(res)
 
 	//If any request gets a 5xx status code or conn reset error, and user has specified F flag, pla execution is stopped
-	//Why 5xx? Because it is not considered as an application business error
-	if (code >= 500 || err != nil) && b.F {
+	if failed(res) && b.F {
 		b.Stop()
 	}
 }
 
-func (b *Boomer) checkRateLimit() error {
-	if b.bucket == nil {
-		return nil
-	}
-	_, err := b.bucket.Add(1)
-	return err
+// failed reports whether res is a failure of the target.
+// Why 5xx? Because it is not considered as an application business error
+func failed(res Result) bool {
+	return res.StatusCode >= 500 || res.Err != nil
 }
 
 func (b *Boomer) triggerLoop() {
 	defer b.wg.Done()
 	defer close(b.jobs)
+	defer atomic.CompareAndSwapInt32(&b.state, stateRunning, stateDraining)
 
-	var i uint
+	var i, sent uint
+	var epoch uint64
+	var last time.Time
+	scale := 1.0
+	start := b.clock.Now()
 	for {
-		if b.Duration == 0 && i >= b.N {
+		if b.Duration == 0 && sent >= b.N {
+			return
+		}
+		if b.trace != nil && !b.trace.within(i) {
 			return
 		}
+		if b.control != nil {
+			// Start the schedule over whenever the controller changes it.
+			now := b.clock.Now()
+			if e, s, until := b.control.state(now); e != epoch {
+				epoch, scale, i = e, s, 0
+				start = b.reschedule(now, last, scale, until)
+			}
+		}
+		due := b.due(start, i)
+		if scale != 1 && !due.IsZero() {
+			due = start.Add(time.Duration(float64(due.Sub(start)) / scale))
+		}
+		if due.IsZero() && start.After(b.clock.Now()) {
+			due = start
+		}
+		if !due.IsZero() && !b.waitUntil(due) {
+			return
+		}
+		if floor := b.burstFloor(); !due.IsZero() && due.Before(floor) {
+			// Too far behind, let the rest of the schedule slip.
+			start = start.Add(floor.Sub(due))
+			due = floor
+		}
+		j := job{due: due, scenario: b.nextScenario()}
+		if j.scenario == nil {
+			if j.w = b.nextRequest(); j.w == nil {
+				return
+			}
+		}
 		select {
 		case <-b.stop:
 			return
-		case b.jobs <- b.Request:
+		case b.jobs <- j:
 			i++
-			err := b.checkRateLimit()
-			if err != nil {
-				time.Sleep(b.bucket.Reset().Sub(time.Now()))
-			}
+			sent++
+			last = due
+			atomic.AddUint64(&b.sent, 1)
 		}
 	}
 }
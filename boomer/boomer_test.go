@@ -15,10 +15,17 @@
 package boomer
 
 import (
+	"crypto/tls"
 	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -52,37 +59,249 @@ func TestN(t *testing.T) {
 	}
 }
 
-func TestQPS(t *testing.T) {
-	var wg sync.WaitGroup
-	var count int64
-	handler := func(w http.ResponseWriter, r *http.Request) {
-		atomic.AddInt64(&count, int64(1))
-	}
-	server := httptest.NewServer(http.HandlerFunc(handler))
+func TestScheduleLag(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
 	defer server.Close()
 
 	req := fasthttp.AcquireRequest()
 	req.SetRequestURI(server.URL)
-	req.Header.SetMethod("GET")
 	boomer := NewBoomer(string(req.Host()), req).
-		WithAmount(20).
-		WithConcurrency(2).
-		WithRateLimit(1, time.Second)
+		WithAmount(5).
+		WithConcurrency(1).
+		WithRateLimit(20, time.Second)
+	if boomer.RateInterval != 50*time.Millisecond {
+		t.Errorf("Expected a rate interval of 50ms, found %v", boomer.RateInterval)
+	}
+	var lagged int
+	done := make(chan struct{})
 	go func() {
-		for range boomer.Results() {
+		for res := range boomer.Results() {
+			if res.Lag < -time.Second || res.Lag > time.Second {
+				t.Errorf("Expected lags within a second of schedule, found %v", res.Lag)
+			}
+			if res.Lag != 0 {
+				lagged++
+			}
 		}
+		close(done)
 	}()
-	wg.Add(1)
-	time.AfterFunc(time.Second, func() {
-		if atomic.LoadInt64(&count) > 1 {
-			t.Errorf("Expected to boom 1 times, found %d", atomic.LoadInt64(&count))
+	boomer.Run()
+	boomer.Wait()
+	<-done
+	if lagged == 0 {
+		t.Errorf("Expected results to carry their scheduling lag")
+	}
+}
+
+func TestConnStats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	boomer := NewBoomer(string(req.Host()), req).
+		WithAmount(20).
+		WithConcurrency(1)
+	boomer.Run()
+	boomer.Wait()
+	stats := boomer.ConnStats()
+	if stats.Opened != 1 {
+		t.Errorf("Expected a single connection, found %d", stats.Opened)
+	}
+	if stats.Requests != 20 {
+		t.Errorf("Expected 20 requests over the connection, found %d", stats.Requests)
+	}
+}
+
+// timeoutConn fails every read with a timeout.
+type timeoutConn struct {
+	net.Conn
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func (timeoutConn) Read(p []byte) (int, error) {
+	return 0, timeoutError{}
+}
+
+func (timeoutConn) Close() error {
+	return nil
+}
+
+func TestConnStatsTimeout(t *testing.T) {
+	b := NewBoomer("127.0.0.1:1", nil)
+	conn := &trackedConn{Conn: timeoutConn{}, tracker: &b.conns, clock: b.clock, opened: b.clock.Now()}
+	conn.Read(make([]byte, 1))
+	conn.Close()
+	if stats := b.ConnStats(); stats.Closed != 1 || stats.ClosedByServer != 0 {
+		t.Errorf("Expected a connection closed after a client timeout not to count as closed by the server, found %+v", stats)
+	}
+}
+
+func TestQuotaBackoff(t *testing.T) {
+	var count int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt64(&count, 1) <= 5 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
 		}
-		wg.Done()
-	})
+	}))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	boomer := NewBoomer(string(req.Host()), req).
+		WithAmount(20).
+		WithConcurrency(1).
+		WithRateLimit(100, time.Second).
+		WithQuotaBackoff(true)
 	boomer.Run()
-	wg.Wait()
-	boomer.Stop()
 	boomer.Wait()
+	stats := boomer.QuotaStats()
+	if stats.Limited != 5 || stats.Accepted != 15 {
+		t.Errorf("Expected 5 limited and 15 accepted requests, found %d and %d", stats.Limited, stats.Accepted)
+	}
+	if stats.Backoffs != 1 || stats.Scale != 0.5 {
+		t.Errorf("Expected the rate to be halved once, found %d backoffs to %v", stats.Backoffs, stats.Scale)
+	}
+}
+
+func TestAIMD(t *testing.T) {
+	b := NewBoomer("example.com:80", fasthttp.AcquireRequest()).
+		WithAIMD(2, 0.5, 100*time.Millisecond)
+	now := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	b.aimd.init(now, 10, time.Second)
+
+	if epoch, scale, _ := b.aimd.state(now.Add(time.Second / 2)); epoch != 0 || scale != 1 {
+		t.Errorf("Expected the rate to hold within the first window, found epoch %d at scale %v", epoch, scale)
+	}
+	if _, scale, _ := b.aimd.state(now.Add(time.Second)); scale != 1.2 {
+		t.Errorf("Expected the rate to increase to 12, found scale %v", scale)
+	}
+	b.aimd.record(Result{StatusCode: 200, Duration: time.Second})
+	if _, scale, _ := b.aimd.state(now.Add(2 * time.Second)); scale != 0.6 {
+		t.Errorf("Expected the rate to decrease to 6, found scale %v", scale)
+	}
+	stats := b.AIMDStats()
+	if stats.Rate != 6 || stats.Peak != 12 || stats.Decreases != 1 || stats.Capacity != 12 {
+		t.Errorf("Unexpected AIMD stats %+v", stats)
+	}
+}
+
+func TestShadow(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("primary"))
+	}))
+	defer primary.Close()
+	var host string
+	candidate := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host = r.Host
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		w.Write([]byte("candidate"))
+	}))
+	defer candidate.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(primary.URL + "/fail")
+	shadowAddr := candidate.Listener.Addr().String()
+	boomer := NewBoomer(string(req.Host()), req).
+		WithAmount(1).
+		WithConcurrency(1).
+		WithShadow(shadowAddr, "candidate.local", true)
+	boomer.Run()
+	res := <-boomer.Results()
+	boomer.Wait()
+	if res.Shadow == nil {
+		t.Fatalf("Expected results to carry the shadow's response")
+	}
+	if res.StatusCode != 200 || res.Shadow.StatusCode != 500 {
+		t.Errorf("Expected statuses 200 and 500, found %d and %d", res.StatusCode, res.Shadow.StatusCode)
+	}
+	if res.Shadow.BodyMatch {
+		t.Errorf("Expected bodies not to match")
+	}
+	if host != "candidate.local" {
+		t.Errorf("Expected the shadow's Host header to be candidate.local, found %s", host)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := map[string]time.Duration{
+		"":                              time.Second,
+		"5":                             5 * time.Second,
+		"Sun, 01 Jan 2017 00:00:30 GMT": 30 * time.Second,
+		"Sat, 31 Dec 2016 00:00:00 GMT": 0,
+		"soon":                          time.Second,
+	}
+	for value, expected := range tests {
+		resp := fasthttp.AcquireResponse()
+		if value != "" {
+			resp.Header.Set("Retry-After", value)
+		}
+		if wait := retryAfter(now, resp); wait != expected {
+			t.Errorf("Expected Retry-After %q to wait %v, found %v", value, expected, wait)
+		}
+		fasthttp.ReleaseResponse(resp)
+	}
+}
+
+func TestConcurrentBoomers(t *testing.T) {
+	var plainCount, tlsCount int64
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&plainCount, 1)
+	}))
+	defer plain.Close()
+	secure := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&tlsCount, 1)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer secure.Close()
+
+	newBoomer := func(url string, n, c uint) *Boomer {
+		req := fasthttp.AcquireRequest()
+		req.SetRequestURI(url)
+		return NewBoomer(string(req.Host()), req).
+			WithAmount(n).
+			WithConcurrency(c)
+	}
+	boomers := []*Boomer{newBoomer(plain.URL, 30, 3), newBoomer(secure.URL, 20, 2)}
+	statuses := make([]map[int]int, len(boomers))
+	var wg sync.WaitGroup
+	for i, b := range boomers {
+		statuses[i] = make(map[int]int)
+		wg.Add(1)
+		go func(i int, b *Boomer) {
+			defer wg.Done()
+			done := make(chan struct{})
+			go func() {
+				for res := range b.Results() {
+					statuses[i][res.StatusCode]++
+				}
+				close(done)
+			}()
+			b.Run()
+			b.Wait()
+			<-done
+		}(i, b)
+	}
+	wg.Wait()
+
+	if p, s := atomic.LoadInt64(&plainCount), atomic.LoadInt64(&tlsCount); p != 30 || s != 20 {
+		t.Errorf("Expected 30 and 20 requests, found %d and %d", p, s)
+	}
+	if statuses[0][200] != 30 || statuses[1][202] != 20 {
+		t.Errorf("Expected results not to mix between boomers, found %v", statuses)
+	}
+	if opened := boomers[0].ConnStats().Opened; opened > 3 {
+		t.Errorf("Expected at most 3 connections to the plain server, found %d", opened)
+	}
 }
 
 func TestRequest(t *testing.T) {
@@ -126,6 +345,30 @@ func TestRequest(t *testing.T) {
 	}
 }
 
+func TestRequestMethod(t *testing.T) {
+	var method string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+	}))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	req.Header.SetMethod("PUT")
+	boomer := NewBoomer(string(req.Host()), req).
+		WithAmount(1).
+		WithConcurrency(1)
+	go func() {
+		for range boomer.Results() {
+		}
+	}()
+	boomer.Run()
+	boomer.Wait()
+	if method != "PUT" {
+		t.Errorf("Method is expected to be PUT, %v is found", method)
+	}
+}
+
 func TestBody(t *testing.T) {
 	var count int64
 	handler := func(w http.ResponseWriter, r *http.Request) {
@@ -154,3 +397,748 @@ func TestBody(t *testing.T) {
 		t.Errorf("Expected to boom 10 times, found %d", atomic.LoadInt64(&count))
 	}
 }
+
+func TestPipelining(t *testing.T) {
+	var count int64
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&count, int64(1))
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	req.Header.SetMethod("GET")
+	boomer := NewBoomer(string(req.Host()), req).
+		WithAmount(20).
+		WithConcurrency(4).
+		WithPipelining(2)
+	go func() {
+		for res := range boomer.Results() {
+			if res.Err != nil {
+				t.Errorf("Unexpected error on pipelined request: %v", res.Err)
+			}
+		}
+	}()
+	boomer.Run()
+	boomer.Wait()
+	if atomic.LoadInt64(&count) != 20 {
+		t.Errorf("Expected to boom 20 times, found %d", atomic.LoadInt64(&count))
+	}
+}
+
+func TestSSE(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < 3; i++ {
+			fmt.Fprintf(w, ": keep-alive\n\ndata: %d\n\n", i)
+			w.(http.Flusher).Flush()
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	req.Header.SetMethod("GET")
+	boomer := NewBoomer(string(req.Host()), req).
+		WithAmount(2).
+		WithConcurrency(1).
+		WithSSE(true)
+	var results []Result
+	done := make(chan struct{})
+	go func() {
+		for res := range boomer.Results() {
+			results = append(results, res)
+		}
+		close(done)
+	}()
+	boomer.Run()
+	boomer.Wait()
+	<-done
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 streams, found %d", len(results))
+	}
+	for _, res := range results {
+		if res.Events != 3 {
+			t.Errorf("Expected 3 events per stream, found %d", res.Events)
+		}
+		if res.Err != ErrStreamClosed {
+			t.Errorf("Expected stream to be closed by server, found %v", res.Err)
+		}
+	}
+}
+
+func TestSSELongLines(t *testing.T) {
+	b := NewBoomer("127.0.0.1:1", nil)
+	// Longer than the 64KB lines bufio.Scanner reads.
+	data := strings.Repeat("x", 100<<10)
+	stream := "data: " + data + "\n\n: " + data + "\n\ndata: x\r\n\r\n"
+	var res Result
+	if err := b.countEvents(strings.NewReader(stream), b.clock.Now(), &res); err != io.EOF {
+		t.Errorf("Expected the stream to end, found %v", err)
+	}
+	if res.Events != 2 {
+		t.Errorf("Expected 2 events, found %d", res.Events)
+	}
+}
+
+func TestStreaming(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("first"))
+		w.(http.Flusher).Flush()
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte("second"))
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	req.Header.SetMethod("GET")
+	boomer := NewBoomer(string(req.Host()), req).
+		WithAmount(1).
+		WithConcurrency(1).
+		WithStreaming(true)
+	var results []Result
+	done := make(chan struct{})
+	go func() {
+		for res := range boomer.Results() {
+			results = append(results, res)
+		}
+		close(done)
+	}()
+	boomer.Run()
+	boomer.Wait()
+	<-done
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 result, found %d", len(results))
+	}
+	res := results[0]
+	if res.Err != nil {
+		t.Fatalf("Unexpected error: %v", res.Err)
+	}
+	if res.ContentLength != len("firstsecond") {
+		t.Errorf("Expected to read %d bytes, found %d", len("firstsecond"), res.ContentLength)
+	}
+	if res.FirstByte >= res.Duration || res.Duration < 100*time.Millisecond {
+		t.Errorf("Expected first byte (%v) to arrive before the stream ends (%v)", res.FirstByte, res.Duration)
+	}
+}
+
+func TestRequestMix(t *testing.T) {
+	var gets, posts int64
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			atomic.AddInt64(&gets, 1)
+		case "POST":
+			atomic.AddInt64(&posts, 1)
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	get := fasthttp.AcquireRequest()
+	get.SetRequestURI(server.URL)
+	get.Header.SetMethod("GET")
+	post := fasthttp.AcquireRequest()
+	get.CopyTo(post)
+	post.Header.SetMethod("POST")
+	boomer := NewBoomer(string(get.Host()), get).
+		WithAmount(100).
+		WithConcurrency(2).
+		WithRequestMix([]*WeightedRequest{
+			{Request: get, Weight: 3, Label: "get"},
+			{Request: post, Weight: 1, Label: "post"},
+		})
+	labels := make(map[string]int)
+	done := make(chan struct{})
+	go func() {
+		for res := range boomer.Results() {
+			labels[res.Label]++
+		}
+		close(done)
+	}()
+	boomer.Run()
+	boomer.Wait()
+	<-done
+	if atomic.LoadInt64(&gets)+atomic.LoadInt64(&posts) != 100 {
+		t.Errorf("Expected to boom 100 times, found %d", atomic.LoadInt64(&gets)+atomic.LoadInt64(&posts))
+	}
+	if atomic.LoadInt64(&gets) <= atomic.LoadInt64(&posts) {
+		t.Errorf("Expected more GETs than POSTs, found %d and %d", atomic.LoadInt64(&gets), atomic.LoadInt64(&posts))
+	}
+	if labels["get"] != int(atomic.LoadInt64(&gets)) || labels["post"] != int(atomic.LoadInt64(&posts)) {
+		t.Errorf("Results were not labeled correctly: %v", labels)
+	}
+}
+
+func TestRequestMixRate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	boomer := NewBoomer(string(req.Host()), req).
+		WithDuration(500 * time.Millisecond).
+		WithConcurrency(2).
+		WithRequestMix([]*WeightedRequest{
+			{Request: req, Weight: 9, Label: "capped", RateInterval: 100 * time.Millisecond},
+			{Request: req, Weight: 1, Label: "free"},
+		})
+	labels := make(map[string]int)
+	done := make(chan struct{})
+	go func() {
+		for res := range boomer.Results() {
+			labels[res.Label]++
+		}
+		close(done)
+	}()
+	boomer.Run()
+	boomer.Wait()
+	<-done
+	if labels["capped"] > 6 {
+		t.Errorf("Expected at most 6 capped requests in 500ms, found %d", labels["capped"])
+	}
+	if labels["free"] <= labels["capped"] {
+		t.Errorf("Expected the free request to take the capped one's share, found %v", labels)
+	}
+}
+
+func TestWorkerPanic(t *testing.T) {
+	var count int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&count, 1)
+	}))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	var hooked int64
+	boomer := NewBoomer(string(req.Host()), req).
+		WithAmount(10).
+		WithConcurrency(1).
+		WithRequestHook(func(vu int, req *fasthttp.Request) {
+			if atomic.AddInt64(&hooked, 1) == 1 {
+				panic("broken hook")
+			}
+		})
+	var panics int
+	done := make(chan struct{})
+	go func() {
+		for res := range boomer.Results() {
+			if res.Err != nil && res.Err.Error() == "worker panic: broken hook" {
+				panics++
+			}
+		}
+		close(done)
+	}()
+	boomer.Run()
+	boomer.Wait()
+	<-done
+	if panics != 1 || boomer.WorkerPanics() != 1 {
+		t.Errorf("Expected 1 panic to be reported, found %d results and %d panics", panics, boomer.WorkerPanics())
+	}
+	if atomic.LoadInt64(&count) != 9 {
+		t.Errorf("Expected the worker to keep sending after the panic, found %d requests", atomic.LoadInt64(&count))
+	}
+}
+
+func TestRequestFilter(t *testing.T) {
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI("http://localhost:80")
+	errFiltered := errors.New("filtered")
+	var filtered int64
+	boomer := NewBoomer(string(req.Host()), req).
+		WithAmount(10).
+		WithConcurrency(1).
+		WithRequestFilter(func(vu int, req *fasthttp.Request) error {
+			if atomic.AddInt64(&filtered, 1)%2 == 0 {
+				return errFiltered
+			}
+			return nil
+		})
+	var failed int
+	done := make(chan struct{})
+	go func() {
+		for res := range boomer.Results() {
+			if res.Err == errFiltered {
+				failed++
+			}
+		}
+		close(done)
+	}()
+	boomer.Run()
+	boomer.Wait()
+	<-done
+	if failed != 5 || boomer.WorkerPanics() != 0 {
+		t.Errorf("Expected 5 requests failed by the filter without panics, found %d and %d panics", failed, boomer.WorkerPanics())
+	}
+}
+
+func TestRequestHook(t *testing.T) {
+	var lock sync.Mutex
+	vus := make(map[string]int)
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		vus[r.Header.Get("X-VU")]++
+		lock.Unlock()
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	req.Header.SetMethod("GET")
+	boomer := NewBoomer(string(req.Host()), req).
+		WithAmount(20).
+		WithConcurrency(2).
+		WithRequestHook(func(vu int, req *fasthttp.Request) {
+			req.Header.Set("X-VU", fmt.Sprint(vu))
+		})
+	go func() {
+		for range boomer.Results() {
+		}
+	}()
+	boomer.Run()
+	boomer.Wait()
+	if vus["1"]+vus["2"] != 20 {
+		t.Errorf("Expected 20 requests from virtual users 1 and 2, found %v", vus)
+	}
+}
+
+func TestAffinityCookie(t *testing.T) {
+	var count int64
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt64(&count, 1)
+		if _, err := r.Cookie("backend"); err != nil || n == 3 {
+			// New sessions and the third request get assigned a backend.
+			http.SetCookie(w, &http.Cookie{Name: "backend", Value: fmt.Sprint("b", n)})
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	req.Header.SetMethod("GET")
+	boomer := NewBoomer(string(req.Host()), req).
+		WithAmount(5).
+		WithConcurrency(1).
+		WithAffinityCookie("backend")
+	var backends []string
+	var breaks int
+	done := make(chan struct{})
+	go func() {
+		for res := range boomer.Results() {
+			backends = append(backends, res.Backend)
+			if res.AffinityBroken {
+				breaks++
+			}
+		}
+		close(done)
+	}()
+	boomer.Run()
+	boomer.Wait()
+	<-done
+	if breaks != 1 {
+		t.Errorf("Expected affinity to be broken once, found %d", breaks)
+	}
+	if backends[0] != "b1" || backends[1] != "b1" || backends[4] != "b3" {
+		t.Errorf("Backends were not tracked correctly: %v", backends)
+	}
+}
+
+func TestDNSFanout(t *testing.T) {
+	var count int64
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&count, int64(1))
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	req.Header.SetMethod("GET")
+	boomer := NewBoomer(string(req.Host()), req).
+		WithAmount(10).
+		WithConcurrency(2).
+		WithDNSFanout(true, 0)
+	addrs := make(map[string]int)
+	done := make(chan struct{})
+	go func() {
+		for res := range boomer.Results() {
+			addrs[res.Addr]++
+		}
+		close(done)
+	}()
+	boomer.Run()
+	boomer.Wait()
+	<-done
+	if atomic.LoadInt64(&count) != 10 {
+		t.Errorf("Expected to boom 10 times, found %d", atomic.LoadInt64(&count))
+	}
+	if addrs["127.0.0.1"] != 10 {
+		t.Errorf("Expected every request to be served by 127.0.0.1, found %v", addrs)
+	}
+}
+
+func TestHappyEyeballs(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	req.Header.SetMethod("GET")
+	boomer := NewBoomer(string(req.Host()), req).
+		WithAmount(10).
+		WithConcurrency(1).
+		WithHappyEyeballs(true)
+	go func() {
+		for range boomer.Results() {
+		}
+	}()
+	boomer.Run()
+	boomer.Wait()
+	stats := boomer.DialStats()
+	if stats.IPv4 != 1 || stats.IPv6 != 0 || stats.Failures != 0 {
+		t.Errorf("Expected a single IPv4 connection, found %+v", stats)
+	}
+	if stats.Addrs["127.0.0.1"] != 1 {
+		t.Errorf("Expected the connection to use 127.0.0.1, found %v", stats.Addrs)
+	}
+}
+
+func TestRequestsPerConn(t *testing.T) {
+	var lock sync.Mutex
+	conns := make(map[string]int)
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		conns[r.RemoteAddr]++
+		lock.Unlock()
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	req.Header.SetMethod("GET")
+	boomer := NewBoomer(string(req.Host()), req).
+		WithAmount(9).
+		WithConcurrency(1).
+		WithConnectionChurn(0, 3)
+	go func() {
+		for range boomer.Results() {
+		}
+	}()
+	boomer.Run()
+	boomer.Wait()
+	if len(conns) != 3 {
+		t.Errorf("Expected 3 connections, found %v", conns)
+	}
+	for addr, n := range conns {
+		if n != 3 {
+			t.Errorf("Expected 3 requests on connection %v, found %d", addr, n)
+		}
+	}
+}
+
+func TestTLSInfo(t *testing.T) {
+	var count int64
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&count, int64(1))
+	}
+	server := httptest.NewTLSServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	req.Header.SetMethod("GET")
+	boomer := NewBoomer(string(req.Host()), req).
+		WithAmount(5).
+		WithConcurrency(1)
+	go func() {
+		for range boomer.Results() {
+		}
+	}()
+	boomer.Run()
+	boomer.Wait()
+	if atomic.LoadInt64(&count) != 5 {
+		t.Errorf("Expected to boom 5 times over TLS, found %d", atomic.LoadInt64(&count))
+	}
+	info, err := boomer.TLSInfo()
+	if err != nil {
+		t.Fatalf("Unexpected TLS handshake error: %v", err)
+	}
+	if info.Version == "" || info.CipherSuite == "" || info.NotAfter.IsZero() {
+		t.Errorf("TLS details were not recorded: %+v", info)
+	}
+}
+
+func TestTLSInfoOCSP(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.StartTLS()
+	defer server.Close()
+
+	boomer := NewBoomer(server.Listener.Addr().String(), nil)
+	boomer.probeTLS()
+	if info, err := boomer.TLSInfo(); err != nil || info.OCSPStapled {
+		t.Errorf("Expected no OCSP staple, found %+v %v", info, err)
+	}
+	server.TLS.Certificates[0].OCSPStaple = []byte("staple")
+	boomer.probeTLS()
+	if info, err := boomer.TLSInfo(); err != nil || !info.OCSPStapled {
+		t.Errorf("Expected an OCSP staple, found %+v %v", info, err)
+	}
+}
+
+func TestParseCurves(t *testing.T) {
+	ids, err := ParseCurves("X25519, P-256")
+	if err != nil {
+		t.Fatalf("Valid curves were not parsed correctly: %v", err)
+	}
+	if len(ids) != 2 || ids[0] != tls.X25519 || ids[1] != tls.CurveP256 {
+		t.Errorf("Valid curves were not parsed correctly, parsed values: %v", ids)
+	}
+	if _, err := ParseCurves("X25519,P-999"); err == nil {
+		t.Errorf("An unknown curve passed parsing")
+	}
+}
+
+func TestRetriesWithIdempotencyKey(t *testing.T) {
+	var lock sync.Mutex
+	keys := make(map[string]int)
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		key := r.Header.Get("Idempotency-Key")
+		keys[key]++
+		if keys[key] == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	req.Header.SetMethod("POST")
+	boomer := NewBoomer(string(req.Host()), req).
+		WithAmount(5).
+		WithConcurrency(1).
+		WithRetries(2).
+		WithIdempotencyKey("Idempotency-Key")
+	var attempts int
+	done := make(chan struct{})
+	go func() {
+		for res := range boomer.Results() {
+			attempts += res.Attempts
+			if res.StatusCode != http.StatusOK {
+				t.Errorf("Expected retried request to succeed, found status %d", res.StatusCode)
+			}
+		}
+		close(done)
+	}()
+	boomer.Run()
+	boomer.Wait()
+	<-done
+	if len(keys) != 5 || attempts != 10 {
+		t.Errorf("Expected 5 keys sent twice each, found %d keys and %d attempts", len(keys), attempts)
+	}
+}
+
+func TestSetupTeardown(t *testing.T) {
+	var lock sync.Mutex
+	var requests []string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		lock.Unlock()
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	request := func(method, path string) *fasthttp.Request {
+		req := fasthttp.AcquireRequest()
+		req.SetRequestURI(server.URL + path)
+		req.Header.SetMethod(method)
+		return req
+	}
+	run := func(setup string) (int, *Boomer) {
+		requests = nil
+		boomer := NewBoomer(string(request("GET", "/").Host()), request("GET", "/items")).
+			WithAmount(10).
+			WithConcurrency(2).
+			WithSetup(request("POST", setup)).
+			WithTeardown(request("DELETE", "/users/1"))
+		var results int
+		done := make(chan struct{})
+		go func() {
+			for range boomer.Results() {
+				results++
+			}
+			close(done)
+		}()
+		boomer.Run()
+		boomer.Wait()
+		<-done
+		return results, boomer
+	}
+
+	results, boomer := run("/users")
+	if results != 10 {
+		t.Errorf("Expected 10 results without setup and teardown, found %d", results)
+	}
+	if len(requests) != 12 || requests[0] != "POST /users" || requests[11] != "DELETE /users/1" {
+		t.Errorf("Setup and teardown were not sent around the test: %v", requests)
+	}
+	if errs := boomer.LifecycleErrors(); len(errs) != 0 || boomer.SetupErr() != nil {
+		t.Errorf("Expected no lifecycle errors, found %v", errs)
+	}
+
+	results, boomer = run("/fail")
+	if results != 0 || len(requests) != 2 || requests[1] != "DELETE /users/1" {
+		t.Errorf("Expected only teardown after a failed setup, found %d results and %v", results, requests)
+	}
+	if errs := boomer.LifecycleErrors(); len(errs) != 1 || boomer.SetupErr() != errs[0] {
+		t.Errorf("Expected the setup failure, found %v", errs)
+	}
+}
+
+func TestTenantHeader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	boomer := NewBoomer(string(req.Host()), req).
+		WithAmount(10).
+		WithConcurrency(2).
+		WithTenantHeader("X-Tenant").
+		WithRequestHook(func(vu int, req *fasthttp.Request) {
+			req.Header.Set("X-Tenant", fmt.Sprintf("tenant-%d", vu))
+		})
+	tenants := make(map[string]int)
+	done := make(chan struct{})
+	go func() {
+		for res := range boomer.Results() {
+			tenants[res.Tenant]++
+		}
+		close(done)
+	}()
+	boomer.Run()
+	boomer.Wait()
+	<-done
+	if tenants["tenant-1"]+tenants["tenant-2"] != 10 {
+		t.Errorf("Results did not carry their tenants: %v", tenants)
+	}
+}
+
+func TestCacheStatus(t *testing.T) {
+	for _, c := range []struct {
+		headers  [][2]string
+		expected string
+	}{
+		{[][2]string{{"X-Cache", "Hit from cloudfront"}}, CacheHit},
+		{[][2]string{{"CF-Cache-Status", "MISS"}}, CacheMiss},
+		{[][2]string{{"X-Cache", "MISS"}, {"Age", "10"}}, CacheMiss},
+		{[][2]string{{"Age", "10"}}, CacheHit},
+		{[][2]string{{"Age", "0"}}, CacheMiss},
+		{nil, CacheUnknown},
+	} {
+		resp := fasthttp.AcquireResponse()
+		for _, h := range c.headers {
+			resp.Header.Set(h[0], h[1])
+		}
+		if status := cacheStatus(resp); status != c.expected {
+			t.Errorf("Expected cache status %v of %v, found %v", c.expected, c.headers, status)
+		}
+	}
+}
+
+func TestConditionalRequests(t *testing.T) {
+	b := NewBoomer("example.org:80", fasthttp.AcquireRequest()).WithConditionalRequests(true)
+	var sess session
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI("http://example.org/items")
+	resp := fasthttp.AcquireResponse()
+	resp.Header.Set("ETag", `"v1"`)
+
+	var res Result
+	sess.prepare(b, req)
+	sess.observe(b, req, resp, &res)
+	if res.Validated || len(req.Header.Peek("If-None-Match")) > 0 {
+		t.Errorf("Expected the first request not to be conditional")
+	}
+
+	req.Reset()
+	req.SetRequestURI("http://example.org/items")
+	sess.prepare(b, req)
+	sess.observe(b, req, resp, &res)
+	if v := string(req.Header.Peek("If-None-Match")); !res.Validated || v != `"v1"` {
+		t.Errorf("Expected the second request to send back the ETag, found %q", v)
+	}
+
+	req.Reset()
+	req.SetRequestURI("http://example.org/other")
+	sess.prepare(b, req)
+	if sess.validated {
+		t.Errorf("Expected requests to other URLs not to be conditional")
+	}
+}
+
+func TestPreflightDue(t *testing.T) {
+	for _, c := range []struct {
+		share    float64
+		expected int
+	}{
+		{0.3, 30},
+		{0.5, 50},
+		{1, 100},
+		{0.01, 1},
+	} {
+		b := NewBoomer("example.org:80", fasthttp.AcquireRequest()).WithPreflight(c.share, "https://example.com", "")
+		var due int
+		for i := 0; i < 100; i++ {
+			if b.preflight.due() {
+				due++
+			}
+		}
+		if due != c.expected {
+			t.Errorf("Expected %d preflights out of 100 requests for %v, found %d", c.expected, c.share, due)
+		}
+	}
+	if NewBoomer("example.org:80", fasthttp.AcquireRequest()).WithPreflight(0, "", "").preflight != nil {
+		t.Errorf("Expected no preflights without a share")
+	}
+}
+
+func TestParseServerTiming(t *testing.T) {
+	timings := parseServerTiming(`db;dur=53, cache;desc="Cache Read, L2";dur=23.2, miss, total;dur="120"`, nil)
+	timings = parseServerTiming("render;desc=html;dur=0.5", timings)
+	expected := []ServerTiming{
+		{"db", 53 * time.Millisecond},
+		{"cache", 23200 * time.Microsecond},
+		{"total", 120 * time.Millisecond},
+		{"render", 500 * time.Microsecond},
+	}
+	if !reflect.DeepEqual(timings, expected) {
+		t.Errorf("Expected server timings %v, found %v", expected, timings)
+	}
+}
+
+func TestPropagateTrace(t *testing.T) {
+	req := fasthttp.AcquireRequest()
+	first, second := propagateTrace(req), propagateTrace(req)
+	if len(first) != 32 || first == second {
+		t.Errorf("Expected unique 32 digit trace ids, found %v and %v", first, second)
+	}
+	if v := string(req.Header.Peek("traceparent")); !strings.HasPrefix(v, "00-"+second+"-") {
+		t.Errorf("Expected traceparent of trace %v, found %v", second, v)
+	}
+}
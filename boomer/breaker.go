@@ -0,0 +1,151 @@
+package boomer
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// breakerBuckets is the amount of buckets the breaker window is split into.
+	breakerBuckets = 10
+	// breakerMinRequests is the minimum amount of requests in the window
+	// for the breaker to open.
+	breakerMinRequests = 10
+)
+
+// MinBreakerWindow is the shortest circuit breaker window, so each of its
+// buckets spans at least a millisecond.
+const MinBreakerWindow = breakerBuckets * time.Millisecond
+
+// ErrCircuitOpen is reported for requests not sent because the circuit
+// breaker was open.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+type breakerBucket struct {
+	start  time.Time
+	total  int
+	errors int
+}
+
+// breaker emulates a client side circuit breaker: it opens when the error
+// rate over the window reaches the threshold, rejecting requests for a whole
+// window, and then lets a single probe request decide whether to close.
+type breaker struct {
+	lock      sync.Mutex
+	threshold float64
+	window    time.Duration
+	buckets   [breakerBuckets]breakerBucket
+	state     breakerState
+	openedAt  time.Time
+	probing   bool
+	trips     int
+}
+
+// WithCircuitBreaker makes Boomer stop sending requests for window once the
+// rate of failed requests over the last window reaches threshold (0 to 1),
+// like production clients with circuit breakers do. Requests not sent are
+// reported with ErrCircuitOpen. It panics if window is shorter than
+// MinBreakerWindow.
+func (b *Boomer) WithCircuitBreaker(threshold float64, window time.Duration) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	if threshold > 0 && window > 0 && window < MinBreakerWindow {
+		panic(fmt.Sprintf("Circuit breaker window must be at least %v", MinBreakerWindow))
+	}
+	b.breaker = nil
+	if threshold > 0 && window > 0 {
+		b.breaker = &breaker{threshold: threshold, window: window}
+	}
+	return b
+}
+
+// BreakerTrips returns how many times the circuit breaker opened.
+func (b *Boomer) BreakerTrips() int {
+	if b.breaker == nil {
+		return 0
+	}
+	b.breaker.lock.Lock()
+	defer b.breaker.lock.Unlock()
+	return b.breaker.trips
+}
+
+// allow reports whether a request can be sent right now.
+func (c *breaker) allow(now time.Time) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	switch c.state {
+	case breakerOpen:
+		if now.Sub(c.openedAt) < c.window {
+			return false
+		}
+		c.state = breakerHalfOpen
+		c.probing = true
+		return true
+	case breakerHalfOpen:
+		if c.probing {
+			return false
+		}
+		c.probing = true
+		return true
+	}
+	return true
+}
+
+func (c *breaker) record(now time.Time, failed bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.state == breakerHalfOpen {
+		c.probing = false
+		if failed {
+			c.open(now)
+		} else {
+			c.state = breakerClosed
+			c.buckets = [breakerBuckets]breakerBucket{}
+		}
+		return
+	}
+	if c.state == breakerOpen {
+		return
+	}
+
+	size := c.window / breakerBuckets
+	bucket := &c.buckets[(now.UnixNano()/int64(size))%breakerBuckets]
+	// Unlike Truncate, Add keeps the monotonic reading of now, which later
+	// comparisons then use. Buckets are told apart by their wall clock
+	// start though, as the monotonic reading drifts between readings.
+	if start := now.Add(-time.Duration(now.UnixNano() % int64(size))); bucket.start.UnixNano() != start.UnixNano() {
+		*bucket = breakerBucket{start: start}
+	}
+	bucket.total++
+	if failed {
+		bucket.errors++
+	}
+
+	var total, errors int
+	for _, bucket := range c.buckets {
+		if now.Sub(bucket.start) < c.window {
+			total += bucket.total
+			errors += bucket.errors
+		}
+	}
+	if total >= breakerMinRequests && float64(errors)/float64(total) >= c.threshold {
+		c.open(now)
+	}
+}
+
+func (c *breaker) open(now time.Time) {
+	c.state = breakerOpen
+	c.openedAt = now
+	c.trips++
+}
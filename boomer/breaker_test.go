@@ -0,0 +1,72 @@
+package boomer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestBreaker(t *testing.T) {
+	c := &breaker{threshold: 0.5, window: 10 * time.Second}
+	now := time.Now()
+	for i := 0; i < breakerMinRequests; i++ {
+		if !c.allow(now) {
+			t.Fatalf("Breaker opened before reaching the minimum amount of requests")
+		}
+		c.record(now, i%2 == 0)
+	}
+	if c.allow(now.Add(time.Second)) {
+		t.Errorf("Expected breaker to open at a 50%% error rate")
+	}
+
+	// After a window a single probe is let through.
+	now = now.Add(11 * time.Second)
+	if !c.allow(now) || c.allow(now) {
+		t.Errorf("Expected breaker to let a single probe through")
+	}
+	c.record(now, true)
+	if c.allow(now) || c.trips != 2 {
+		t.Errorf("Expected a failed probe to open the breaker again")
+	}
+
+	now = now.Add(11 * time.Second)
+	c.allow(now)
+	c.record(now, false)
+	if !c.allow(now) || !c.allow(now) {
+		t.Errorf("Expected a successful probe to close the breaker")
+	}
+}
+
+func TestBreakerWindow(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected a window shorter than %v to panic", MinBreakerWindow)
+		}
+	}()
+	NewBoomer("127.0.0.1:0", nil).WithCircuitBreaker(0.5, 5*time.Nanosecond)
+}
+
+func TestBreakerPanic(t *testing.T) {
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI("http://localhost:80")
+	boomer := NewBoomer(string(req.Host()), req).
+		WithAmount(1).
+		WithConcurrency(1).
+		WithCircuitBreaker(0.5, time.Hour).
+		WithAssertion(func(resp *fasthttp.Response) error {
+			panic("broken assertion")
+		})
+	// Half-open on the next request, which is let through as a probe.
+	boomer.breaker.open(boomer.clock.Now().Add(-2 * time.Hour))
+	go func() {
+		for range boomer.Results() {
+		}
+	}()
+	boomer.Run()
+	boomer.Wait()
+	c := boomer.breaker
+	if c.probing || c.state != breakerOpen || c.trips != 2 {
+		t.Errorf("Expected the panicking probe to open the breaker again, found state %v and %d trips", c.state, c.trips)
+	}
+}
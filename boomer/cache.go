@@ -0,0 +1,50 @@
+package boomer
+
+import (
+	"bytes"
+	"strconv"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Cache statuses of responses, as told by their headers.
+const (
+	CacheHit     = "hit"
+	CacheMiss    = "miss"
+	CacheUnknown = "unknown"
+)
+
+// cacheHeaders are the headers caches and CDNs tell whether a response was
+// a hit with.
+var cacheHeaders = []string{"X-Cache", "CF-Cache-Status", "X-Cache-Status", "X-Proxy-Cache"}
+
+// WithCacheStatus makes results report whether their responses were cache
+// hits, as told by the headers of common caches and CDNs or a positive Age.
+func (b *Boomer) WithCacheStatus(report bool) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.CacheStatus = report
+	return b
+}
+
+func cacheStatus(resp *fasthttp.Response) string {
+	for _, name := range cacheHeaders {
+		v := bytes.ToUpper(resp.Header.Peek(name))
+		switch {
+		case len(v) == 0:
+			continue
+		case bytes.Contains(v, []byte("HIT")):
+			return CacheHit
+		default:
+			return CacheMiss
+		}
+	}
+	if age, err := strconv.Atoi(string(resp.Header.Peek("Age"))); err == nil {
+		if age > 0 {
+			return CacheHit
+		}
+		return CacheMiss
+	}
+	return CacheUnknown
+}
@@ -0,0 +1,81 @@
+package boomer
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/valyala/fasthttp"
+)
+
+// capture writes the first requests and responses of a test verbatim.
+type capture struct {
+	lock      sync.Mutex
+	w         io.Writer
+	remaining int
+	written   int
+	err       error
+}
+
+// WithCapture writes the first n complete requests, as sent, along with
+// their responses to w, so they can be checked at full fidelity. 0 disables
+// it.
+func (b *Boomer) WithCapture(w io.Writer, n int) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.capture = nil
+	if n > 0 {
+		b.capture = &capture{w: w, remaining: n}
+	}
+	return b
+}
+
+// CaptureErr returns the error which stopped writing captured requests, if
+// any.
+func (b *Boomer) CaptureErr() error {
+	if b.capture == nil {
+		return nil
+	}
+	b.capture.lock.Lock()
+	defer b.capture.lock.Unlock()
+	return b.capture.err
+}
+
+func (c *capture) save(req *fasthttp.Request, resp *fasthttp.Response, res Result) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.remaining == 0 || c.err != nil {
+		return
+	}
+	c.remaining--
+	c.written++
+	w := &errWriter{w: c.w}
+	fmt.Fprintf(w, "=== Request %d ===\n", c.written)
+	w.Write(req.Header.Header())
+	w.Write(req.Body())
+	if res.StatusCode == 0 {
+		fmt.Fprintf(w, "\n\n=== Response %d: %v ===\n\n", c.written, res.Err)
+	} else {
+		fmt.Fprintf(w, "\n\n=== Response %d: %4.4f secs. ===\n", c.written, res.Duration.Seconds())
+		w.Write(resp.Header.Header())
+		w.Write(resp.Body())
+		w.Write([]byte("\n\n"))
+	}
+	c.err = w.err
+}
+
+// errWriter keeps the first error of a series of writes.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (w *errWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	var n int
+	n, w.err = w.w.Write(p)
+	return n, w.err
+}
@@ -0,0 +1,62 @@
+package boomer
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestCapture(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Served-By", "test")
+		w.Write([]byte("pong"))
+	}))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL + "/ping")
+	req.Header.SetMethod("POST")
+	req.Header.Set("Authorization", "Bearer token")
+	req.SetBodyString("ping")
+	var buf bytes.Buffer
+	boomer := NewBoomer(string(req.Host()), req).
+		WithAmount(5).
+		WithConcurrency(1).
+		WithCapture(&buf, 2)
+	go func() {
+		for range boomer.Results() {
+		}
+	}()
+	boomer.Run()
+	boomer.Wait()
+
+	captured := buf.String()
+	if strings.Count(captured, "=== Request") != 2 {
+		t.Errorf("Expected 2 captured requests, found %q", captured)
+	}
+	for _, expected := range []string{"POST /ping", "Authorization: Bearer token", "ping", "X-Served-By: test", "pong"} {
+		if !strings.Contains(captured, expected) {
+			t.Errorf("Expected the capture to contain %q, found %q", expected, captured)
+		}
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestCaptureErr(t *testing.T) {
+	boomer := NewBoomer("example.com:80", fasthttp.AcquireRequest()).
+		WithCapture(failingWriter{}, 2)
+	boomer.capture.save(boomer.Request, fasthttp.AcquireResponse(), Result{StatusCode: 200})
+	if err := boomer.CaptureErr(); err == nil || err.Error() != "disk full" {
+		t.Errorf("Expected the write error to be kept, found %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package boomer
+
+import (
+	"crypto/tls"
+	"net"
+	"time"
+)
+
+// ClientOptions tune the HTTP clients of a Boomer, zero values keep the
+// fasthttp defaults. Only the buffer sizes and MaxIdleConnDuration apply
+// when pipelining.
+type ClientOptions struct {
+	// Name is sent in the User-Agent header of requests without one.
+	Name string
+
+	MaxIdleConnDuration time.Duration
+	ReadBufferSize      int
+	WriteBufferSize     int
+
+	// MaxResponseBodySize makes responses with larger bodies fail.
+	MaxResponseBodySize int
+
+	// DisableHeaderNamesNormalizing sends header names as given instead of
+	// canonicalizing them.
+	DisableHeaderNamesNormalizing bool
+}
+
+// WithTLSConfig sets the base of every TLS configuration of the Boomer, the
+// server name and curve preferences are still set on top of it when
+// needed. It is not modified.
+func (b *Boomer) WithTLSConfig(config *tls.Config) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.TLSConfig = config
+	return b
+}
+
+// WithDialer makes Boomer open connections with dial instead of dialing
+// TCP itself, the rest of the connection settings still wrap it.
+func (b *Boomer) WithDialer(dial func(addr string) (net.Conn, error)) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.Dialer = dial
+	return b
+}
+
+// WithClientOptions tunes the HTTP clients of the Boomer.
+func (b *Boomer) WithClientOptions(opts ClientOptions) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.ClientOptions = opts
+	return b
+}
@@ -0,0 +1,105 @@
+package boomer
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+// run sends n requests with b, returning how many failed.
+func run(b *Boomer) (failures int) {
+	done := make(chan struct{})
+	go func() {
+		for res := range b.Results() {
+			if failed(res) {
+				failures++
+			}
+		}
+		close(done)
+	}()
+	b.Run()
+	b.Wait()
+	<-done
+	return failures
+}
+
+func TestWithDialer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	var dials int64
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI("http://unresolvable.invalid/")
+	boomer := NewBoomer("unresolvable.invalid:80", req).
+		WithAmount(10).
+		WithConcurrency(1).
+		WithDialer(func(addr string) (net.Conn, error) {
+			atomic.AddInt64(&dials, 1)
+			return net.Dial("tcp", server.Listener.Addr().String())
+		})
+	if failures := run(boomer); failures > 0 {
+		t.Errorf("Expected requests through the dialer to succeed, %d failed", failures)
+	}
+	if dials != 1 {
+		t.Errorf("Expected a single dial, found %d", dials)
+	}
+}
+
+func TestWithTLSConfig(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	config := &tls.Config{RootCAs: roots, ServerName: "example.com"}
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	boomer := NewBoomer(string(req.Host()), req).
+		WithAmount(5).
+		WithConcurrency(1).
+		WithTLSConfig(config)
+	if failures := run(boomer); failures > 0 {
+		t.Errorf("Expected the certificate to be verified with the given roots, %d requests failed", failures)
+	}
+	if config.ServerName != "example.com" || config.NextProtos != nil {
+		t.Errorf("Expected the TLS config not to be modified, found %+v", config)
+	}
+
+	config = &tls.Config{RootCAs: x509.NewCertPool(), ServerName: "example.com"}
+	boomer = NewBoomer(string(req.Host()), req).
+		WithAmount(5).
+		WithConcurrency(1).
+		WithTLSConfig(config)
+	if failures := run(boomer); failures != 5 {
+		t.Errorf("Expected requests to fail verification without roots, %d failed", failures)
+	}
+}
+
+func TestWithClientOptions(t *testing.T) {
+	var agent atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agent.Store(r.UserAgent())
+		w.Write([]byte(strings.Repeat("x", 1024)))
+	}))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	boomer := NewBoomer(string(req.Host()), req).
+		WithAmount(5).
+		WithConcurrency(1).
+		WithClientOptions(ClientOptions{Name: "pla-test", MaxResponseBodySize: 512})
+	if failures := run(boomer); failures != 5 {
+		t.Errorf("Expected responses over the maximum body size to fail, %d failed", failures)
+	}
+	if agent.Load() != "pla-test" {
+		t.Errorf("Expected the client name as User-Agent, found %v", agent.Load())
+	}
+}
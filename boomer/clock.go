@@ -0,0 +1,80 @@
+package boomer
+
+import "time"
+
+// Clock tells time to Boomer, so tests of duration based runs and rate
+// limiting can control it instead of sleeping.
+type Clock interface {
+	Now() time.Time
+
+	// After sends the current time on the returned channel once d elapsed.
+	After(d time.Duration) <-chan time.Time
+
+	// NewTimer returns a timer sending the current time once d elapsed,
+	// which unlike After can be stopped before.
+	NewTimer(d time.Duration) Timer
+
+	// NewTicker returns a ticker sending the current time every d.
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker delivers ticks of a Clock at intervals.
+type Ticker interface {
+	Chan() <-chan time.Time
+	Stop()
+}
+
+// Timer delivers a single tick of a Clock.
+type Timer interface {
+	Chan() <-chan time.Time
+	Stop()
+}
+
+// realClock is the Clock of the time package.
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (t realTicker) Chan() <-chan time.Time {
+	return t.C
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) Chan() <-chan time.Time {
+	return t.C
+}
+
+func (t realTimer) Stop() {
+	t.Timer.Stop()
+}
+
+// WithClock makes Boomer tell time with clock, which schedules requests,
+// ends duration based runs and measures latencies.
+func (b *Boomer) WithClock(clock Clock) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.clock = clock
+	return b
+}
@@ -0,0 +1,117 @@
+package boomer_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mercadolibre/pla/boomer"
+	"github.com/mercadolibre/pla/boomertest"
+	"github.com/valyala/fasthttp"
+)
+
+func newClock() *boomertest.Clock {
+	return boomertest.NewClock(time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC))
+}
+
+func newRequest() *fasthttp.Request {
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI("http://example.com/")
+	return req
+}
+
+func TestQPS(t *testing.T) {
+	doer := &boomertest.Doer{}
+	clock := newClock()
+	b := boomer.NewBoomer("example.com:80", newRequest()).
+		WithAmount(20).
+		WithConcurrency(2).
+		WithRateLimit(1, time.Second).
+		WithClock(clock).
+		WithDoer(doer)
+	b.Run()
+	<-b.Results()
+	select {
+	case <-b.Results():
+		t.Errorf("Expected to boom 1 times within the first second, found more")
+	case <-time.After(50 * time.Millisecond):
+	}
+	clock.Advance(time.Second)
+	<-b.Results()
+	if doer.Requests() != 2 {
+		t.Errorf("Expected to boom 2 times within two seconds, found %d", doer.Requests())
+	}
+	go func() {
+		for range b.Results() {
+		}
+	}()
+	b.Stop()
+	b.Wait()
+}
+
+func TestFakeClockRateLimit(t *testing.T) {
+	clock := newClock()
+	start := clock.Now()
+	b := boomer.NewBoomer("example.com:80", newRequest()).
+		WithAmount(5).
+		WithConcurrency(1).
+		WithRateLimit(10, time.Second).
+		WithClock(clock).
+		WithDoer(&boomertest.Doer{})
+	b.Run()
+	for i := 0; i < 5; i++ {
+		res := <-b.Results()
+		if want := start.Add(time.Duration(i) * 100 * time.Millisecond); !res.Start.Equal(want) {
+			t.Errorf("Expected request %d to be sent at %v, found %v", i, want, res.Start)
+		}
+		if res.Lag != 0 {
+			t.Errorf("Expected request %d to be sent on schedule, found a lag of %v", i, res.Lag)
+		}
+		clock.Advance(100 * time.Millisecond)
+	}
+	b.Wait()
+}
+
+func TestFakeClockDuration(t *testing.T) {
+	clock := newClock()
+	b := boomer.NewBoomer("example.com:80", newRequest()).
+		WithDuration(time.Hour).
+		WithConcurrency(1).
+		WithClock(clock).
+		WithDoer(&boomertest.Doer{})
+	done := make(chan struct{})
+	go func() {
+		for range b.Results() {
+		}
+		close(done)
+	}()
+	b.Run()
+	clock.Advance(time.Hour)
+	b.Wait()
+	<-done
+}
+
+func TestFakeLatency(t *testing.T) {
+	clock := newClock()
+	doer := &boomertest.Doer{
+		Latency:  boomertest.Constant(250 * time.Millisecond),
+		Statuses: []int{200, 503},
+		Clock:    clock,
+	}
+	b := boomer.NewBoomer("example.com:80", newRequest()).
+		WithAmount(2).
+		WithConcurrency(1).
+		WithClock(clock).
+		WithDoer(doer)
+	b.Run()
+	for i, status := range []int{200, 503} {
+		for clock.Waiters() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		clock.Advance(250 * time.Millisecond)
+		res := <-b.Results()
+		if res.StatusCode != status || res.Duration != 250*time.Millisecond {
+			t.Errorf("Expected request %d to take 250ms with status %d, found %v with %d", i, status, res.Duration, res.StatusCode)
+		}
+	}
+	b.Wait()
+}
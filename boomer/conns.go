@@ -0,0 +1,135 @@
+package boomer
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/sschepens/gohistogram"
+	"github.com/valyala/fasthttp"
+)
+
+// ConnStats describes how long connections lived and how many requests they
+// carried, as seen from the client.
+type ConnStats struct {
+	// Opened and Closed count connections, ClosedByServer those the server
+	// closed before Boomer did.
+	Opened, Closed, ClosedByServer int
+
+	// Requests is the amount of requests sent over every connection.
+	Requests int
+
+	// Lifetimes holds the 50th, 90th and 99th percentiles of how long
+	// closed connections were open, LongestLifetime the maximum.
+	Lifetimes       [3]time.Duration
+	LongestLifetime time.Duration
+}
+
+// LifetimePercentiles are the percentiles reported in ConnStats.Lifetimes.
+var LifetimePercentiles = [3]int{50, 90, 99}
+
+type connTracker struct {
+	lock      sync.Mutex
+	stats     ConnStats
+	lifetimes *gohistogram.NumericHistogram
+}
+
+// ConnStats returns the connection statistics gathered so far.
+func (b *Boomer) ConnStats() ConnStats {
+	b.conns.lock.Lock()
+	defer b.conns.lock.Unlock()
+	stats := b.conns.stats
+	if b.conns.lifetimes != nil {
+		for i, p := range LifetimePercentiles {
+			stats.Lifetimes[i] = time.Duration(b.conns.lifetimes.Quantile(float64(p)/100) * float64(time.Second))
+		}
+	}
+	return stats
+}
+
+// dialTracked wraps connections of dial to keep track of them.
+func (b *Boomer) dialTracked(dial func(addr string) (net.Conn, error)) func(addr string) (net.Conn, error) {
+	return func(addr string) (net.Conn, error) {
+		conn, err := dial(addr)
+		if err != nil {
+			return conn, err
+		}
+		b.conns.lock.Lock()
+		b.conns.stats.Opened++
+		b.conns.lock.Unlock()
+		return &trackedConn{Conn: conn, tracker: &b.conns, clock: b.clock, opened: b.clock.Now(), idle: true}, nil
+	}
+}
+
+// trackedConn counts requests as writes following a read, or the first one.
+type trackedConn struct {
+	net.Conn
+	tracker *connTracker
+	clock   Clock
+	opened  time.Time
+
+	lock   sync.Mutex
+	idle   bool
+	eof    bool
+	closed bool
+}
+
+func (c *trackedConn) Write(p []byte) (int, error) {
+	c.lock.Lock()
+	if c.idle {
+		c.idle = false
+		c.tracker.lock.Lock()
+		c.tracker.stats.Requests++
+		c.tracker.lock.Unlock()
+	}
+	c.lock.Unlock()
+	return c.Conn.Write(p)
+}
+
+func (c *trackedConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.lock.Lock()
+	c.idle = true
+	if err != nil && n == 0 && closedByServer(err) {
+		c.eof = true
+	}
+	c.lock.Unlock()
+	return n, err
+}
+
+// closedByServer tells whether a failed read means the server closed the
+// connection, rather than the client giving up on it after a timeout.
+func closedByServer(err error) bool {
+	if err == fasthttp.ErrTimeout {
+		return false
+	}
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		return false
+	}
+	return true
+}
+
+func (c *trackedConn) Close() error {
+	c.lock.Lock()
+	closed, eof := c.closed, c.eof
+	c.closed = true
+	c.lock.Unlock()
+	if !closed {
+		lifetime := c.clock.Now().Sub(c.opened)
+		t := c.tracker
+		t.lock.Lock()
+		t.stats.Closed++
+		if eof {
+			t.stats.ClosedByServer++
+		}
+		if t.lifetimes == nil {
+			t.lifetimes = gohistogram.NewHistogram(20)
+		}
+		t.lifetimes.Add(lifetime.Seconds())
+		if lifetime > t.stats.LongestLifetime {
+			t.stats.LongestLifetime = lifetime
+		}
+		t.lock.Unlock()
+	}
+	return c.Conn.Close()
+}
@@ -0,0 +1,38 @@
+package boomer
+
+import "sync/atomic"
+
+// counterShards is how many cache lines a counter is spread over, more than
+// the Ps of most machines so workers running on different ones seldom share
+// a shard.
+const counterShards = 64
+
+// counter is a counter sharded by worker, so workers counting at very high
+// rates don't bounce a single cache line across cores. Go doesn't expose
+// which P a goroutine runs on, but each worker mostly stays on one, so
+// sharding by worker keeps the increments of a P on its own lines. The
+// zero value is ready to use.
+type counter struct {
+	shards [counterShards]counterShard
+}
+
+// counterShard is padded to a cache line so shards don't share one.
+type counterShard struct {
+	n uint64
+	_ [56]byte
+}
+
+// add counts one on the shard of worker vu.
+func (c *counter) add(vu int) {
+	atomic.AddUint64(&c.shards[uint(vu)%counterShards].n, 1)
+}
+
+// load returns the sum of every shard. It is not a snapshot of all of them
+// at once, but never goes back.
+func (c *counter) load() uint64 {
+	var n uint64
+	for i := range c.shards {
+		n += atomic.LoadUint64(&c.shards[i].n)
+	}
+	return n
+}
@@ -0,0 +1,48 @@
+package boomer
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCounter(t *testing.T) {
+	var c counter
+	var wg sync.WaitGroup
+	for vu := 0; vu < 2*counterShards; vu++ {
+		wg.Add(1)
+		go func(vu int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				c.add(vu)
+			}
+		}(vu)
+	}
+	wg.Wait()
+	if n := c.load(); n != 2*counterShards*100 {
+		t.Errorf("Expected %d, got %d", 2*counterShards*100, n)
+	}
+}
+
+// BenchmarkCounter and BenchmarkCounterShared count from every P at once,
+// the former on the shard of each goroutine, the latter on a single word
+// as live statistics used to. Run with -cpu 1,4,16 to see contention grow.
+func BenchmarkCounter(b *testing.B) {
+	var c counter
+	var workers int64
+	b.RunParallel(func(pb *testing.PB) {
+		vu := int(atomic.AddInt64(&workers, 1))
+		for pb.Next() {
+			c.add(vu)
+		}
+	})
+}
+
+func BenchmarkCounterShared(b *testing.B) {
+	var n uint64
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			atomic.AddUint64(&n, 1)
+		}
+	})
+}
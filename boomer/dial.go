@@ -0,0 +1,135 @@
+package boomer
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// fallbackDelay is how long an IPv6 connection attempt runs alone before
+// racing it against IPv4, as recommended by RFC 8305.
+const fallbackDelay = 300 * time.Millisecond
+
+// DialStats keeps information of the connections opened by Boomer.
+type DialStats struct {
+	// IPv4 and IPv6 are the amount of connections established per family,
+	// along with the total time spent connecting, in happy eyeballs mode.
+	IPv4, IPv6         int
+	IPv4Time, IPv6Time time.Duration
+
+	// Fallbacks are connections successfully established over IPv4 to a
+	// host which also has IPv6 addresses.
+	Fallbacks int
+
+	// Failures are connections which could not be established.
+	Failures int
+
+	// Addrs is the amount of connections established per remote address.
+	Addrs map[string]int
+
+	// Backoffs is the amount of times connecting to an address was paused
+	// after consecutive failures, and BackoffTime the total pause.
+	Backoffs    int
+	BackoffTime time.Duration
+}
+
+type dialRecorder struct {
+	lock  sync.Mutex
+	stats DialStats
+
+	// ipv6 tells whether hosts have IPv6 addresses, resolved once per host.
+	ipv6 map[string]bool
+}
+
+// WithHappyEyeballs makes Boomer race IPv6 and IPv4 connection attempts,
+// recording which family and address each connection ended up using.
+func (b *Boomer) WithHappyEyeballs(enabled bool) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.HappyEyeballs = enabled
+	return b
+}
+
+// DialStats returns a copy of the connection statistics gathered so far.
+func (b *Boomer) DialStats() DialStats {
+	b.dials.lock.Lock()
+	defer b.dials.lock.Unlock()
+	stats := b.dials.stats
+	stats.Addrs = make(map[string]int, len(b.dials.stats.Addrs))
+	for addr, n := range b.dials.stats.Addrs {
+		stats.Addrs[addr] = n
+	}
+	return stats
+}
+
+func (b *Boomer) dialDualStack(addr string) (net.Conn, error) {
+	dialer := &net.Dialer{
+		Timeout:       b.ConnectTimeout,
+		DualStack:     true,
+		FallbackDelay: fallbackDelay,
+	}
+	s := b.clock.Now()
+	conn, err := dialer.Dial("tcp", addr)
+	d := b.clock.Now().Sub(s)
+	var ip net.IP
+	var fallback bool
+	if err == nil {
+		ip = conn.RemoteAddr().(*net.TCPAddr).IP
+		// Resolved before locking, so other dials don't wait on DNS.
+		fallback = ip.To4() != nil && b.hasIPv6(addr)
+	}
+
+	b.dials.lock.Lock()
+	defer b.dials.lock.Unlock()
+	stats := &b.dials.stats
+	if err != nil {
+		stats.Failures++
+		return nil, err
+	}
+	if stats.Addrs == nil {
+		stats.Addrs = make(map[string]int)
+	}
+	stats.Addrs[ip.String()]++
+	if ip.To4() == nil {
+		stats.IPv6++
+		stats.IPv6Time += d
+	} else {
+		stats.IPv4++
+		stats.IPv4Time += d
+		if fallback {
+			stats.Fallbacks++
+		}
+	}
+	return conn, nil
+}
+
+// hasIPv6 tells whether the host of addr has IPv6 addresses, looking it up
+// only the first time.
+func (b *Boomer) hasIPv6(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	b.dials.lock.Lock()
+	ipv6, ok := b.dials.ipv6[host]
+	b.dials.lock.Unlock()
+	if ok {
+		return ipv6
+	}
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		// Not cached, the lookup may succeed next time.
+		return false
+	}
+	for _, ip := range ips {
+		ipv6 = ipv6 || ip.To4() == nil
+	}
+	b.dials.lock.Lock()
+	if b.dials.ipv6 == nil {
+		b.dials.ipv6 = make(map[string]bool)
+	}
+	b.dials.ipv6[host] = ipv6
+	b.dials.lock.Unlock()
+	return ipv6
+}
@@ -0,0 +1,46 @@
+package boomer
+
+import (
+	"github.com/valyala/fasthttp"
+)
+
+// RequestFactory builds the requests workers send. A request is built for
+// every job and released once its result was notified, so requests are
+// never shared between jobs nor workers.
+type RequestFactory interface {
+	// New returns a request with the content of w.Request for the virtual
+	// user vu. Prepare and request hooks are applied to it afterwards.
+	New(vu int, w *WeightedRequest) *fasthttp.Request
+	// Release is called with every request once it is no longer used.
+	Release(req *fasthttp.Request)
+}
+
+// pooledFactory copies templates into requests pooled by fasthttp.
+type pooledFactory struct{}
+
+func (pooledFactory) New(vu int, w *WeightedRequest) *fasthttp.Request {
+	req := fasthttp.AcquireRequest()
+	w.Request.CopyTo(req)
+	return req
+}
+
+func (pooledFactory) Release(req *fasthttp.Request) {
+	fasthttp.ReleaseRequest(req)
+}
+
+// WithRequestFactory makes Boomer build requests with f instead of copying
+// templates into pooled requests.
+func (b *Boomer) WithRequestFactory(f RequestFactory) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.factory = f
+	return b
+}
+
+func (b *Boomer) requestFactory() RequestFactory {
+	if b.factory != nil {
+		return b.factory
+	}
+	return pooledFactory{}
+}
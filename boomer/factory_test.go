@@ -0,0 +1,101 @@
+package boomer
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+// TestNoRequestBleed checks that nothing set on a request leaks into later
+// ones, run it with -race.
+func TestNoRequestBleed(t *testing.T) {
+	var bleeds, total int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&total, 1)
+		body, _ := ioutil.ReadAll(r.Body)
+		item := r.URL.Path[1:]
+		ok := len(r.Header["X-Vu"]) == 1
+		switch item {
+		case "get":
+			ok = ok && len(body) == 0 && r.Header.Get("X-Item") == ""
+		default:
+			ok = ok && string(body) == item && r.Header.Get("X-Item") == item
+		}
+		ok = ok && (r.Header.Get("X-Prepared") != "") == (item == "post")
+		if !ok {
+			atomic.AddInt64(&bleeds, 1)
+		}
+	}))
+	defer server.Close()
+
+	template := func(method, item, body string) *fasthttp.Request {
+		req := fasthttp.AcquireRequest()
+		req.SetRequestURI(server.URL + "/" + item)
+		req.Header.SetMethod(method)
+		if body != "" {
+			req.Header.Set("X-Item", item)
+			req.SetBodyString(body)
+		}
+		return req
+	}
+	mix := []*WeightedRequest{
+		{
+			Request: template("POST", "post", "post"),
+			Weight:  1,
+			Prepare: func(req *fasthttp.Request) error { req.Header.Set("X-Prepared", "1"); return nil },
+		},
+		{Request: template("GET", "get", ""), Weight: 1},
+		{Request: template("PUT", "put", "put"), Weight: 1},
+	}
+	req := mix[0].Request
+	boomer := NewBoomer(string(req.Host()), req).
+		WithAmount(300).
+		WithConcurrency(8).
+		WithRequestMix(mix).
+		WithRequestHook(func(vu int, req *fasthttp.Request) {
+			req.Header.Add("X-VU", strconv.Itoa(vu))
+		})
+	if failures := run(boomer); failures > 0 {
+		t.Errorf("Expected every request to succeed, %d failed", failures)
+	}
+	if total != 300 || bleeds > 0 {
+		t.Errorf("Expected 300 requests without bleeding between them, found %d of %d", bleeds, total)
+	}
+}
+
+type countingFactory struct {
+	created, released int64
+}
+
+func (f *countingFactory) New(vu int, w *WeightedRequest) *fasthttp.Request {
+	atomic.AddInt64(&f.created, 1)
+	req := &fasthttp.Request{}
+	w.Request.CopyTo(req)
+	return req
+}
+
+func (f *countingFactory) Release(req *fasthttp.Request) {
+	atomic.AddInt64(&f.released, 1)
+}
+
+func TestRequestFactory(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	factory := &countingFactory{}
+	boomer := NewBoomer(string(req.Host()), req).
+		WithAmount(20).
+		WithConcurrency(4).
+		WithRequestFactory(factory)
+	run(boomer)
+	if factory.created != 20 || factory.released != 20 {
+		t.Errorf("Expected 20 requests created and released, found %d and %d", factory.created, factory.released)
+	}
+}
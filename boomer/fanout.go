@@ -0,0 +1,86 @@
+package boomer
+
+import (
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// endpoint is a client bound to a single resolved address.
+type endpoint struct {
+	addr   string
+	client Doer
+}
+
+// WithDNSFanout spreads requests evenly across every address the host of
+// Addr resolves to, with separate connections for each of them. Addresses
+// are resolved again every refresh, 0 resolves them only once.
+func (b *Boomer) WithDNSFanout(fanout bool, refresh time.Duration) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.DNSFanout = fanout
+	b.DNSRefresh = refresh
+	return b
+}
+
+func (b *Boomer) initFanout() {
+	if err := b.resolve(); err != nil {
+		// Let requests fail dialing the target so errors get reported.
+		b.endpoints.Store([]*endpoint{{addr: b.Addr, client: b.newClient(b.Addr, "")}})
+	}
+	if b.DNSRefresh > 0 {
+		go func() {
+			ticker := b.clock.NewTicker(b.DNSRefresh)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-b.stop:
+					return
+				case <-ticker.Chan():
+					// Keep the previous addresses on failure.
+					b.resolve()
+				}
+			}
+		}()
+	}
+}
+
+// resolve looks up the host of Addr, reusing the clients of addresses which
+// were already known.
+func (b *Boomer) resolve() error {
+	host, port, err := net.SplitHostPort(b.Addr)
+	if err != nil {
+		return err
+	}
+	ips, err := net.LookupHost(host)
+	if err != nil {
+		return err
+	}
+	known := make(map[string]*endpoint)
+	if current, ok := b.endpoints.Load().([]*endpoint); ok {
+		for _, e := range current {
+			known[e.addr] = e
+		}
+	}
+	endpoints := make([]*endpoint, 0, len(ips))
+	for _, ip := range ips {
+		if e, ok := known[ip]; ok {
+			endpoints = append(endpoints, e)
+			continue
+		}
+		endpoints = append(endpoints, &endpoint{
+			addr:   ip,
+			client: b.newClient(net.JoinHostPort(ip, port), host),
+		})
+	}
+	b.endpoints.Store(endpoints)
+	return nil
+}
+
+// nextEndpoint picks resolved addresses in a round robin fashion.
+func (b *Boomer) nextEndpoint() *endpoint {
+	endpoints := b.endpoints.Load().([]*endpoint)
+	i := atomic.AddUint64(&b.endpointSeq, 1)
+	return endpoints[i%uint64(len(endpoints))]
+}
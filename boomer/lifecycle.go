@@ -0,0 +1,112 @@
+package boomer
+
+import (
+	"fmt"
+
+	"github.com/valyala/fasthttp"
+)
+
+// lifecycle holds the requests sent once around the test.
+type lifecycle struct {
+	setup    []*fasthttp.Request
+	teardown []*fasthttp.Request
+	errs     []error
+	setupErr error
+}
+
+// WithSetup makes Boomer send reqs, in order, once before the test, to
+// create the data it needs. Their results are not reported, and the test
+// does not start if any of them fails.
+func (b *Boomer) WithSetup(reqs ...*fasthttp.Request) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	if b.lifecycle == nil {
+		b.lifecycle = &lifecycle{}
+	}
+	b.lifecycle.setup = append(b.lifecycle.setup, reqs...)
+	return b
+}
+
+// WithTeardown makes Boomer send reqs, in order, once after the test, even
+// a failed or stopped one, to leave the target clean. Their results are not
+// reported.
+func (b *Boomer) WithTeardown(reqs ...*fasthttp.Request) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	if b.lifecycle == nil {
+		b.lifecycle = &lifecycle{}
+	}
+	b.lifecycle.teardown = append(b.lifecycle.teardown, reqs...)
+	return b
+}
+
+// LifecycleErrors returns the failures of setup and teardown requests.
+func (b *Boomer) LifecycleErrors() []error {
+	if b.lifecycle == nil {
+		return nil
+	}
+	return b.lifecycle.errs
+}
+
+// SetupErr returns why a setup request failed, in which case the test did
+// not start.
+func (b *Boomer) SetupErr() error {
+	if b.lifecycle == nil {
+		return nil
+	}
+	return b.lifecycle.setupErr
+}
+
+// runSetup sends the setup requests, it returns false if one failed.
+func (b *Boomer) runSetup() bool {
+	if b.lifecycle == nil {
+		return true
+	}
+	for _, req := range b.lifecycle.setup {
+		if err := b.sendOnce(req); err != nil {
+			b.lifecycle.setupErr = fmt.Errorf("setup %v", err)
+			b.lifecycle.errs = append(b.lifecycle.errs, b.lifecycle.setupErr)
+			return false
+		}
+	}
+	return true
+}
+
+// runTeardown sends every teardown request, whether others failed or not.
+func (b *Boomer) runTeardown() {
+	if b.lifecycle == nil {
+		return
+	}
+	for _, req := range b.lifecycle.teardown {
+		if err := b.sendOnce(req); err != nil {
+			b.lifecycle.errs = append(b.lifecycle.errs, fmt.Errorf("teardown %v", err))
+		}
+	}
+}
+
+// sendOnce sends req out of the test, failing on errors and on 4xx and 5xx
+// responses.
+func (b *Boomer) sendOnce(req *fasthttp.Request) error {
+	c := b.client
+	if c == nil {
+		// Streams and DNS fanout have no client of their own.
+		c = b.newClient(b.Addr, "")
+	}
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+	var err error
+	if b.Timeout > 0 {
+		err = c.DoTimeout(req, resp, b.Timeout)
+	} else {
+		err = c.Do(req, resp)
+	}
+	if err == nil && resp.StatusCode() >= 400 {
+		err = fmt.Errorf("status code %d", resp.StatusCode())
+	}
+	if err != nil {
+		return fmt.Errorf("%s %s: %v", req.Header.Method(), req.URI().Path(), err)
+	}
+	return nil
+}
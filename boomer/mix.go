@@ -0,0 +1,157 @@
+package boomer
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// WeightedRequest is a request template which is sent a share of the time
+// proportional to its Weight when running a request mix.
+type WeightedRequest struct {
+	Request *fasthttp.Request
+	Weight  uint
+
+	// Label identifies the results of this request in reports.
+	Label string
+
+	// RateInterval, when set, is the minimum time between two sends of
+	// this request, capping its rate independently of the others.
+	RateInterval time.Duration
+
+	// Prepare, when set, is called on the copy of Request about to be sent,
+	// returning an error fails the request without sending it.
+	Prepare func(req *fasthttp.Request) error
+
+	// Ready, when set, tells whether the request can be picked right now.
+	Ready func() bool
+
+	// Capture, when set, is called with every successful response.
+	Capture func(req *fasthttp.Request, resp *fasthttp.Response)
+
+	// Budget, when set, is the latency the request should stay within as a
+	// step of a scenario, results report whether it did. OnFailure is what
+	// the iteration does when the step fails, and StepRetries how many times
+	// it is sent again when retried.
+	Budget      time.Duration
+	OnFailure   OnFailure
+	StepRetries uint
+
+	// Branches pick the step sent after this one by its response, the
+	// first one taken wins.
+	Branches []Branch
+}
+
+// WithRequestMix makes Boomer pick each request from mix, randomly
+// according to their weights, instead of always sending Request.
+func (b *Boomer) WithRequestMix(mix []*WeightedRequest) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.mix = mix
+	b.mixTotal = 0
+	for _, w := range mix {
+		b.mixTotal += w.Weight
+	}
+	return b
+}
+
+// WithSeed seeds the random picks of the request mix, so runs with the same
+// seed send the same sequence of requests. 0 seeds them with the time.
+func (b *Boomer) WithSeed(seed int64) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.seed = seed
+	return b
+}
+
+func (b *Boomer) initMix() {
+	if len(b.mix) == 0 {
+		b.WithRequestMix([]*WeightedRequest{{Request: b.Request, Weight: 1}})
+	}
+	seed := b.seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	b.rand = rand.New(rand.NewSource(seed))
+	b.ready = make([]bool, len(b.mix))
+	b.allowed = make([]time.Time, len(b.mix))
+}
+
+// nextRequest is only called from the trigger loop, so b.rand, b.ready and
+// b.allowed need no lock. It waits while every ready request is throttled
+// by its own rate, returning nil if Boomer is stopped meanwhile.
+func (b *Boomer) nextRequest() *WeightedRequest {
+	if len(b.mix) == 1 && b.mix[0].RateInterval == 0 {
+		return b.mix[0]
+	}
+	for {
+		w, wait := b.pick(b.clock.Now())
+		if w != nil {
+			return w
+		}
+		if !b.waitUntil(wait) {
+			return nil
+		}
+	}
+}
+
+// pick randomly picks a request among the ready ones. When every ready
+// request is throttled it returns nil along with when the first of them is
+// allowed again.
+func (b *Boomer) pick(now time.Time) (*WeightedRequest, time.Time) {
+	total := b.mixTotal
+	var throttled bool
+	var wait time.Time
+	for i, w := range b.mix {
+		b.ready[i] = w.Ready == nil || w.Ready()
+		if b.ready[i] && now.Before(b.allowed[i]) {
+			if !throttled || b.allowed[i].Before(wait) {
+				wait = b.allowed[i]
+			}
+			b.ready[i], throttled = false, true
+		}
+		if !b.ready[i] {
+			total -= w.Weight
+		}
+	}
+	if total == 0 {
+		if throttled {
+			return nil, wait
+		}
+		// Nothing is ready, send anything rather than stalling the test.
+		total = b.mixTotal
+		for i := range b.ready {
+			b.ready[i] = true
+		}
+	}
+	n := uint(b.rand.Int63n(int64(total)))
+	for i, w := range b.mix {
+		if !b.ready[i] {
+			continue
+		}
+		if n < w.Weight {
+			b.throttle(i, now)
+			return w, now
+		}
+		n -= w.Weight
+	}
+	return b.mix[len(b.mix)-1], now
+}
+
+// throttle schedules when the i-th request of the mix can be sent again
+// after being picked at now. Lateness is caught up on, but idle time does
+// not accumulate into bursts.
+func (b *Boomer) throttle(i int, now time.Time) {
+	interval := b.mix[i].RateInterval
+	if interval == 0 {
+		return
+	}
+	last := b.allowed[i]
+	if now.Sub(last) >= interval {
+		last = now
+	}
+	b.allowed[i] = last.Add(interval)
+}
@@ -0,0 +1,46 @@
+package boomer
+
+import (
+	"math/rand"
+	"net"
+	"time"
+)
+
+// WithSimulatedLatency delays every write to the target by latency, plus or
+// minus a random jitter, to approximate clients on distant networks.
+func (b *Boomer) WithSimulatedLatency(latency, jitter time.Duration) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.SimulatedLatency = latency
+	b.SimulatedJitter = jitter
+	return b
+}
+
+func (b *Boomer) dialLatency(dial func(addr string) (net.Conn, error)) func(addr string) (net.Conn, error) {
+	return func(addr string) (net.Conn, error) {
+		conn, err := dial(addr)
+		if err != nil {
+			return nil, err
+		}
+		return &latencyConn{Conn: conn, latency: b.SimulatedLatency, jitter: b.SimulatedJitter}, nil
+	}
+}
+
+// latencyConn delays writes to the underlying connection.
+type latencyConn struct {
+	net.Conn
+	latency time.Duration
+	jitter  time.Duration
+}
+
+func (c *latencyConn) Write(p []byte) (int, error) {
+	d := c.latency
+	if c.jitter > 0 {
+		d += time.Duration(rand.Int63n(int64(2*c.jitter))) - c.jitter
+	}
+	if d > 0 {
+		time.Sleep(d)
+	}
+	return c.Conn.Write(p)
+}
@@ -0,0 +1,30 @@
+package boomer
+
+import (
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestSimulatedLatency(t *testing.T) {
+	b := NewBoomer("127.0.0.1:1", nil).
+		WithSimulatedLatency(50*time.Millisecond, 10*time.Millisecond)
+	server, client := net.Pipe()
+	defer server.Close()
+	go ioutil.ReadAll(server)
+
+	dial := b.dialLatency(func(addr string) (net.Conn, error) {
+		return client, nil
+	})
+	conn, err := dial("127.0.0.1:1")
+	if err != nil {
+		t.Fatalf("Unexpected dial error: %v", err)
+	}
+	defer conn.Close()
+	s := time.Now()
+	conn.Write([]byte("GET / HTTP/1.1\r\n\r\n"))
+	if d := time.Now().Sub(s); d < 40*time.Millisecond {
+		t.Errorf("Expected write to be delayed at least 40ms, took %v", d)
+	}
+}
@@ -0,0 +1,164 @@
+package boomer
+
+import (
+	"fmt"
+	"regexp"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// spinThreshold is how close to a request's scheduled time the trigger loop
+// stops sleeping and spins, timers are not precise enough below it.
+const spinThreshold = 200 * time.Microsecond
+
+var rateRegexp = regexp.MustCompile(`^(\d+(?:\.\d+)?)/(\w+)$`)
+
+// ParseRate parses a rate like 300/m into an amount of requests per period,
+// the unit may be s, m, h or any duration like 10s.
+func ParseRate(s string) (float64, time.Duration, error) {
+	match := rateRegexp.FindStringSubmatch(s)
+	if match == nil {
+		return 0, 0, fmt.Errorf("could not parse the provided rate; input = %v", s)
+	}
+	count, err := strconv.ParseFloat(match[1], 64)
+	if err != nil || count <= 0 {
+		return 0, 0, fmt.Errorf("rate must be positive; input = %v", s)
+	}
+	switch match[2] {
+	case "s":
+		return count, time.Second, nil
+	case "m":
+		return count, time.Minute, nil
+	case "h":
+		return count, time.Hour, nil
+	}
+	per, err := time.ParseDuration(match[2])
+	if err != nil || per <= 0 {
+		return 0, 0, fmt.Errorf("rate unit must be s, m, h or a positive duration; input = %v", s)
+	}
+	return count, per, nil
+}
+
+// Pacing determines how rate limited requests are spread over time.
+type Pacing int
+
+const (
+	// PacingUniform sends every request at its own evenly spaced time.
+	PacingUniform Pacing = iota
+	// PacingBurst sends the requests of every rate window at once, then
+	// waits for the next window.
+	PacingBurst
+)
+
+// ParsePacing returns the pacing named by s, uniform or burst.
+func ParsePacing(s string) (Pacing, error) {
+	switch s {
+	case "uniform":
+		return PacingUniform, nil
+	case "burst":
+		return PacingBurst, nil
+	}
+	return 0, fmt.Errorf("unknown pacing %q, must be uniform or burst", s)
+}
+
+// WithPacing determines how rate limited requests are spread over time.
+func (b *Boomer) WithPacing(p Pacing) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.Pacing = p
+	return b
+}
+
+// due returns when the i-th request is scheduled to be sent, zero when not
+// rate limited nor following a trace.
+func (b *Boomer) due(start time.Time, i uint) time.Time {
+	switch {
+	case b.trace != nil:
+		return b.trace.due(start, i)
+	case b.RateInterval == 0 || b.RatePerWorker:
+		return time.Time{}
+	case b.Pacing == PacingBurst:
+		return start.Add(time.Duration(i/b.rateN) * b.rateWindow)
+	default:
+		// A burst is sent right away, as if the first requests were due
+		// at the start.
+		if b.RateBurst > 1 {
+			if i < b.RateBurst-1 {
+				i = 0
+			} else {
+				i -= b.RateBurst - 1
+			}
+		}
+		return start.Add(time.Duration(i) * b.RateInterval)
+	}
+}
+
+// WithRateBurst lets up to n rate limited requests be sent at once, at the
+// start or to catch up when behind schedule, before pacing kicks in. 1
+// keeps traffic strictly smooth, 0 catches up without limit.
+func (b *Boomer) WithRateBurst(n uint) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.RateBurst = n
+	return b
+}
+
+// burstFloor returns the earliest time a request can be due so that no
+// more than RateBurst are sent at once, zero when unlimited.
+func (b *Boomer) burstFloor() time.Time {
+	if b.RateBurst == 0 || b.RateInterval == 0 || b.RatePerWorker || b.trace != nil || b.Pacing == PacingBurst {
+		return time.Time{}
+	}
+	return b.clock.Now().Add(-time.Duration(b.RateBurst-1) * b.RateInterval)
+}
+
+// WithRatePerWorker makes the rate limit apply to each worker on its own,
+// every worker then sends its requests evenly spaced at that rate instead of
+// sharing a single aggregate schedule.
+func (b *Boomer) WithRatePerWorker(perWorker bool) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.RatePerWorker = perWorker
+	return b
+}
+
+// workerDue returns when the n-th request of worker vu is scheduled to be
+// sent with a per worker rate. Workers are staggered over the interval so
+// they don't all send at once.
+func (b *Boomer) workerDue(start time.Time, vu int, n uint) time.Time {
+	offset := b.RateInterval * time.Duration(vu-1) / time.Duration(b.C)
+	return start.Add(offset + time.Duration(n)*b.RateInterval)
+}
+
+// waitUntil blocks until t, sleeping most of the wait and spinning the
+// rest of it on the real clock. It returns false if Boomer was stopped
+// meanwhile.
+func (b *Boomer) waitUntil(t time.Time) bool {
+	_, spin := b.clock.(realClock)
+	for {
+		d := t.Sub(b.clock.Now())
+		if d <= 0 {
+			return true
+		}
+		if spin && d <= spinThreshold {
+			runtime.Gosched()
+			continue
+		}
+		if spin {
+			d -= spinThreshold
+		}
+		// A timer, stopped on the way out, so waits cut short by Stop don't
+		// leave one behind.
+		timer := b.clock.NewTimer(d)
+		select {
+		case <-b.stop:
+			timer.Stop()
+			return false
+		case <-timer.Chan():
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package boomer_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mercadolibre/pla/boomer"
+	"github.com/mercadolibre/pla/boomertest"
+	"github.com/valyala/fasthttp"
+)
+
+func TestParsePacing(t *testing.T) {
+	if p, err := boomer.ParsePacing("uniform"); err != nil || p != boomer.PacingUniform {
+		t.Errorf("Expected uniform pacing, found %v %v", p, err)
+	}
+	if p, err := boomer.ParsePacing("burst"); err != nil || p != boomer.PacingBurst {
+		t.Errorf("Expected burst pacing, found %v %v", p, err)
+	}
+	if _, err := boomer.ParsePacing("random"); err == nil {
+		t.Errorf("An unknown pacing passed parsing")
+	}
+}
+
+// spread returns the time between the first and last requests sent with
+// the given pacing.
+func spread(pacing boomer.Pacing) time.Duration {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	b := boomer.NewBoomer(string(req.Host()), req).
+		WithAmount(5).
+		WithConcurrency(5).
+		WithRateLimit(5, 500*time.Millisecond).
+		WithPacing(pacing)
+	var first, last time.Time
+	done := make(chan struct{})
+	go func() {
+		for res := range b.Results() {
+			if first.IsZero() || res.Start.Before(first) {
+				first = res.Start
+			}
+			if res.Start.After(last) {
+				last = res.Start
+			}
+		}
+		close(done)
+	}()
+	b.Run()
+	b.Wait()
+	<-done
+	return last.Sub(first)
+}
+
+func TestPacing(t *testing.T) {
+	if d := spread(boomer.PacingUniform); d < 350*time.Millisecond {
+		t.Errorf("Expected uniform pacing to spread requests over 400ms, found %v", d)
+	}
+	if d := spread(boomer.PacingBurst); d > 100*time.Millisecond {
+		t.Errorf("Expected burst pacing to send requests at once, found %v", d)
+	}
+}
+
+func TestRateBurst(t *testing.T) {
+	clock := newClock()
+	start := clock.Now()
+	b := boomer.NewBoomer("example.com:80", newRequest()).
+		WithAmount(6).
+		WithConcurrency(1).
+		WithRateLimit(10, time.Second).
+		WithRateBurst(3).
+		WithClock(clock).
+		WithDoer(&boomertest.Doer{})
+	b.Run()
+	for i := 0; i < 3; i++ {
+		if res := <-b.Results(); !res.Start.Equal(start) {
+			t.Errorf("Expected request %d to be sent right away in the burst, found %v", i, res.Start.Sub(start))
+		}
+	}
+	for clock.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	// Fall a second behind, only a burst of 3 catches up.
+	clock.Advance(time.Second)
+	for i, lag := range []time.Duration{200 * time.Millisecond, 100 * time.Millisecond, 0} {
+		if res := <-b.Results(); res.Lag != lag {
+			t.Errorf("Expected catching up request %d to lag %v, found %v", i+3, lag, res.Lag)
+		}
+	}
+	b.Wait()
+}
+
+func TestRatePerWorker(t *testing.T) {
+	clock := newClock()
+	start := clock.Now()
+	b := boomer.NewBoomer("example.com:80", newRequest()).
+		WithAmount(4).
+		WithConcurrency(2).
+		WithRateLimit(1, time.Second).
+		WithRatePerWorker(true).
+		WithClock(clock).
+		WithDoer(&boomertest.Doer{})
+	b.Run()
+	// Each worker sends one request per second, the second one half a
+	// second after the first.
+	for _, at := range []time.Duration{0, 500 * time.Millisecond, time.Second, 1500 * time.Millisecond} {
+		for clock.Now().Before(start.Add(at)) {
+			for clock.Waiters() == 0 {
+				time.Sleep(time.Millisecond)
+			}
+			clock.Advance(500 * time.Millisecond)
+		}
+		res := <-b.Results()
+		if !res.Start.Equal(start.Add(at)) || res.Lag != 0 {
+			t.Errorf("Expected a request sent on schedule at %v, found %v lagging %v", at, res.Start.Sub(start), res.Lag)
+		}
+	}
+	b.Wait()
+}
+
+func TestRateLimitStop(t *testing.T) {
+	clock := newClock()
+	b := boomer.NewBoomer("example.com:80", newRequest()).
+		WithAmount(10).
+		WithConcurrency(1).
+		WithRateLimit(1, time.Hour).
+		WithClock(clock).
+		WithDoer(&boomertest.Doer{})
+	go func() {
+		for range b.Results() {
+		}
+	}()
+	b.Run()
+	for clock.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	b.Stop()
+	b.Wait()
+	if n := clock.Waiters(); n != 0 {
+		t.Errorf("Expected stopping to stop the timer waiting for the next request, found %d waiting", n)
+	}
+}
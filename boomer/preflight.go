@@ -0,0 +1,68 @@
+package boomer
+
+import (
+	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// preflight sends CORS preflights before a share of the requests.
+type preflight struct {
+	// sent is first to keep it aligned for atomic operations.
+	sent    uint64
+	share   float64
+	origin  string
+	headers string
+}
+
+// WithPreflight makes Boomer send an OPTIONS preflight, as browsers do for
+// CORS, before share of the requests, between 0 and 1, evenly spread. Every
+// request gets an Origin of origin, preflights ask for its method and for
+// headers, a comma separated list which may be empty. Results report the
+// latency of their preflight apart, requests are sent even when it fails.
+func (b *Boomer) WithPreflight(share float64, origin, headers string) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.preflight = nil
+	if share > 0 {
+		b.preflight = &preflight{share: share, origin: origin, headers: headers}
+	}
+	return b
+}
+
+// due tells whether the next request needs a preflight.
+func (p *preflight) due() bool {
+	n := float64(atomic.AddUint64(&p.sent, 1))
+	return math.Floor(n*p.share) > math.Floor((n-1)*p.share)
+}
+
+// send sends the preflight of req, returning its latency and whether it
+// failed.
+func (p *preflight) send(b *Boomer, req *fasthttp.Request, resp *fasthttp.Response) (time.Duration, error) {
+	pre := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(pre)
+	pre.SetRequestURI(string(req.URI().FullURI()))
+	pre.Header.SetHost(string(req.Header.Host()))
+	pre.Header.SetMethod("OPTIONS")
+	pre.Header.Set("Origin", p.origin)
+	pre.Header.Set("Access-Control-Request-Method", string(req.Header.Method()))
+	if p.headers != "" {
+		pre.Header.Set("Access-Control-Request-Headers", p.headers)
+	}
+	res := b.do(pre, resp)
+	switch {
+	// Assertions are meant for the responses of the requests, so only
+	// errors without a response count.
+	case res.StatusCode == 0:
+		return res.Duration, res.Err
+	case res.StatusCode < 200 || res.StatusCode >= 300:
+		return res.Duration, fmt.Errorf("preflight responded with status %d", res.StatusCode)
+	case len(resp.Header.Peek("Access-Control-Allow-Origin")) == 0:
+		return res.Duration, fmt.Errorf("preflight response has no Access-Control-Allow-Origin")
+	}
+	return res.Duration, nil
+}
@@ -0,0 +1,132 @@
+package boomer
+
+import (
+	"crypto/tls"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Prepare resolves the target and opens every connection the test will
+// use, with their TLS handshakes, before it starts, so one-time costs do
+// not skew short runs. It returns how many connections were opened, and
+// the first error found opening them. SSE, streaming and custom Doers are
+// not prepared. Connections left idle longer than the client keeps idle
+// ones, ex: waiting for a start time, are replaced by new ones when the
+// test starts, as the target may have closed them.
+func (b *Boomer) Prepare() (int, error) {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	if b.SSE || b.Stream || b.client != nil {
+		return 0, nil
+	}
+	atomic.StoreInt32(&b.state, stateWarmup)
+	defer atomic.StoreInt32(&b.state, stateIdle)
+	host, port, err := net.SplitHostPort(b.Addr)
+	if err != nil {
+		return 0, err
+	}
+	ips, err := net.LookupHost(host)
+	if err != nil {
+		return 0, err
+	}
+	addrs := []string{b.Addr}
+	if b.DNSFanout {
+		addrs = addrs[:0]
+		for _, ip := range ips {
+			addrs = append(addrs, net.JoinHostPort(ip, port))
+		}
+	}
+	n := int(b.C)
+	if b.Pipeline > 0 {
+		n = int((b.C+b.Pipeline-1)/b.Pipeline) * len(addrs)
+	}
+
+	b.prepared = make(map[string]chan preparedConn, len(addrs))
+	for _, addr := range addrs {
+		b.prepared[addr] = make(chan preparedConn, n)
+	}
+	dial := b.dialTLS(b.dialer(), host)
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	var opened int
+	var firstErr error
+	for i := 0; i < n; i++ {
+		addr := addrs[i%len(addrs)]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := dial(addr)
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			opened++
+			b.prepared[addr] <- preparedConn{Conn: conn, opened: b.clock.Now()}
+		}()
+	}
+	wg.Wait()
+	return opened, firstErr
+}
+
+// preparedConn is a connection opened by Prepare, idle since opened.
+type preparedConn struct {
+	net.Conn
+	opened time.Time
+}
+
+// dialPrepared hands out the connections opened by Prepare before dialing
+// new ones, closing those idle for too long instead.
+func (b *Boomer) dialPrepared(dial func(addr string) (net.Conn, error)) func(addr string) (net.Conn, error) {
+	maxIdle := b.ClientOptions.MaxIdleConnDuration
+	if maxIdle == 0 {
+		maxIdle = fasthttp.DefaultMaxIdleConnDuration
+	}
+	return func(addr string) (net.Conn, error) {
+		for {
+			select {
+			case conn := <-b.prepared[addr]:
+				if b.clock.Now().Sub(conn.opened) < maxIdle {
+					return conn.Conn, nil
+				}
+				conn.Close()
+			default:
+				return dial(addr)
+			}
+		}
+	}
+}
+
+// dialTLS makes TLS handshakes on the connections of dial, when the target
+// uses TLS, so they can be made ahead of time. Clients using it must not
+// make them again.
+func (b *Boomer) dialTLS(dial func(addr string) (net.Conn, error), serverName string) func(addr string) (net.Conn, error) {
+	if !b.TLS() {
+		return dial
+	}
+	config := b.newTLSConfig(serverName)
+	return func(addr string) (net.Conn, error) {
+		conn, err := dial(addr)
+		if err != nil {
+			return nil, err
+		}
+		if b.ConnectTimeout > 0 {
+			conn.SetDeadline(time.Now().Add(b.ConnectTimeout))
+		}
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn.SetDeadline(time.Time{})
+		return tlsConn, nil
+	}
+}
@@ -0,0 +1,73 @@
+package boomer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func testPrepare(t *testing.T, server *httptest.Server, stale bool) {
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	boomer := NewBoomer(string(req.Host()), req).
+		WithAmount(30).
+		WithConcurrency(3)
+	opened, err := boomer.Prepare()
+	if opened != 3 || err != nil {
+		t.Fatalf("Expected 3 prepared connections, found %d %v", opened, err)
+	}
+	want := 3
+	if stale {
+		// As if waiting to start for longer than connections are kept idle.
+		for addr, conns := range boomer.prepared {
+			aged := make(chan preparedConn, cap(conns))
+			for len(conns) > 0 {
+				conn := <-conns
+				conn.opened = conn.opened.Add(-time.Hour)
+				aged <- conn
+			}
+			boomer.prepared[addr] = aged
+		}
+		want = 6
+	}
+	var failures int
+	done := make(chan struct{})
+	go func() {
+		for res := range boomer.Results() {
+			if failed(res) {
+				failures++
+			}
+		}
+		close(done)
+	}()
+	boomer.Run()
+	boomer.Wait()
+	<-done
+	if failures > 0 {
+		t.Errorf("Expected requests over prepared connections to succeed, %d failed", failures)
+	}
+	if stats := boomer.ConnStats(); stats.Opened != want {
+		t.Errorf("Expected %d connections opened, with the prepared ones, found %d", want, stats.Opened)
+	}
+}
+
+func TestPrepare(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	testPrepare(t, server, false)
+}
+
+func TestPrepareTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	testPrepare(t, server, false)
+}
+
+func TestPrepareStale(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	testPrepare(t, server, true)
+}
@@ -0,0 +1,66 @@
+package boomer
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+)
+
+// Profile is a preset of client settings and concurrency suited to a kind
+// of test.
+type Profile int
+
+const (
+	// ProfileDefault keeps the fasthttp defaults and a worker per core.
+	ProfileDefault Profile = iota
+	// ProfileHighThroughput uses large buffers, so fewer reads and writes
+	// move each response, and several workers per core to keep them busy.
+	ProfileHighThroughput
+	// ProfileLowLatency uses a worker per core, so requests don't queue
+	// for the CPU, and the default buffers.
+	ProfileLowLatency
+	// ProfileManyConnections uses small buffers, so thousands of open
+	// connections fit in memory, keeps idle ones open and uses enough
+	// workers to open them.
+	ProfileManyConnections
+)
+
+// ParseProfile returns the profile named by s, default, high-throughput,
+// low-latency or many-connections.
+func ParseProfile(s string) (Profile, error) {
+	switch s {
+	case "", "default":
+		return ProfileDefault, nil
+	case "high-throughput":
+		return ProfileHighThroughput, nil
+	case "low-latency":
+		return ProfileLowLatency, nil
+	case "many-connections":
+		return ProfileManyConnections, nil
+	}
+	return 0, fmt.Errorf("unknown profile %q, must be high-throughput, low-latency or many-connections", s)
+}
+
+// ClientOptions returns the client settings of the profile.
+func (p Profile) ClientOptions() ClientOptions {
+	switch p {
+	case ProfileHighThroughput:
+		return ClientOptions{ReadBufferSize: 64 << 10, WriteBufferSize: 64 << 10}
+	case ProfileManyConnections:
+		return ClientOptions{ReadBufferSize: 1 << 10, WriteBufferSize: 1 << 10, MaxIdleConnDuration: time.Minute}
+	}
+	return ClientOptions{}
+}
+
+// Concurrency returns the amount of workers the profile uses when none is
+// given.
+func (p Profile) Concurrency() uint {
+	cpus := uint(runtime.NumCPU())
+	switch p {
+	case ProfileHighThroughput:
+		return 4 * cpus
+	case ProfileManyConnections:
+		return 1000
+	}
+	return cpus
+}
@@ -0,0 +1,32 @@
+package boomer
+
+import "testing"
+
+func TestParseProfile(t *testing.T) {
+	for s, want := range map[string]Profile{
+		"":                 ProfileDefault,
+		"high-throughput":  ProfileHighThroughput,
+		"low-latency":      ProfileLowLatency,
+		"many-connections": ProfileManyConnections,
+	} {
+		if p, err := ParseProfile(s); err != nil || p != want {
+			t.Errorf("Expected %q to parse as profile %d, found %d: %v", s, want, p, err)
+		}
+	}
+	if _, err := ParseProfile("fast"); err == nil {
+		t.Errorf("An unknown profile passed parsing")
+	}
+}
+
+func TestProfileClientOptions(t *testing.T) {
+	if opts := ProfileDefault.ClientOptions(); opts != (ClientOptions{}) {
+		t.Errorf("Expected the default profile to keep the fasthttp defaults, found %+v", opts)
+	}
+	high, many := ProfileHighThroughput.ClientOptions(), ProfileManyConnections.ClientOptions()
+	if high.ReadBufferSize <= many.ReadBufferSize || high.WriteBufferSize <= many.WriteBufferSize {
+		t.Errorf("Expected high throughput buffers to be larger than many connections ones, found %+v and %+v", high, many)
+	}
+	if ProfileHighThroughput.Concurrency() <= ProfileLowLatency.Concurrency() {
+		t.Errorf("Expected high throughput to use more workers than low latency")
+	}
+}
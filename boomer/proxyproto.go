@@ -0,0 +1,78 @@
+package boomer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sync/atomic"
+)
+
+var proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// WithProxyProtocol makes Boomer start every connection with a PROXY
+// protocol header of the given version (1 or 2), as load balancers do. The
+// source addresses are used in a round robin fashion, when none is given
+// the actual local address of the connection is sent.
+func (b *Boomer) WithProxyProtocol(version int, sources []*net.TCPAddr) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.ProxyProtocol = version
+	b.ProxySources = sources
+	return b
+}
+
+func (b *Boomer) dialProxyProtocol(dial func(addr string) (net.Conn, error)) func(addr string) (net.Conn, error) {
+	return func(addr string) (net.Conn, error) {
+		conn, err := dial(addr)
+		if err != nil {
+			return nil, err
+		}
+		src := conn.LocalAddr().(*net.TCPAddr)
+		if len(b.ProxySources) > 0 {
+			i := atomic.AddUint64(&b.proxySeq, 1)
+			src = b.ProxySources[i%uint64(len(b.ProxySources))]
+		}
+		header := proxyHeader(b.ProxyProtocol, src, conn.RemoteAddr().(*net.TCPAddr))
+		if _, err := conn.Write(header); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return conn, nil
+	}
+}
+
+// proxyHeader builds a PROXY protocol header, addresses of different
+// families are both sent as IPv6.
+func proxyHeader(version int, src, dst *net.TCPAddr) []byte {
+	srcIP, dstIP := src.IP.To4(), dst.IP.To4()
+	if srcIP == nil || dstIP == nil {
+		srcIP, dstIP = src.IP.To16(), dst.IP.To16()
+	}
+	if version == 1 {
+		family := "TCP4"
+		if len(srcIP) == net.IPv6len {
+			family = "TCP6"
+		}
+		return []byte(fmt.Sprintf("PROXY %s %s %s %d %d\r\n", family, srcIP, dstIP, src.Port, dst.Port))
+	}
+
+	var buf bytes.Buffer
+	buf.Write(proxyV2Signature)
+	// Version 2, PROXY command.
+	buf.WriteByte(0x21)
+	if len(srcIP) == net.IPv4len {
+		// AF_INET over STREAM.
+		buf.WriteByte(0x11)
+	} else {
+		// AF_INET6 over STREAM.
+		buf.WriteByte(0x21)
+	}
+	binary.Write(&buf, binary.BigEndian, uint16(2*len(srcIP)+4))
+	buf.Write(srcIP)
+	buf.Write(dstIP)
+	binary.Write(&buf, binary.BigEndian, uint16(src.Port))
+	binary.Write(&buf, binary.BigEndian, uint16(dst.Port))
+	return buf.Bytes()
+}
@@ -0,0 +1,35 @@
+package boomer
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+func TestProxyHeaderV1(t *testing.T) {
+	src := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 12345}
+	dst := &net.TCPAddr{IP: net.ParseIP("10.0.0.2"), Port: 80}
+	if h := string(proxyHeader(1, src, dst)); h != "PROXY TCP4 10.0.0.1 10.0.0.2 12345 80\r\n" {
+		t.Errorf("Unexpected PROXY v1 header: %q", h)
+	}
+	dst = &net.TCPAddr{IP: net.ParseIP("::1"), Port: 443}
+	if h := string(proxyHeader(1, src, dst)); h != "PROXY TCP6 ::ffff:10.0.0.1 ::1 12345 443\r\n" {
+		t.Errorf("Unexpected PROXY v1 header: %q", h)
+	}
+}
+
+func TestProxyHeaderV2(t *testing.T) {
+	src := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 12345}
+	dst := &net.TCPAddr{IP: net.ParseIP("10.0.0.2"), Port: 80}
+	h := proxyHeader(2, src, dst)
+	expected := append([]byte{}, proxyV2Signature...)
+	expected = append(expected,
+		0x21, 0x11, 0x00, 0x0c,
+		10, 0, 0, 1,
+		10, 0, 0, 2,
+		0x30, 0x39,
+		0x00, 0x50)
+	if !bytes.Equal(h, expected) {
+		t.Errorf("Unexpected PROXY v2 header: %x", h)
+	}
+}
@@ -0,0 +1,130 @@
+package boomer
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	// quotaMinScale is the smallest fraction of the configured rate the
+	// quota backoff slows down to.
+	quotaMinScale = 1.0 / 64
+	// quotaDefaultWait is how long requests pause after a 429 response
+	// without a usable Retry-After header.
+	quotaDefaultWait = time.Second
+)
+
+// QuotaStats describes how Boomer backed off from the target's quota.
+type QuotaStats struct {
+	// Limited is the amount of 429 responses, Accepted of any other.
+	Limited, Accepted uint64
+	// Backoffs is how many times the offered rate was halved.
+	Backoffs int
+	// Paused is the time no requests were sent because of Retry-After.
+	Paused time.Duration
+	// Scale is the fraction of the configured rate offered at the end.
+	Scale float64
+}
+
+// quota halves the offered rate, at most once per window, whenever the
+// target responds 429 Too Many Requests, and pauses sending for as long as
+// its Retry-After asks. The rate doubles again every window without them.
+type quota struct {
+	lock   sync.Mutex
+	window time.Duration
+	stats  QuotaStats
+	until  time.Time
+	cut    time.Time
+	epoch  uint64
+}
+
+// WithQuotaBackoff makes Boomer honor 429 responses by backing off the
+// offered rate and pausing for their Retry-After, to probe quotas without
+// hammering past them.
+func (b *Boomer) WithQuotaBackoff(enabled bool) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.quota = nil
+	if enabled {
+		b.quota = &quota{stats: QuotaStats{Scale: 1}}
+	}
+	return b
+}
+
+// QuotaStats returns how Boomer backed off so far, zero when disabled.
+func (b *Boomer) QuotaStats() QuotaStats {
+	if b.quota == nil {
+		return QuotaStats{}
+	}
+	b.quota.lock.Lock()
+	defer b.quota.lock.Unlock()
+	return b.quota.stats
+}
+
+// init sets the window to the rate limit's, or a second when not limited.
+func (q *quota) init(window time.Duration) {
+	q.window = window
+	if q.window == 0 {
+		q.window = time.Second
+	}
+}
+
+func (q *quota) record(now time.Time, resp *fasthttp.Response) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if resp.StatusCode() != fasthttp.StatusTooManyRequests {
+		q.stats.Accepted++
+		return
+	}
+	q.stats.Limited++
+	if until := now.Add(retryAfter(now, resp)); until.After(q.until) {
+		from := q.until
+		if from.Before(now) {
+			from = now
+		}
+		q.stats.Paused += until.Sub(from)
+		q.until = until
+		q.epoch++
+	}
+	if now.Sub(q.cut) >= q.window && q.stats.Scale > quotaMinScale {
+		q.stats.Scale /= 2
+		q.stats.Backoffs++
+		q.epoch++
+	}
+	q.cut = now
+}
+
+func (q *quota) state(now time.Time) (uint64, float64, time.Time) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if q.stats.Scale < 1 && now.Sub(q.cut) >= q.window {
+		q.stats.Scale *= 2
+		if q.stats.Scale > 1 {
+			q.stats.Scale = 1
+		}
+		q.cut = now
+		q.epoch++
+	}
+	return q.epoch, q.stats.Scale, q.until
+}
+
+// retryAfter returns how long resp asks to wait, in seconds or as an HTTP
+// date.
+func retryAfter(now time.Time, resp *fasthttp.Response) time.Duration {
+	value := resp.Header.Peek("Retry-After")
+	if secs, err := strconv.Atoi(string(value)); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if date, err := http.ParseTime(string(value)); err == nil {
+		if wait := date.Sub(now); wait > 0 {
+			return wait
+		}
+		return 0
+	}
+	return quotaDefaultWait
+}
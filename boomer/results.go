@@ -0,0 +1,325 @@
+package boomer
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ResultsPolicy determines what workers do with results the consumer of
+// Results is not ready to receive.
+type ResultsPolicy int
+
+const (
+	// ResultsBlock makes workers wait for the consumer, which throttles the
+	// test itself when the consumer is slow.
+	ResultsBlock ResultsPolicy = iota
+	// ResultsDrop discards results the consumer is not ready for.
+	ResultsDrop
+	// ResultsSpill writes results the consumer is not ready for to a
+	// temporary file, delivering them as soon as it catches up.
+	ResultsSpill
+	// ResultsDropOldest discards the oldest buffered results to make room
+	// for new ones, so the consumer always gets the latest. Without a
+	// buffer it discards new ones, like ResultsDrop.
+	ResultsDropOldest
+)
+
+// ParseResultsPolicy returns the policy named by s, block, drop, spill or
+// drop-oldest.
+func ParseResultsPolicy(s string) (ResultsPolicy, error) {
+	switch s {
+	case "block":
+		return ResultsBlock, nil
+	case "drop":
+		return ResultsDrop, nil
+	case "spill":
+		return ResultsSpill, nil
+	case "drop-oldest":
+		return ResultsDropOldest, nil
+	}
+	return 0, fmt.Errorf("unknown results policy %q, must be block, drop, spill or drop-oldest", s)
+}
+
+// ResultsStats tells how results were delivered to the consumer.
+type ResultsStats struct {
+	// Dropped and Spilled are the amount of results discarded or written to
+	// disk because the consumer was not ready for them.
+	Dropped uint64
+	Spilled uint64
+
+	// Blocked is the total time workers waited for the consumer.
+	Blocked time.Duration
+}
+
+// WithResultsPolicy determines what workers do with results the consumer
+// is not ready to receive.
+func (b *Boomer) WithResultsPolicy(p ResultsPolicy) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.ResultsPolicy = p
+	return b
+}
+
+// WithResultsBuffer makes Results buffer up to n results the consumer is
+// not ready to receive before the policy applies, instead of one per
+// worker, so bursts or a slow consumer don't stall workers.
+func (b *Boomer) WithResultsBuffer(n uint) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.ResultsBuffer = n
+	if n == 0 {
+		n = b.C
+	}
+	b.results = make(chan Result, n)
+	return b
+}
+
+// ResultsStats returns how results were delivered so far.
+func (b *Boomer) ResultsStats() ResultsStats {
+	stats := ResultsStats{
+		Dropped: atomic.LoadUint64(&b.dropped),
+		Blocked: time.Duration(atomic.LoadInt64(&b.blocked)),
+	}
+	if b.spill != nil {
+		stats.Spilled = atomic.LoadUint64(&b.spill.written)
+	}
+	return stats
+}
+
+// initSpill starts spilling results to disk, falling back to blocking if no
+// temporary file can be created.
+func (b *Boomer) initSpill() {
+	s, err := newSpill()
+	if err != nil {
+		b.ResultsPolicy = ResultsBlock
+		return
+	}
+	b.spill = s
+	go s.forward(b.results, &b.dropped)
+}
+
+func (b *Boomer) deliver(res Result) {
+	switch {
+	case b.ResultsPolicy == ResultsDropOldest && cap(b.results) > 0:
+		for {
+			select {
+			case b.results <- res:
+				return
+			default:
+			}
+			select {
+			case <-b.results:
+				atomic.AddUint64(&b.dropped, 1)
+			default:
+			}
+		}
+	case b.ResultsPolicy == ResultsDrop, b.ResultsPolicy == ResultsDropOldest:
+		select {
+		case b.results <- res:
+		default:
+			atomic.AddUint64(&b.dropped, 1)
+		}
+	case b.spill != nil:
+		// Results keep going to disk while there are spilled ones, so they
+		// are delivered roughly in order.
+		if b.spill.empty() {
+			select {
+			case b.results <- res:
+				return
+			default:
+			}
+		}
+		if b.spill.write(res) != nil {
+			b.block(res)
+		}
+	default:
+		b.block(res)
+	}
+}
+
+func (b *Boomer) block(res Result) {
+	select {
+	case b.results <- res:
+	default:
+		start := b.clock.Now()
+		b.results <- res
+		atomic.AddInt64(&b.blocked, int64(b.clock.Now().Sub(start)))
+	}
+}
+
+// spilledErrors are the errors compared by identity which are restored when
+// reading spilled results.
+var spilledErrors = map[string]error{
+	ErrStreamClosed.Error(): ErrStreamClosed,
+	ErrCircuitOpen.Error():  ErrCircuitOpen,
+}
+
+// spilledResult is the JSON line a Result is spilled as, errors are kept as
+// their messages.
+type spilledResult struct {
+	Result
+	Err          string         `json:",omitempty"`
+	PreflightErr string         `json:",omitempty"`
+	Shadow       *spilledShadow `json:",omitempty"`
+}
+
+// spilledShadow is how the ShadowResult of a spilled Result is kept.
+type spilledShadow struct {
+	ShadowResult
+	Err string `json:",omitempty"`
+}
+
+func newSpilledResult(res Result) spilledResult {
+	record := spilledResult{
+		Result:       res,
+		Err:          spilledError(res.Err),
+		PreflightErr: spilledError(res.PreflightErr),
+	}
+	if res.Shadow != nil {
+		record.Shadow = &spilledShadow{ShadowResult: *res.Shadow, Err: spilledError(res.Shadow.Err)}
+	}
+	return record
+}
+
+// result restores the spilled Result.
+func (record *spilledResult) result() Result {
+	res := record.Result
+	res.Err = restoredError(record.Err)
+	res.PreflightErr = restoredError(record.PreflightErr)
+	res.Shadow = nil
+	if record.Shadow != nil {
+		shadow := record.Shadow.ShadowResult
+		shadow.Err = restoredError(record.Shadow.Err)
+		res.Shadow = &shadow
+	}
+	return res
+}
+
+func spilledError(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func restoredError(msg string) error {
+	if msg == "" {
+		return nil
+	}
+	if err := spilledErrors[msg]; err != nil {
+		return err
+	}
+	return errors.New(msg)
+}
+
+// spill is an on disk queue of results, written by workers and read back by
+// a single forwarder.
+type spill struct {
+	written uint64
+
+	lock    sync.Mutex
+	file    *os.File
+	reader  *bufio.Reader
+	pending int
+	ready   chan struct{}
+	done    chan struct{}
+	drained chan struct{}
+}
+
+func newSpill() (*spill, error) {
+	file, err := ioutil.TempFile("", "pla-results")
+	if err != nil {
+		return nil, err
+	}
+	reader, err := os.Open(file.Name())
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+	return &spill{
+		file:    file,
+		reader:  bufio.NewReader(reader),
+		ready:   make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		drained: make(chan struct{}),
+	}, nil
+}
+
+func (s *spill) empty() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.pending == 0
+}
+
+func (s *spill) write(res Result) error {
+	line, err := json.Marshal(newSpilledResult(res))
+	if err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	s.pending++
+	atomic.AddUint64(&s.written, 1)
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// forward sends spilled results to out until finish is called and every
+// one of them was delivered. Results which can't be read back are counted
+// in dropped.
+func (s *spill) forward(out chan<- Result, dropped *uint64) {
+	defer close(s.drained)
+	for {
+		if s.empty() {
+			select {
+			case <-s.ready:
+				continue
+			case <-s.done:
+				if s.empty() {
+					return
+				}
+				continue
+			}
+		}
+		// Only whole lines are counted as pending, so this never reads a
+		// partially written one.
+		line, err := s.reader.ReadBytes('\n')
+		if err != nil {
+			return
+		}
+		var record spilledResult
+		if json.Unmarshal(line, &record) == nil {
+			out <- record.result()
+		} else {
+			atomic.AddUint64(dropped, 1)
+		}
+		s.lock.Lock()
+		s.pending--
+		s.lock.Unlock()
+	}
+}
+
+// finish waits for every spilled result to be delivered and removes the
+// spill file, workers must not write to it anymore.
+func (s *spill) finish() {
+	close(s.done)
+	<-s.drained
+	s.file.Close()
+	os.Remove(s.file.Name())
+}
@@ -0,0 +1,85 @@
+package boomer
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseResultsPolicy(t *testing.T) {
+	for name, policy := range map[string]ResultsPolicy{"block": ResultsBlock, "drop": ResultsDrop, "spill": ResultsSpill, "drop-oldest": ResultsDropOldest} {
+		if p, err := ParseResultsPolicy(name); err != nil || p != policy {
+			t.Errorf("Expected %v to be parsed as %v, found %v %v", name, policy, p, err)
+		}
+	}
+	if _, err := ParseResultsPolicy("buffer"); err == nil {
+		t.Errorf("An unknown results policy passed parsing")
+	}
+}
+
+func TestDropOldest(t *testing.T) {
+	b := NewBoomer("example.org:80", nil).
+		WithResultsBuffer(2).
+		WithConcurrency(8).
+		WithResultsPolicy(ResultsDropOldest)
+	for i := 1; i <= 5; i++ {
+		b.deliver(Result{StatusCode: i})
+	}
+	if first, second := <-b.results, <-b.results; first.StatusCode != 4 || second.StatusCode != 5 {
+		t.Errorf("Expected the latest results 4 and 5 to be kept, found %v and %v", first.StatusCode, second.StatusCode)
+	}
+	if dropped := b.ResultsStats().Dropped; dropped != 3 {
+		t.Errorf("Expected 3 dropped results, found %v", dropped)
+	}
+}
+
+func TestSpill(t *testing.T) {
+	s, err := newSpill()
+	if err != nil {
+		t.Fatalf("Could not create spill: %v", err)
+	}
+	out := make(chan Result)
+	var dropped uint64
+	go s.forward(out, &dropped)
+	written := []Result{
+		{StatusCode: 200, Duration: time.Second, Label: "first"},
+		{Err: ErrStreamClosed},
+		{Err: errors.New("connection refused")},
+		{StatusCode: 200, PreflightErr: errors.New("preflight rejected"), Shadow: &ShadowResult{Err: errors.New("shadow timeout")}},
+	}
+	for _, res := range written {
+		if err := s.write(res); err != nil {
+			t.Fatalf("Could not spill result: %v", err)
+		}
+	}
+	var read []Result
+	done := make(chan struct{})
+	go func() {
+		for res := range out {
+			read = append(read, res)
+		}
+		close(done)
+	}()
+	s.finish()
+	close(out)
+	<-done
+	if len(read) != 4 || atomic.LoadUint64(&dropped) != 0 {
+		t.Fatalf("Expected 4 spilled results, found %d and %d dropped", len(read), dropped)
+	}
+	if read[0].StatusCode != 200 || read[0].Duration != time.Second || read[0].Label != "first" {
+		t.Errorf("Result was not restored correctly: %v", read[0])
+	}
+	if read[1].Err != ErrStreamClosed {
+		t.Errorf("Expected known errors to be restored, found %v", read[1].Err)
+	}
+	if read[2].Err == nil || read[2].Err.Error() != "connection refused" {
+		t.Errorf("Expected error messages to be kept, found %v", read[2].Err)
+	}
+	if read[3].PreflightErr == nil || read[3].PreflightErr.Error() != "preflight rejected" {
+		t.Errorf("Expected preflight errors to be kept, found %v", read[3].PreflightErr)
+	}
+	if read[3].Shadow == nil || read[3].Shadow.Err == nil || read[3].Shadow.Err.Error() != "shadow timeout" {
+		t.Errorf("Expected shadow errors to be kept, found %v", read[3].Shadow)
+	}
+}
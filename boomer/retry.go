@@ -0,0 +1,45 @@
+package boomer
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/valyala/fasthttp"
+)
+
+// WithRetries makes Boomer send failed requests again, up to n more times.
+// Durations then span every attempt of the request.
+func (b *Boomer) WithRetries(n uint) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.Retries = n
+	return b
+}
+
+// WithIdempotencyKey makes Boomer set a unique key in the header named
+// header for every logical request, kept across its retries, so duplicated
+// operations can be detected at the server.
+func (b *Boomer) WithIdempotencyKey(header string) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.IdempotencyHeader = header
+	return b
+}
+
+func (b *Boomer) doWithRetries(req *fasthttp.Request, resp *fasthttp.Response) Result {
+	if b.IdempotencyHeader != "" {
+		req.Header.Set(b.IdempotencyHeader, fmt.Sprintf("%016x%016x", rand.Int63(), rand.Int63()))
+	}
+	res := b.do(req, resp)
+	res.Attempts = 1
+	for res.Attempts <= int(b.Retries) && failed(res) && !b.stopped() {
+		d := res.Duration
+		attempts := res.Attempts
+		res = b.do(req, resp)
+		res.Duration += d
+		res.Attempts = attempts + 1
+	}
+	return res
+}
@@ -0,0 +1,87 @@
+package boomer
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/valyala/fasthttp"
+)
+
+// samples saves the responses of failed requests to a directory, up to an
+// amount of files and of bytes in total.
+type samples struct {
+	lock     sync.Mutex
+	dir      string
+	maxCount int
+	maxBytes int64
+	count    int
+	bytes    int64
+	err      error
+}
+
+// WithFailureSamples saves the status, headers and body of up to maxCount
+// responses of failed requests, and maxBytes in total, to files in dir for
+// debugging after the run. An empty dir disables it.
+func (b *Boomer) WithFailureSamples(dir string, maxCount int, maxBytes int64) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.samples = nil
+	if dir != "" {
+		b.samples = &samples{dir: dir, maxCount: maxCount, maxBytes: maxBytes}
+	}
+	return b
+}
+
+// SampleStats tells how many failed responses were saved to Dir, and the
+// error which stopped saving them, if any.
+type SampleStats struct {
+	Dir   string
+	Saved int
+	Err   error
+}
+
+// FailureSamples returns how many failed responses were saved so far.
+func (b *Boomer) FailureSamples() SampleStats {
+	if b.samples == nil {
+		return SampleStats{}
+	}
+	b.samples.lock.Lock()
+	defer b.samples.lock.Unlock()
+	return SampleStats{Dir: b.samples.dir, Saved: b.samples.count, Err: b.samples.err}
+}
+
+// save writes resp to a new file, preceded by the request line and the
+// failure, unless the caps were reached.
+func (s *samples) save(req *fasthttp.Request, resp *fasthttp.Response, res Result) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s %s", req.Header.Method(), req.RequestURI())
+	if res.Err != nil {
+		fmt.Fprintf(&buf, " -> %v", res.Err)
+	}
+	buf.WriteString("\n\n")
+	buf.Write(resp.Header.Header())
+	buf.Write(resp.Body())
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.err != nil || s.count >= s.maxCount || s.bytes+int64(buf.Len()) > s.maxBytes {
+		return
+	}
+	if s.count == 0 {
+		if s.err = os.MkdirAll(s.dir, 0755); s.err != nil {
+			return
+		}
+	}
+	s.count++
+	name := filepath.Join(s.dir, fmt.Sprintf("failure-%04d.http", s.count))
+	if s.err = ioutil.WriteFile(name, buf.Bytes(), 0644); s.err != nil {
+		s.count--
+		return
+	}
+	s.bytes += int64(buf.Len())
+}
@@ -0,0 +1,71 @@
+package boomer
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestFailureSamples(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Trace", "abc")
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte("upstream down"))
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "pla-samples")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL + "/items")
+	boomer := NewBoomer(string(req.Host()), req).
+		WithAmount(5).
+		WithConcurrency(1).
+		WithFailureSamples(dir, 3, 1<<20)
+	go func() {
+		for range boomer.Results() {
+		}
+	}()
+	boomer.Run()
+	boomer.Wait()
+
+	if stats := boomer.FailureSamples(); stats.Saved != 3 || stats.Err != nil {
+		t.Errorf("Expected 3 saved responses, found %d %v", stats.Saved, stats.Err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.http"))
+	if len(files) != 3 {
+		t.Fatalf("Expected 3 files, found %v", files)
+	}
+	sample, _ := ioutil.ReadFile(files[0])
+	for _, expected := range []string{"GET /items", "502", "X-Trace: abc", "upstream down"} {
+		if !strings.Contains(string(sample), expected) {
+			t.Errorf("Expected the sample to contain %q, found %q", expected, sample)
+		}
+	}
+}
+
+func TestFailureSamplesSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pla-samples")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := &samples{dir: dir, maxCount: 10, maxBytes: 10}
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	resp.SetBodyString("a body longer than ten bytes")
+	s.save(req, resp, Result{StatusCode: 500})
+	if s.count != 0 {
+		t.Errorf("Expected samples over the size cap not to be saved")
+	}
+}
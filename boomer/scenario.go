@@ -0,0 +1,196 @@
+package boomer
+
+import (
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Scenario is a user journey, every iteration of it sends its steps in
+// order from the same worker, so they share its session. Weight is its
+// share of the iterations when mixed with others.
+type Scenario struct {
+	Name   string
+	Weight uint
+	Steps  []*WeightedRequest
+}
+
+// WithScenario makes Boomer run iterations of s instead of single requests.
+// The amount and rate limits then count iterations.
+func (b *Boomer) WithScenario(s *Scenario) *Boomer {
+	return b.WithScenarios([]*Scenario{s})
+}
+
+// WithScenarios makes Boomer run iterations of scenarios, each picked
+// randomly according to their weights, instead of single requests.
+func (b *Boomer) WithScenarios(scenarios []*Scenario) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.scenarios = scenarios
+	b.scenariosTotal = 0
+	for _, s := range scenarios {
+		b.scenariosTotal += s.Weight
+	}
+	return b
+}
+
+// WithIterationPacing makes every worker start a new iteration of the
+// scenario each d, however long the previous one took, so iterations
+// arrive at a stable rate. Workers running late start right away, skipping
+// the iterations they missed instead of bursting to catch up.
+func (b *Boomer) WithIterationPacing(d time.Duration) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.IterationPacing = d
+	return b
+}
+
+// Scenarios returns the scenarios Boomer runs, nil when it runs single
+// requests.
+func (b *Boomer) Scenarios() []*Scenario {
+	return b.scenarios
+}
+
+// nextScenario picks the scenario of the next iteration, it is only called
+// from the trigger loop, like nextRequest.
+func (b *Boomer) nextScenario() *Scenario {
+	if len(b.scenarios) == 0 {
+		return nil
+	}
+	if len(b.scenarios) == 1 || b.scenariosTotal == 0 {
+		return b.scenarios[0]
+	}
+	n := uint(b.rand.Int63n(int64(b.scenariosTotal)))
+	for _, s := range b.scenarios {
+		if n < s.Weight {
+			return s
+		}
+		n -= s.Weight
+	}
+	return b.scenarios[len(b.scenarios)-1]
+}
+
+// runIteration sends the steps of the scenario of j, the first one when j
+// is due. Iterations in progress finish when Boomer is stopped, but do not
+// send more steps.
+func (b *Boomer) runIteration(vu int, j job, factory RequestFactory, resp *fasthttp.Response, sess *session) bool {
+	it := &iteration{start: b.clock.Now(), steps: len(j.scenario.Steps)}
+	for step := 1; step != 0; step = it.next {
+		if step > 1 || it.attempt > 0 {
+			select {
+			case <-b.stop:
+				return true
+			default:
+			}
+		}
+		w := j.scenario.Steps[step-1]
+		if !b.runJob(vu, job{w: w, due: j.due, step: step, iteration: it, scenario: j.scenario}, factory, resp, sess) {
+			return false
+		}
+		// Only the first step was scheduled.
+		j.due = time.Time{}
+		if it.next == step {
+			it.attempt++
+		} else {
+			it.attempt = 0
+		}
+	}
+	return true
+}
+
+// iterationDue returns when worker vu starts its n-th paced iteration,
+// advancing n past the iterations already missed at now. Workers are
+// staggered over the pacing so they don't all start at once.
+func (b *Boomer) iterationDue(start, now time.Time, vu int, n *uint) time.Time {
+	offset := b.IterationPacing * time.Duration(vu-1) / time.Duration(b.C)
+	if late := now.Sub(start.Add(offset + time.Duration(*n)*b.IterationPacing)); late > b.IterationPacing {
+		*n += uint(late / b.IterationPacing)
+	}
+	due := start.Add(offset + time.Duration(*n)*b.IterationPacing)
+	*n++
+	return due
+}
+
+// OnFailure is what a scenario iteration does when one of its steps fails.
+type OnFailure int
+
+const (
+	// OnFailureContinue sends the next steps anyway.
+	OnFailureContinue OnFailure = iota
+	// OnFailureAbort ends the iteration, so later steps which depend on the
+	// failed one don't fail in cascade.
+	OnFailureAbort
+	// OnFailureRetry sends the step again, up to its StepRetries times, and
+	// then ends the iteration.
+	OnFailureRetry
+)
+
+// Branch makes a scenario iteration follow with step Next, counted from 1,
+// instead of the next one, or end when Next is 0. It is taken when its
+// step responds with Status, or with any other status when Not. A zero
+// Status, without Not, matches any response.
+type Branch struct {
+	Status int
+	Not    bool
+	Next   int
+}
+
+// expects tells whether the branch is taken on a given status, which then
+// doesn't fail the step.
+func (br *Branch) expects() bool {
+	return br.Status != 0 && !br.Not
+}
+
+// branch returns the first of the branches of w res takes, if any.
+func (w *WeightedRequest) branch(res *Result) *Branch {
+	for i := range w.Branches {
+		br := &w.Branches[i]
+		if br.Status == 0 && !br.Not || (res.StatusCode == br.Status) != br.Not {
+			return br
+		}
+	}
+	return nil
+}
+
+// iteration tracks a scenario iteration in progress.
+type iteration struct {
+	start   time.Time
+	steps   int
+	failed  bool
+	attempt uint
+
+	// next is the step sent after the one recorded, the same one when it
+	// is retried, 0 when the iteration is over.
+	next int
+}
+
+// record accounts for the result of the step-th step, w, picking the next
+// one. The transaction completes when there isn't any.
+func (it *iteration) record(b *Boomer, w *WeightedRequest, step int, res *Result) {
+	it.next = step + 1
+	br := w.branch(res)
+	// Unlike single requests, steps fail with client errors too, as a
+	// rejected login breaks the rest of the journey.
+	if (res.Err != nil || res.StatusCode >= 400) && (br == nil || !br.expects()) {
+		switch {
+		case w.OnFailure == OnFailureRetry && it.attempt < w.StepRetries:
+			it.next = step
+		case w.OnFailure == OnFailureAbort, w.OnFailure == OnFailureRetry:
+			it.failed, it.next = true, 0
+		default:
+			it.failed = true
+		}
+	}
+	if br != nil && it.next == step+1 {
+		it.next = br.Next
+	}
+	if it.next > it.steps {
+		it.next = 0
+	}
+	if it.next == 0 {
+		res.Transaction = b.clock.Now().Sub(it.start)
+		res.TransactionFailed = it.failed
+	}
+}
@@ -0,0 +1,225 @@
+package boomer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestScenario(t *testing.T) {
+	var lock sync.Mutex
+	paths := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		paths[r.URL.Path]++
+		lock.Unlock()
+	}))
+	defer server.Close()
+
+	step := func(path string, budget time.Duration) *WeightedRequest {
+		req := fasthttp.AcquireRequest()
+		req.SetRequestURI(server.URL + path)
+		return &WeightedRequest{Request: req, Weight: 1, Label: "GET " + path, Budget: budget}
+	}
+	scenario := &Scenario{Steps: []*WeightedRequest{
+		step("/items", 0),
+		step("/cart", time.Nanosecond),
+		step("/checkout", time.Hour),
+	}}
+	boomer := NewBoomer(string(scenario.Steps[0].Request.Host()), scenario.Steps[0].Request).
+		WithAmount(4).
+		WithConcurrency(2).
+		WithScenario(scenario)
+	steps := make(map[int]int)
+	var overBudget, transactions int
+	done := make(chan struct{})
+	go func() {
+		for res := range boomer.Results() {
+			steps[res.Step]++
+			if res.OverBudget {
+				overBudget++
+			}
+			if res.Transaction > 0 {
+				transactions++
+				if res.Step != 3 || res.Transaction < res.Duration {
+					t.Errorf("Transaction of step %d is shorter than it, %v", res.Step, res.Transaction)
+				}
+			}
+		}
+		close(done)
+	}()
+	boomer.Run()
+	boomer.Wait()
+	<-done
+	if steps[1] != 4 || steps[2] != 4 || steps[3] != 4 || len(steps) != 3 {
+		t.Errorf("Expected 4 iterations of every step, found %v", steps)
+	}
+	if paths["/items"] != 4 || paths["/cart"] != 4 || paths["/checkout"] != 4 {
+		t.Errorf("Expected every step to be sent 4 times, found %v", paths)
+	}
+	if transactions != 4 {
+		t.Errorf("Expected a transaction per iteration, found %d", transactions)
+	}
+	if overBudget != 4 {
+		t.Errorf("Expected only the step with a tiny budget to breach it, found %d breaches", overBudget)
+	}
+}
+
+func TestIterationDue(t *testing.T) {
+	b := NewBoomer("localhost:80", fasthttp.AcquireRequest()).
+		WithConcurrency(2).
+		WithIterationPacing(4 * time.Second)
+	start := time.Unix(0, 0)
+	var n uint
+	for _, c := range []struct {
+		vu   int
+		now  time.Duration
+		want time.Duration
+	}{
+		{1, 0, 0},
+		{1, 3 * time.Second, 4 * time.Second},
+		// Later than a whole pacing, missed iterations are skipped.
+		{1, 17 * time.Second, 16 * time.Second},
+		{1, 17 * time.Second, 20 * time.Second},
+	} {
+		if due := b.iterationDue(start, start.Add(c.now), c.vu, &n); due.Sub(start) != c.want {
+			t.Errorf("Expected iteration at %v to be due at %v, found %v", c.now, c.want, due.Sub(start))
+		}
+	}
+	n = 0
+	if due := b.iterationDue(start, start, 2, &n); due.Sub(start) != 2*time.Second {
+		t.Errorf("Expected the second worker to be staggered by 2s, found %v", due.Sub(start))
+	}
+}
+
+func TestScenarios(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(server.URL)
+	scenario := func(name string, weight uint) *Scenario {
+		return &Scenario{Name: name, Weight: weight, Steps: []*WeightedRequest{{Request: req, Weight: 1}}}
+	}
+	boomer := NewBoomer(string(req.Host()), req).
+		WithAmount(200).
+		WithConcurrency(2).
+		WithScenarios([]*Scenario{scenario("browse", 3), scenario("checkout", 1)})
+	iterations := make(map[string]int)
+	done := make(chan struct{})
+	go func() {
+		for res := range boomer.Results() {
+			iterations[res.Scenario]++
+		}
+		close(done)
+	}()
+	boomer.Run()
+	boomer.Wait()
+	<-done
+	if iterations["browse"]+iterations["checkout"] != 200 || iterations["browse"] <= iterations["checkout"] {
+		t.Errorf("Expected scenarios to share the iterations according to their weights, found %v", iterations)
+	}
+}
+
+func TestOnFailure(t *testing.T) {
+	b := NewBoomer("localhost:80", fasthttp.AcquireRequest())
+	abort := &WeightedRequest{OnFailure: OnFailureAbort}
+	retry := &WeightedRequest{OnFailure: OnFailureRetry, StepRetries: 2}
+	cont := &WeightedRequest{}
+	for _, c := range []struct {
+		w       *WeightedRequest
+		status  int
+		attempt uint
+		next    int
+	}{
+		{abort, 200, 0, 2},
+		{abort, 401, 0, 0},
+		{cont, 500, 0, 2},
+		{retry, 503, 0, 1},
+		{retry, 503, 1, 1},
+		{retry, 503, 2, 0},
+		{retry, 200, 1, 2},
+	} {
+		it := &iteration{start: b.clock.Now(), steps: 3, attempt: c.attempt}
+		res := Result{StatusCode: c.status}
+		it.record(b, c.w, 1, &res)
+		if it.next != c.next {
+			t.Errorf("Status %d on attempt %d of %v: expected step %d next, found %d", c.status, c.attempt, c.w.OnFailure, c.next, it.next)
+		}
+		if c.next == 0 && (res.Transaction <= 0 || !res.TransactionFailed) {
+			t.Errorf("Expected the aborted iteration to end a failed transaction, found %v", res)
+		}
+	}
+}
+
+func TestBranches(t *testing.T) {
+	b := NewBoomer("localhost:80", fasthttp.AcquireRequest())
+	w := &WeightedRequest{OnFailure: OnFailureAbort, Branches: []Branch{
+		{Status: 404, Next: 3},
+		{Status: 200, Not: true, Next: 0},
+		{Next: 4},
+	}}
+	for _, c := range []struct {
+		status int
+		next   int
+		failed bool
+	}{
+		{404, 3, false},
+		{500, 0, true},
+		{201, 0, false},
+		{200, 4, false},
+	} {
+		it := &iteration{start: b.clock.Now(), steps: 5}
+		res := Result{StatusCode: c.status}
+		it.record(b, w, 1, &res)
+		if it.next != c.next || it.failed != c.failed {
+			t.Errorf("Status %d: expected step %d next and failed %v, found %d and %v", c.status, c.next, c.failed, it.next, it.failed)
+		}
+	}
+}
+
+func TestScenarioCircuitOpen(t *testing.T) {
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI("http://localhost:80")
+	scenario := &Scenario{Steps: []*WeightedRequest{
+		{Request: req, Weight: 1},
+		{Request: req, Weight: 1},
+	}}
+	boomer := NewBoomer(string(req.Host()), req).
+		WithAmount(10).
+		WithConcurrency(1).
+		WithScenario(scenario).
+		WithCircuitBreaker(0.5, time.Hour)
+	for i := 0; i < breakerMinRequests; i++ {
+		boomer.breaker.record(boomer.clock.Now(), true)
+	}
+	var open, transactions int
+	done := make(chan struct{})
+	go func() {
+		for res := range boomer.Results() {
+			if res.Err == ErrCircuitOpen {
+				open++
+			}
+			if res.Transaction > 0 && res.TransactionFailed {
+				transactions++
+			}
+		}
+		close(done)
+	}()
+	go func() {
+		boomer.Run()
+		boomer.Wait()
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected steps refused by the open circuit to move the iteration on")
+	}
+	if open != 20 || transactions != 10 {
+		t.Errorf("Expected 10 failed transactions with every step refused, found %d transactions and %d refused", transactions, open)
+	}
+}
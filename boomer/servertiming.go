@@ -0,0 +1,73 @@
+package boomer
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ServerTiming is a phase of the handling of a request the server reported
+// in a Server-Timing header, ex: db;dur=12.5.
+type ServerTiming struct {
+	Name     string
+	Duration time.Duration
+}
+
+// serverTimings returns the phases of every Server-Timing header of resp
+// with a duration, nil without any.
+func serverTimings(resp *fasthttp.Response) []ServerTiming {
+	var timings []ServerTiming
+	resp.Header.VisitAll(func(key, value []byte) {
+		if bytes.EqualFold(key, []byte("Server-Timing")) {
+			timings = parseServerTiming(string(value), timings)
+		}
+	})
+	return timings
+}
+
+// parseServerTiming appends the metrics of a Server-Timing header value
+// that have a duration, in milliseconds, to timings.
+func parseServerTiming(value string, timings []ServerTiming) []ServerTiming {
+	for _, metric := range splitQuoted(value, ',') {
+		params := splitQuoted(metric, ';')
+		name := strings.TrimSpace(params[0])
+		if name == "" {
+			continue
+		}
+		for _, param := range params[1:] {
+			kv := strings.SplitN(param, "=", 2)
+			if len(kv) != 2 || !strings.EqualFold(strings.TrimSpace(kv[0]), "dur") {
+				continue
+			}
+			ms, err := strconv.ParseFloat(strings.Trim(strings.TrimSpace(kv[1]), `"`), 64)
+			if err == nil && ms >= 0 {
+				timings = append(timings, ServerTiming{Name: name, Duration: time.Duration(ms * float64(time.Millisecond))})
+			}
+			break
+		}
+	}
+	return timings
+}
+
+// splitQuoted splits s around sep, except within double quotes, as the
+// descriptions of metrics may have them.
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	var quoted bool
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quoted:
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
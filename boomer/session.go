@@ -0,0 +1,146 @@
+package boomer
+
+import (
+	"github.com/valyala/fasthttp"
+)
+
+// WithAffinityCookie makes every worker behave as a client session sticking
+// to a backend through the cookie name, which is sent back on subsequent
+// requests. Results report the backend and whether affinity was broken.
+func (b *Boomer) WithAffinityCookie(name string) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.AffinityCookie = name
+	return b
+}
+
+// WithAffinityHeader makes every worker track the backend serving it through
+// the response header name. Results report the backend and whether affinity
+// was broken.
+func (b *Boomer) WithAffinityHeader(name string) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.AffinityHeader = name
+	return b
+}
+
+// WithConditionalRequests makes every worker keep the ETag and
+// Last-Modified validators of the responses it gets, and send them back
+// when requesting the same URL again, so the target can answer 304 Not
+// Modified. Results report which requests were conditional.
+func (b *Boomer) WithConditionalRequests(conditional bool) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.Conditional = conditional
+	return b
+}
+
+// maxValidators is how many URLs a worker keeps validators of, requests to
+// ever changing URLs would otherwise grow them without bound.
+const maxValidators = 1024
+
+// validators are the ETag and Last-Modified of a response.
+type validators struct {
+	etag         string
+	lastModified string
+}
+
+// session keeps the client state of a single worker.
+type session struct {
+	backend  string
+	requests uint
+
+	validators map[string]validators
+	// validated tells whether the request being sent carries validators.
+	validated bool
+}
+
+func (s *session) prepare(b *Boomer, req *fasthttp.Request) {
+	if b.Conditional {
+		s.prepareConditional(req)
+	}
+	if b.AffinityCookie != "" && s.backend != "" {
+		req.Header.SetCookie(b.AffinityCookie, s.backend)
+	}
+	if b.RequestsPerConn > 0 {
+		// Workers hold a connection at a time, so closing it every
+		// RequestsPerConn requests of the worker approximates the limit.
+		s.requests++
+		if s.requests%b.RequestsPerConn == 0 {
+			req.SetConnectionClose()
+		}
+	}
+}
+
+func (s *session) observe(b *Boomer, req *fasthttp.Request, resp *fasthttp.Response, res *Result) {
+	if b.Conditional {
+		s.observeConditional(req, resp, res)
+	}
+	var backend string
+	switch {
+	case b.AffinityHeader != "":
+		backend = string(resp.Header.Peek(b.AffinityHeader))
+	case b.AffinityCookie != "":
+		c := fasthttp.AcquireCookie()
+		c.SetKey(b.AffinityCookie)
+		if resp.Header.Cookie(c) {
+			backend = string(c.Value())
+		}
+		fasthttp.ReleaseCookie(c)
+		if backend == "" {
+			// The cookie is only set again once the backend changes.
+			backend = s.backend
+		}
+	default:
+		return
+	}
+	res.Backend = backend
+	res.AffinityBroken = s.backend != "" && backend != s.backend
+	if backend != "" {
+		s.backend = backend
+	}
+}
+
+// cacheable tells whether validators of req are kept.
+func cacheable(req *fasthttp.Request) bool {
+	return req.Header.IsGet() || req.Header.IsHead()
+}
+
+func (s *session) prepareConditional(req *fasthttp.Request) {
+	s.validated = false
+	if !cacheable(req) {
+		return
+	}
+	v, ok := s.validators[string(req.URI().FullURI())]
+	if !ok {
+		return
+	}
+	if v.etag != "" {
+		req.Header.Set("If-None-Match", v.etag)
+	}
+	if v.lastModified != "" {
+		req.Header.Set("If-Modified-Since", v.lastModified)
+	}
+	s.validated = true
+}
+
+func (s *session) observeConditional(req *fasthttp.Request, resp *fasthttp.Response, res *Result) {
+	res.Validated = s.validated
+	if resp.StatusCode() != fasthttp.StatusOK || !cacheable(req) {
+		return
+	}
+	v := validators{
+		etag:         string(resp.Header.Peek("ETag")),
+		lastModified: string(resp.Header.Peek("Last-Modified")),
+	}
+	if v.etag == "" && v.lastModified == "" {
+		return
+	}
+	if s.validators == nil || len(s.validators) >= maxValidators {
+		s.validators = make(map[string]validators)
+	}
+	s.validators[string(req.URI().FullURI())] = v
+}
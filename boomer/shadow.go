@@ -0,0 +1,103 @@
+package boomer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"net"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ShadowResult is the result of the copy of a request sent to the shadow
+// target.
+type ShadowResult struct {
+	Err        error
+	StatusCode int
+	Duration   time.Duration
+
+	// BodyMatch tells whether both responses had the same body, only set
+	// when comparing bodies.
+	BodyMatch bool
+}
+
+// shadowReply is a ShadowResult along with the hash of its response body.
+type shadowReply struct {
+	res  ShadowResult
+	hash []byte
+}
+
+// WithShadow sends a copy of every request, at the same time, to the
+// candidate at addr with host as its Host header, and reports how it
+// responded in Result.Shadow. bodies compares the SHA-256 hashes of both
+// response bodies too. An empty addr disables it.
+func (b *Boomer) WithShadow(addr, host string, bodies bool) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.ShadowAddr = addr
+	b.ShadowHost = host
+	b.ShadowBodies = bodies
+	return b
+}
+
+func (b *Boomer) initShadow() {
+	serverName, _, err := net.SplitHostPort(b.ShadowAddr)
+	if err != nil {
+		serverName = b.ShadowAddr
+	}
+	b.shadowClient = b.newClient(b.ShadowAddr, serverName)
+}
+
+// startShadow sends a copy of req to the shadow target, the reply is sent
+// on the returned channel.
+func (b *Boomer) startShadow(req *fasthttp.Request) <-chan shadowReply {
+	r := fasthttp.AcquireRequest()
+	req.CopyTo(r)
+	r.URI().SetHost(b.ShadowHost)
+	r.Header.SetHost(b.ShadowHost)
+	c := make(chan shadowReply, 1)
+	go func() {
+		defer fasthttp.ReleaseRequest(r)
+		resp := fasthttp.AcquireResponse()
+		defer fasthttp.ReleaseResponse(resp)
+		var reply shadowReply
+		start := b.clock.Now()
+		var err error
+		if b.Timeout > 0 {
+			err = b.shadowClient.DoTimeout(r, resp, b.Timeout)
+		} else {
+			err = b.shadowClient.Do(r, resp)
+		}
+		reply.res.Duration = b.clock.Now().Sub(start)
+		reply.res.Err = err
+		if err == nil {
+			reply.res.StatusCode = resp.StatusCode()
+			if b.ShadowBodies {
+				reply.hash, reply.res.Err = bodyHash(resp)
+			}
+		}
+		c <- reply
+	}()
+	return c
+}
+
+// finishShadow waits for the reply of the shadow target and compares it to
+// the primary's response.
+func (b *Boomer) finishShadow(c <-chan shadowReply, res Result, resp *fasthttp.Response) *ShadowResult {
+	reply := <-c
+	if b.ShadowBodies && res.Err == nil && reply.res.Err == nil {
+		hash, err := bodyHash(resp)
+		reply.res.BodyMatch = err == nil && bytes.Equal(hash, reply.hash)
+	}
+	return &reply.res
+}
+
+func bodyHash(resp *fasthttp.Response) ([]byte, error) {
+	body, err := ResponseBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(body)
+	return hash[:], nil
+}
@@ -0,0 +1,89 @@
+package boomer
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sschepens/gohistogram"
+)
+
+// Snapshot holds live statistics of a running test.
+type Snapshot struct {
+	Status
+
+	// Requests counts every result so far, Errors those which failed.
+	Requests, Errors uint64
+	ErrorRate        float64
+	// RPS is the amount of results per second since the test started.
+	RPS float64
+
+	// Mean and the percentiles of the latencies of successful requests,
+	// only set WithSnapshotLatencies.
+	Mean, P50, P90, P99 time.Duration
+}
+
+// live aggregates results as they are notified, for Snapshot. Counting is
+// lock free and sharded by worker, only latencies, when kept, are added
+// under lock.
+type live struct {
+	requests  counter
+	errors    counter
+	keep      bool
+	lock      sync.Mutex
+	latencies *gohistogram.NumericHistogram
+}
+
+// WithSnapshotLatencies makes snapshots include the mean and percentiles of
+// latencies, which every result then adds to a histogram shared by every
+// worker.
+func (b *Boomer) WithSnapshotLatencies(enabled bool) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.live.keep = enabled
+	return b
+}
+
+func (l *live) record(vu int, res Result) {
+	// Requests first, so snapshots never find more errors than requests.
+	l.requests.add(vu)
+	if failed(res) {
+		l.errors.add(vu)
+		return
+	}
+	if !l.keep {
+		return
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.latencies == nil {
+		l.latencies = gohistogram.NewHistogram(20)
+	}
+	l.latencies.Add(res.Duration.Seconds())
+}
+
+// Snapshot returns live statistics of the test, it is safe to call at any
+// time from any goroutine.
+func (b *Boomer) Snapshot() Snapshot {
+	s := Snapshot{Status: b.Status()}
+	s.Errors = b.live.errors.load()
+	s.Requests = b.live.requests.load()
+	b.live.lock.Lock()
+	defer b.live.lock.Unlock()
+	if s.Requests > 0 {
+		s.ErrorRate = float64(s.Errors) / float64(s.Requests)
+	}
+	if s.Elapsed > 0 {
+		s.RPS = float64(s.Requests) / s.Elapsed.Seconds()
+	}
+	if h := b.live.latencies; h != nil && h.Count() > 0 {
+		secs := func(v float64) time.Duration {
+			return time.Duration(v * float64(time.Second))
+		}
+		s.Mean = secs(h.Mean())
+		s.P50 = secs(h.Quantile(0.5))
+		s.P90 = secs(h.Quantile(0.9))
+		s.P99 = secs(h.Quantile(0.99))
+	}
+	return s
+}
@@ -0,0 +1,35 @@
+package boomer_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mercadolibre/pla/boomer"
+	"github.com/mercadolibre/pla/boomertest"
+)
+
+func TestSnapshot(t *testing.T) {
+	clock := newClock()
+	b := boomer.NewBoomer("example.com:80", newRequest()).
+		WithAmount(4).
+		WithConcurrency(1).
+		WithClock(clock).
+		WithDoer(&boomertest.Doer{Statuses: []int{200, 200, 200, 503}})
+	done := make(chan struct{})
+	go func() {
+		for range b.Results() {
+		}
+		close(done)
+	}()
+	b.Run()
+	b.Wait()
+	<-done
+	clock.Advance(2 * time.Second)
+	s := b.Snapshot()
+	if s.Requests != 4 || s.Errors != 1 || s.ErrorRate != 0.25 {
+		t.Errorf("Expected 4 requests with 1 error, found %+v", s)
+	}
+	if s.RPS != 2 {
+		t.Errorf("Expected 2 requests per second, found %v", s.RPS)
+	}
+}
@@ -0,0 +1,93 @@
+package boomer
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ErrStreamClosed is reported when the server ends an event stream before
+// the test finished.
+var ErrStreamClosed = errors.New("event stream closed by server")
+
+// WithSSE makes Boomer open Server-Sent Events streams instead of performing
+// one-shot requests, each of the C workers keeps one stream open at a time.
+func (b *Boomer) WithSSE(sse bool) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.SSE = sse
+	return b
+}
+
+func (b *Boomer) doSSE(r *fasthttp.Request) Result {
+	req, err := newStreamRequest(r)
+	if err != nil {
+		return Result{Err: err}
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	s := b.clock.Now()
+	resp, err := b.streamClient.Do(req)
+	if err != nil {
+		return Result{Err: err, Duration: b.clock.Now().Sub(s)}
+	}
+	defer resp.Body.Close()
+
+	res := Result{StatusCode: resp.StatusCode}
+	if resp.StatusCode != http.StatusOK {
+		res.Duration = b.clock.Now().Sub(s)
+		return res
+	}
+
+	done := b.closeOnStop(resp.Body)
+	defer close(done)
+
+	err = b.countEvents(resp.Body, s, &res)
+	res.Duration = b.clock.Now().Sub(s)
+
+	if !b.stopped() {
+		if err != io.EOF {
+			res.Err = err
+		} else {
+			res.Err = ErrStreamClosed
+		}
+	}
+	return res
+}
+
+// countEvents counts the events of a stream read from r on res, start being
+// when it was opened, until reading fails, with io.EOF once the server ends
+// it. Events are dispatched by a blank line, comment lines don't count.
+// Lines are read in slices, so data lines of any length fit, only how they
+// start matters.
+func (b *Boomer) countEvents(r io.Reader, start time.Time, res *Result) error {
+	var pending, partial bool
+	var err error
+	reader := bufio.NewReader(r)
+	for err == nil || err == bufio.ErrBufferFull {
+		var line []byte
+		line, err = reader.ReadSlice('\n')
+		if len(line) > 0 && !partial {
+			switch {
+			case len(bytes.TrimRight(line, "\r\n")) == 0:
+				if pending {
+					if res.Events == 0 {
+						res.FirstEvent = b.clock.Now().Sub(start)
+					}
+					res.Events++
+					pending = false
+				}
+			case line[0] != ':':
+				pending = true
+			}
+		}
+		partial = err == bufio.ErrBufferFull
+	}
+	return err
+}
@@ -0,0 +1,101 @@
+package boomer
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Phase is the part of the load profile Boomer is going through.
+type Phase int32
+
+const (
+	// PhaseIdle is before Boomer runs.
+	PhaseIdle Phase = iota
+	// PhaseWarmup is while Prepare opens connections.
+	PhaseWarmup
+	// PhaseRamp is while the offered rate increases.
+	PhaseRamp
+	// PhaseSteady is while the offered rate holds.
+	PhaseSteady
+	// PhaseRampDown is while the offered rate decreases, or requests in
+	// flight finish once no more are sent.
+	PhaseRampDown
+	// PhaseDone is once every request finished.
+	PhaseDone
+)
+
+var phaseNames = [...]string{"idle", "warm-up", "ramp", "steady", "ramp-down", "done"}
+
+func (p Phase) String() string {
+	return phaseNames[p]
+}
+
+// States stored by Boomer, ramps and the steady phase are told apart by
+// the load profile while running.
+const (
+	stateIdle int32 = iota
+	stateWarmup
+	stateRunning
+	stateDraining
+	stateDone
+)
+
+// Status tells where Boomer is in the test.
+type Status struct {
+	Phase   Phase
+	Elapsed time.Duration
+	// Remaining is an estimate of the time left, -1 when unknown.
+	Remaining time.Duration
+	// Sent is the amount of requests handed to workers so far.
+	Sent uint64
+}
+
+// Status returns where Boomer is in the test, it is safe to call at any time.
+func (b *Boomer) Status() Status {
+	s := Status{Remaining: -1, Sent: atomic.LoadUint64(&b.sent)}
+	if started, ok := b.started.Load().(time.Time); ok {
+		s.Elapsed = b.clock.Now().Sub(started)
+	}
+	switch atomic.LoadInt32(&b.state) {
+	case stateIdle:
+		s.Phase = PhaseIdle
+		return s
+	case stateWarmup:
+		s.Phase = PhaseWarmup
+		return s
+	case stateDraining:
+		s.Phase = PhaseRampDown
+	case stateDone:
+		s.Phase = PhaseDone
+		s.Remaining = 0
+		return s
+	default:
+		s.Phase = PhaseSteady
+		if b.trace != nil {
+			switch slope := b.trace.slope(s.Elapsed); {
+			case slope > 0:
+				s.Phase = PhaseRamp
+			case slope < 0:
+				s.Phase = PhaseRampDown
+			}
+		}
+	}
+
+	switch {
+	case b.Duration > 0:
+		s.Remaining = positive(b.Duration - s.Elapsed)
+	case b.trace != nil && b.N == 0:
+		s.Remaining = positive(b.trace.points[len(b.trace.points)-1].At - s.Elapsed)
+	case b.N > 0 && s.Sent > 0:
+		// Assume the rest is sent at the rate so far.
+		s.Remaining = positive(time.Duration(float64(s.Elapsed) * (float64(b.N) - float64(s.Sent)) / float64(s.Sent)))
+	}
+	return s
+}
+
+func positive(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
@@ -0,0 +1,39 @@
+package boomer_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mercadolibre/pla/boomer"
+	"github.com/mercadolibre/pla/boomertest"
+)
+
+func TestStatus(t *testing.T) {
+	clock := newClock()
+	b := boomer.NewBoomer("example.com:80", newRequest()).
+		WithDuration(time.Hour).
+		WithConcurrency(1).
+		WithRateLimit(1, time.Second).
+		WithClock(clock).
+		WithDoer(&boomertest.Doer{})
+	if s := b.Status(); s.Phase != boomer.PhaseIdle || s.Remaining != -1 {
+		t.Errorf("Expected an idle status before running, found %+v", s)
+	}
+	done := make(chan struct{})
+	go func() {
+		for range b.Results() {
+		}
+		close(done)
+	}()
+	b.Run()
+	clock.Advance(15 * time.Minute)
+	if s := b.Status(); s.Phase != boomer.PhaseSteady || s.Elapsed != 15*time.Minute || s.Remaining != 45*time.Minute {
+		t.Errorf("Expected a steady status with 45m left, found %+v", s)
+	}
+	clock.Advance(45 * time.Minute)
+	b.Wait()
+	<-done
+	if s := b.Status(); s.Phase != boomer.PhaseDone || s.Remaining != 0 {
+		t.Errorf("Expected a done status, found %+v", s)
+	}
+}
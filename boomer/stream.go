@@ -0,0 +1,104 @@
+package boomer
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+
+	"github.com/valyala/fasthttp"
+)
+
+// WithStreaming makes Boomer consume response bodies as they arrive, the
+// time to first byte and the total duration are then reported separately.
+func (b *Boomer) WithStreaming(stream bool) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.Stream = stream
+	return b
+}
+
+// fasthttp buffers whole responses, so streams are consumed with net/http.
+func (b *Boomer) newStreamClient() *http.Client {
+	dial := b.dialer()
+	return &http.Client{
+		Transport: &http.Transport{
+			Dial: func(network, addr string) (net.Conn, error) {
+				return dial(addr)
+			},
+			TLSClientConfig:       b.newTLSConfig(""),
+			MaxIdleConnsPerHost:   int(b.C),
+			DisableCompression:    true,
+			ResponseHeaderTimeout: b.Timeout,
+		},
+	}
+}
+
+func newStreamRequest(r *fasthttp.Request) (*http.Request, error) {
+	req, err := http.NewRequest(string(r.Header.Method()), string(r.URI().FullURI()), bytes.NewReader(r.Body()))
+	if err != nil {
+		return nil, err
+	}
+	r.Header.VisitAll(func(k, v []byte) {
+		if string(k) == "Host" {
+			req.Host = string(v)
+			return
+		}
+		req.Header.Set(string(k), string(v))
+	})
+	return req, nil
+}
+
+// closeOnStop closes body as soon as the test is stopped, unblocking any
+// reader. The returned channel must be closed once the body is consumed.
+func (b *Boomer) closeOnStop(body io.Closer) chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-b.stop:
+			body.Close()
+		case <-done:
+		}
+	}()
+	return done
+}
+
+func (b *Boomer) stopped() bool {
+	select {
+	case <-b.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+func (b *Boomer) doStream(r *fasthttp.Request) Result {
+	req, err := newStreamRequest(r)
+	if err != nil {
+		return Result{Err: err}
+	}
+
+	s := b.clock.Now()
+	resp, err := b.streamClient.Do(req)
+	if err != nil {
+		return Result{Err: err, Duration: b.clock.Now().Sub(s)}
+	}
+	defer resp.Body.Close()
+
+	res := Result{
+		StatusCode: resp.StatusCode,
+		FirstByte:  b.clock.Now().Sub(s),
+	}
+	done := b.closeOnStop(resp.Body)
+	defer close(done)
+
+	size, err := io.Copy(ioutil.Discard, resp.Body)
+	res.Duration = b.clock.Now().Sub(s)
+	res.ContentLength = int(size)
+	if err != nil && !b.stopped() {
+		res.Err = err
+	}
+	return res
+}
@@ -0,0 +1,122 @@
+package boomer
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+var tlsVersions = map[uint16]string{
+	tls.VersionSSL30: "SSL 3.0",
+	tls.VersionTLS10: "TLS 1.0",
+	tls.VersionTLS11: "TLS 1.1",
+	tls.VersionTLS12: "TLS 1.2",
+	tls.VersionTLS13: "TLS 1.3",
+}
+
+var curves = map[string]tls.CurveID{
+	"X25519":         tls.X25519,
+	"P-256":          tls.CurveP256,
+	"P-384":          tls.CurveP384,
+	"P-521":          tls.CurveP521,
+	"X25519MLKEM768": tls.X25519MLKEM768,
+}
+
+// ParseCurves parses a comma separated list of curve names, ex: X25519,P-256.
+func ParseCurves(names string) ([]tls.CurveID, error) {
+	var ids []tls.CurveID
+	for _, name := range strings.Split(names, ",") {
+		id, ok := curves[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown curve; curve = %v", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// TLSInfo describes the TLS connection negotiated with the target.
+type TLSInfo struct {
+	Version     string
+	CipherSuite string
+	// Protocol is the protocol negotiated through ALPN, if any.
+	Protocol string
+
+	// Subject and NotAfter describe the leaf certificate of the target.
+	Subject  string
+	NotAfter time.Time
+
+	// OCSPStapled tells whether the target stapled an OCSP response to the
+	// handshake, sparing clients a request to the certificate authority.
+	OCSPStapled bool
+}
+
+// TLS reports whether requests are made over TLS.
+func (b *Boomer) TLS() bool {
+	return string(b.Request.URI().Scheme()) == "https"
+}
+
+// TLSInfo returns the details of a TLS handshake made with the target before
+// starting the test, or the error that prevented it.
+func (b *Boomer) TLSInfo() (*TLSInfo, error) {
+	return b.tlsInfo, b.tlsErr
+}
+
+// WithCurvePreferences sets the curves offered on TLS handshakes, in order
+// of preference.
+func (b *Boomer) WithCurvePreferences(curves []tls.CurveID) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.CurvePreferences = curves
+	return b
+}
+
+func (b *Boomer) newTLSConfig(serverName string) *tls.Config {
+	config := &tls.Config{InsecureSkipVerify: true}
+	if b.TLSConfig != nil {
+		config = b.TLSConfig.Clone()
+	}
+	if serverName != "" {
+		config.ServerName = serverName
+	}
+	if b.CurvePreferences != nil {
+		config.CurvePreferences = b.CurvePreferences
+	}
+	return config
+}
+
+func (b *Boomer) probeTLS() {
+	host, _, err := net.SplitHostPort(b.Addr)
+	if err != nil {
+		b.tlsErr = err
+		return
+	}
+	config := b.newTLSConfig(host)
+	config.NextProtos = []string{"h2", "http/1.1"}
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: b.ConnectTimeout}, "tcp", b.Addr, config)
+	if err != nil {
+		b.tlsErr = err
+		return
+	}
+	defer conn.Close()
+
+	state := conn.ConnectionState()
+	info := &TLSInfo{
+		Version:     tlsVersions[state.Version],
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		Protocol:    state.NegotiatedProtocol,
+		OCSPStapled: len(state.OCSPResponse) > 0,
+	}
+	if info.Version == "" {
+		info.Version = fmt.Sprintf("0x%04x", state.Version)
+	}
+	if len(state.PeerCertificates) > 0 {
+		cert := state.PeerCertificates[0]
+		info.Subject = cert.Subject.CommonName
+		info.NotAfter = cert.NotAfter
+	}
+	b.tlsInfo = info
+}
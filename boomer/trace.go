@@ -0,0 +1,99 @@
+package boomer
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+)
+
+// TracePoint is the rate, in requests per second, a trace asks for at a
+// time since its start.
+type TracePoint struct {
+	At  time.Duration
+	RPS float64
+}
+
+// trace schedules requests following a rate interpolated linearly between
+// its points. counts holds how many requests are due by every point.
+type trace struct {
+	points []TracePoint
+	counts []float64
+}
+
+// WithRPSTrace makes Boomer follow the rate of points, interpolating
+// between them, instead of a fixed rate limit. The test ends with the trace
+// unless amount or duration end it earlier.
+func (b *Boomer) WithRPSTrace(points []TracePoint) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.trace = nil
+	if len(points) > 0 {
+		b.trace = newTrace(points)
+	}
+	return b
+}
+
+// ValidateTrace checks that points can be followed: at least two of them,
+// in increasing time order, with rates that are not negative.
+func ValidateTrace(points []TracePoint) error {
+	if len(points) < 2 {
+		return errors.New("trace must have at least two points")
+	}
+	for i, p := range points {
+		if p.RPS < 0 {
+			return errors.New("trace rates cannot be negative")
+		}
+		if i > 0 && p.At <= points[i-1].At {
+			return errors.New("trace timestamps must be increasing")
+		}
+	}
+	return nil
+}
+
+func newTrace(points []TracePoint) *trace {
+	t := &trace{points: points, counts: make([]float64, len(points))}
+	for i := 1; i < len(points); i++ {
+		p, q := points[i-1], points[i]
+		t.counts[i] = t.counts[i-1] + (p.RPS+q.RPS)/2*(q.At-p.At).Seconds()
+	}
+	return t
+}
+
+// within reports whether the trace asks for an i-th request at all.
+func (t *trace) within(i uint) bool {
+	return float64(i) < t.counts[len(t.counts)-1]
+}
+
+// due returns when the i-th request of the trace is due, solving the
+// integral of the rate over the segment it falls into.
+func (t *trace) due(start time.Time, i uint) time.Time {
+	c := float64(i)
+	k := sort.Search(len(t.counts), func(k int) bool { return t.counts[k] > c })
+	if k == 0 || k == len(t.counts) {
+		return start.Add(t.points[len(t.points)-1].At)
+	}
+	p, q := t.points[k-1], t.points[k]
+	c -= t.counts[k-1]
+	span := (q.At - p.At).Seconds()
+	slope := (q.RPS - p.RPS) / span
+	var x float64
+	if slope == 0 {
+		x = c / p.RPS
+	} else {
+		x = (math.Sqrt(math.Max(0, p.RPS*p.RPS+2*slope*c)) - p.RPS) / slope
+	}
+	return start.Add(p.At + time.Duration(x*float64(time.Second)))
+}
+
+// slope returns how fast the rate of the trace changes at a time since its
+// start, in requests per second per second.
+func (t *trace) slope(at time.Duration) float64 {
+	k := sort.Search(len(t.points), func(k int) bool { return t.points[k].At > at })
+	if k == 0 || k == len(t.points) {
+		return 0
+	}
+	p, q := t.points[k-1], t.points[k]
+	return (q.RPS - p.RPS) / (q.At - p.At).Seconds()
+}
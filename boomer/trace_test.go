@@ -0,0 +1,57 @@
+package boomer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTraceDue(t *testing.T) {
+	start := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	flat := newTrace([]TracePoint{{0, 10}, {10 * time.Second, 10}})
+	if due := flat.due(start, 25); due.Sub(start) != 2500*time.Millisecond {
+		t.Errorf("Expected request 25 of a flat trace at 2.5s, found %v", due.Sub(start))
+	}
+
+	// A rate of 2t requests per second has t^2 requests due by t.
+	ramp := newTrace([]TracePoint{{0, 0}, {10 * time.Second, 20}})
+	for i, expected := range map[uint]time.Duration{0: 0, 4: 2 * time.Second, 81: 9 * time.Second} {
+		if d := ramp.due(start, i).Sub(start) - expected; d < -time.Millisecond || d > time.Millisecond {
+			t.Errorf("Expected request %d of a ramp at %v, found %v", i, expected, expected+d)
+		}
+	}
+	if !ramp.within(99) || ramp.within(100) {
+		t.Errorf("Expected the ramp to hold 100 requests")
+	}
+}
+
+func TestTraceGap(t *testing.T) {
+	start := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	gap := newTrace([]TracePoint{{0, 1}, {time.Second, 1}, {2 * time.Second, 0}, {3 * time.Second, 0}, {4 * time.Second, 2}, {5 * time.Second, 2}})
+	// 1.5 requests are due by 2s, the third one half a request into the
+	// ramp starting at 3s.
+	if due := gap.due(start, 2).Sub(start); due < 3707*time.Millisecond || due > 3708*time.Millisecond {
+		t.Errorf("Expected requests to skip the idle part of the trace, found %v", due)
+	}
+}
+
+func TestValidateTrace(t *testing.T) {
+	for _, points := range [][]TracePoint{
+		{{0, 1}},
+		{{0, 1}, {0, 2}},
+		{{time.Second, 1}, {0, 2}},
+		{{0, 1}, {time.Second, -1}},
+	} {
+		if err := ValidateTrace(points); err == nil {
+			t.Errorf("An invalid trace passed validation: %v", points)
+		}
+	}
+}
+
+func TestTraceSlope(t *testing.T) {
+	trace := newTrace([]TracePoint{{0, 0}, {10 * time.Second, 20}, {20 * time.Second, 20}, {30 * time.Second, 0}})
+	for at, expected := range map[time.Duration]float64{5 * time.Second: 2, 15 * time.Second: 0, 25 * time.Second: -2, time.Minute: 0} {
+		if slope := trace.slope(at); slope != expected {
+			t.Errorf("Expected a slope of %v at %v, found %v", expected, at, slope)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package boomer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/valyala/fasthttp"
+)
+
+// WithTracePropagation makes every request start a trace, sending a W3C
+// traceparent header with a new trace id, sampled, which results report so
+// slow requests can be looked up in the tracing backend of the target.
+func (b *Boomer) WithTracePropagation(propagate bool) *Boomer {
+	if b.running {
+		panic("Cannot modify boomer while running")
+	}
+	b.TracePropagation = propagate
+	return b
+}
+
+// propagateTrace sets a traceparent header with a new trace on req,
+// returning its id.
+func propagateTrace(req *fasthttp.Request) string {
+	var ids [24]byte
+	for {
+		// Unlike math/rand, it is safe for concurrent use and differs
+		// across runs and processes.
+		rand.Read(ids[:])
+		// All zero ids are invalid.
+		if !zero(ids[:16]) && !zero(ids[16:]) {
+			break
+		}
+	}
+	id := hex.EncodeToString(ids[:16])
+	req.Header.Set("traceparent", "00-"+id+"-"+hex.EncodeToString(ids[16:])+"-01")
+	return id
+}
+
+func zero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
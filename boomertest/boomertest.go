@@ -0,0 +1,132 @@
+// Package boomertest provides fakes to test code built on boomer, such as
+// reporting and load profiles, without sockets nor real time.
+package boomertest
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/mercadolibre/pla/boomer"
+	"github.com/valyala/fasthttp"
+)
+
+// ErrInjected is the error of failed requests when Doer.Err is not set.
+var ErrInjected = errors.New("injected error")
+
+// Doer is an in-memory boomer.Doer which answers requests without a network.
+type Doer struct {
+	// Latency returns how long each request takes, nil answers right away.
+	Latency func() time.Duration
+
+	// Statuses are the status codes of consecutive responses, cycling
+	// through them. Empty answers 200.
+	Statuses []int
+
+	// ErrorRate is the fraction of requests failing with Err.
+	ErrorRate float64
+	Err       error
+
+	// Body is the body of every response.
+	Body []byte
+
+	// Clock, when set, is waited on for latencies instead of sleeping.
+	Clock boomer.Clock
+
+	lock     sync.Mutex
+	requests int
+}
+
+// Do answers req in resp after the configured latency.
+func (d *Doer) Do(req *fasthttp.Request, resp *fasthttp.Response) error {
+	return d.DoTimeout(req, resp, 0)
+}
+
+// DoTimeout answers req in resp after the configured latency, failing with
+// fasthttp.ErrTimeout when it exceeds timeout.
+func (d *Doer) DoTimeout(req *fasthttp.Request, resp *fasthttp.Response, timeout time.Duration) error {
+	d.lock.Lock()
+	n := d.requests
+	d.requests++
+	d.lock.Unlock()
+
+	var latency time.Duration
+	if d.Latency != nil {
+		latency = d.Latency()
+	}
+	if timeout > 0 && latency > timeout {
+		d.wait(timeout)
+		return fasthttp.ErrTimeout
+	}
+	d.wait(latency)
+	if d.ErrorRate > 0 && rand.Float64() < d.ErrorRate {
+		if d.Err != nil {
+			return d.Err
+		}
+		return ErrInjected
+	}
+	status := fasthttp.StatusOK
+	if len(d.Statuses) > 0 {
+		status = d.Statuses[n%len(d.Statuses)]
+	}
+	resp.SetStatusCode(status)
+	resp.SetBody(d.Body)
+	return nil
+}
+
+// Requests returns the amount of requests answered so far.
+func (d *Doer) Requests() int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.requests
+}
+
+func (d *Doer) wait(latency time.Duration) {
+	if latency <= 0 {
+		return
+	}
+	if d.Clock != nil {
+		<-d.Clock.After(latency)
+		return
+	}
+	time.Sleep(latency)
+}
+
+// Constant returns a latency distribution which always takes d.
+func Constant(d time.Duration) func() time.Duration {
+	return func() time.Duration {
+		return d
+	}
+}
+
+// Uniform returns a latency distribution evenly spread between min and max,
+// a max below min is taken as min.
+func Uniform(min, max time.Duration) func() time.Duration {
+	if max < min {
+		max = min
+	}
+	return func() time.Duration {
+		return min + time.Duration(rand.Int63n(int64(max-min)+1))
+	}
+}
+
+// Normal returns a normal latency distribution, negative latencies are
+// taken as 0.
+func Normal(mean, stddev time.Duration) func() time.Duration {
+	return func() time.Duration {
+		d := mean + time.Duration(rand.NormFloat64()*float64(stddev))
+		if d < 0 {
+			return 0
+		}
+		return d
+	}
+}
+
+// Exponential returns an exponential latency distribution, with a long tail
+// of slow requests.
+func Exponential(mean time.Duration) func() time.Duration {
+	return func() time.Duration {
+		return time.Duration(rand.ExpFloat64() * float64(mean))
+	}
+}
@@ -0,0 +1,61 @@
+package boomertest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestDoerStatuses(t *testing.T) {
+	d := &Doer{Statuses: []int{200, 500, 404}}
+	req, resp := fasthttp.AcquireRequest(), fasthttp.AcquireResponse()
+	for i, status := range []int{200, 500, 404, 200} {
+		if err := d.Do(req, resp); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if resp.StatusCode() != status {
+			t.Errorf("Expected response %d to be %d, found %d", i, status, resp.StatusCode())
+		}
+	}
+	if d.Requests() != 4 {
+		t.Errorf("Expected 4 requests, found %d", d.Requests())
+	}
+}
+
+func TestDoerErrors(t *testing.T) {
+	d := &Doer{ErrorRate: 1}
+	if err := d.Do(fasthttp.AcquireRequest(), fasthttp.AcquireResponse()); err != ErrInjected {
+		t.Errorf("Expected an injected error, found %v", err)
+	}
+	clock := NewClock(time.Now())
+	d = &Doer{Latency: Constant(time.Second), Clock: clock}
+	done := make(chan error)
+	go func() {
+		done <- d.DoTimeout(fasthttp.AcquireRequest(), fasthttp.AcquireResponse(), 100*time.Millisecond)
+	}()
+	for clock.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	clock.Advance(100 * time.Millisecond)
+	if err := <-done; err != fasthttp.ErrTimeout {
+		t.Errorf("Expected a timeout, found %v", err)
+	}
+}
+
+func TestLatencies(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		if d := Uniform(time.Millisecond, 2*time.Millisecond)(); d < time.Millisecond || d > 2*time.Millisecond {
+			t.Errorf("Expected a uniform latency between 1ms and 2ms, found %v", d)
+		}
+		if d := Uniform(2*time.Millisecond, time.Millisecond)(); d != 2*time.Millisecond {
+			t.Errorf("Expected a max below min to be taken as min, found %v", d)
+		}
+		if d := Normal(time.Millisecond, 10*time.Millisecond)(); d < 0 {
+			t.Errorf("Expected no negative latencies, found %v", d)
+		}
+		if d := Exponential(time.Millisecond)(); d < 0 {
+			t.Errorf("Expected no negative latencies, found %v", d)
+		}
+	}
+}
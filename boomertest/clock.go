@@ -0,0 +1,109 @@
+package boomertest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mercadolibre/pla/boomer"
+)
+
+// Clock is a boomer.Clock which only moves when advanced, so tests run
+// instantly and deterministically.
+type Clock struct {
+	lock    sync.Mutex
+	now     time.Time
+	waiters []*waiter
+}
+
+type waiter struct {
+	clock  *Clock
+	at     time.Time
+	period time.Duration
+	c      chan time.Time
+}
+
+// NewClock returns a Clock set at now.
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+// Now returns the current time of the clock.
+func (c *Clock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+// After sends the time on the returned channel once the clock advanced d.
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	return c.wait(d, 0).c
+}
+
+// NewTimer returns a timer which fires once the clock advanced d.
+func (c *Clock) NewTimer(d time.Duration) boomer.Timer {
+	return c.wait(d, 0)
+}
+
+// NewTicker returns a ticker which ticks every time the clock advances d.
+func (c *Clock) NewTicker(d time.Duration) boomer.Ticker {
+	return c.wait(d, d)
+}
+
+func (c *Clock) wait(d, period time.Duration) *waiter {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	w := &waiter{clock: c, at: c.now.Add(d), period: period, c: make(chan time.Time, 1)}
+	if d <= 0 && period == 0 {
+		w.c <- c.now
+		return w
+	}
+	c.waiters = append(c.waiters, w)
+	return w
+}
+
+// Advance moves the clock forward by d, firing everything due by then.
+func (c *Clock) Advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.now = c.now.Add(d)
+	waiters := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			waiters = append(waiters, w)
+			continue
+		}
+		select {
+		case w.c <- c.now:
+		default:
+		}
+		if w.period > 0 {
+			w.at = c.now.Add(w.period)
+			waiters = append(waiters, w)
+		}
+	}
+	c.waiters = waiters
+}
+
+// Waiters returns how many timers and tickers are waiting on the clock, so
+// tests can tell when the code under test is blocked on it.
+func (c *Clock) Waiters() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.waiters)
+}
+
+func (w *waiter) Chan() <-chan time.Time {
+	return w.c
+}
+
+func (w *waiter) Stop() {
+	c := w.clock
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for i, other := range c.waiters {
+		if other == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			return
+		}
+	}
+}
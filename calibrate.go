@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mercadolibre/pla/boomer"
+	"github.com/valyala/fasthttp"
+)
+
+// calibrationRequests is the amount of requests sent to measure overhead.
+const calibrationRequests = 1000
+
+// calibrate measures the latency pla itself adds to every request, sending
+// requests one at a time to an embedded server which does nothing. The
+// measure includes the loopback round trip and the server, so it is an
+// upper bound. It ignores the flags of the test, which would measure them
+// instead of pla.
+func calibrate() time.Duration {
+	ln := startEchoServer()
+	defer ln.Close()
+
+	t := &target{url: "http://" + ln.Addr().String() + "/"}
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(t.url)
+	t.boom = boomer.NewBoomer(ln.Addr().String(), req).
+		WithAmount(calibrationRequests).
+		WithConcurrency(1)
+	fmt.Printf("Calibrating against an embedded server at %s...\n", ln.Addr())
+	t.boom.Run()
+	t.process(time.Now())
+
+	overhead := time.Duration(t.latencies.Quantile(0.5) * float64(time.Second))
+	perRequest := time.Duration(float64(t.total) / float64(calibrationRequests))
+	fmt.Printf("  Measured overhead:\t%4.4f secs. per request, including the loopback round trip\n", overhead.Seconds())
+	fmt.Printf("  Generator cost:\t%4.4f secs. per request and worker, including scheduling and result handling\n\n", perRequest.Seconds())
+	return overhead
+}
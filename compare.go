@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"github.com/mercadolibre/pla/boomer"
+	"github.com/mercadolibre/pla/stats"
+)
+
+// significance is the p-value below which latency differences are reported
+// as significant.
+const significance = 0.05
+
+// target accumulates the results of one side of a comparison.
+type target struct {
+	url       string
+	boom      *boomer.Boomer
+	latencies stats.Sample
+	errors    int
+	bytes     int64
+	total     time.Duration
+}
+
+func (t *target) process(start time.Time) {
+	for res := range t.boom.Results() {
+		if res.Err != nil || res.StatusCode >= 500 {
+			t.errors++
+			continue
+		}
+		t.latencies.Add(res.Duration.Seconds())
+		if res.ContentLength > 0 {
+			t.bytes += int64(res.ContentLength)
+		}
+	}
+	t.total = time.Since(start)
+}
+
+// compare runs identical load against both urls at the same time and
+// prints how their latencies differ.
+func compare(urlA, urlB string) {
+	targets := []*target{{url: urlA}, {url: urlB}}
+	fmt.Printf("Comparing %s and %s...\n", urlA, urlB)
+	runTogether(targets)
+	printComparison(targets[0], targets[1])
+}
+
+// runTogether runs identical load against every target at the same time,
+// until all of them are done.
+func runTogether(targets []*target) {
+	for _, t := range targets {
+		t.boom = newBoomer(newRequest(t.url))
+		prepare(t.boom)
+	}
+	waitForStart()
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	go func() {
+		<-c
+		for _, t := range targets {
+			t.boom.Stop()
+		}
+	}()
+
+	var wg sync.WaitGroup
+	start := time.Now()
+	for _, t := range targets {
+		t.boom.Run()
+		wg.Add(1)
+		go func(t *target) {
+			t.process(start)
+			wg.Done()
+		}(t)
+	}
+	wg.Wait()
+}
+
+func printComparison(a, b *target) {
+	row := func(name string, format string, va, vb interface{}) {
+		fmt.Printf("  %-14s"+format+"  "+format+"\n", name, va, vb)
+	}
+	secs := func(name string, fn func(s *stats.Sample) float64) {
+		row(name, "%20.4f", fn(&a.latencies), fn(&b.latencies))
+	}
+	quantile := func(q float64) func(s *stats.Sample) float64 {
+		return func(s *stats.Sample) float64 {
+			return s.Quantile(q)
+		}
+	}
+
+	fmt.Printf("\nComparison:\n")
+	row("", "%20s", "A", "B")
+	row("Requests", "%20d", a.latencies.Len()+a.errors, b.latencies.Len()+b.errors)
+	row("Errors", "%20d", a.errors, b.errors)
+	row("Requests/sec", "%20.4f",
+		float64(a.latencies.Len()+a.errors)/a.total.Seconds(),
+		float64(b.latencies.Len()+b.errors)/b.total.Seconds())
+	secs("Average", (*stats.Sample).Mean)
+	secs("Std. dev.", (*stats.Sample).StdDev)
+	secs("Fastest", (*stats.Sample).Min)
+	secs("50%", quantile(0.5))
+	secs("90%", quantile(0.9))
+	secs("99%", quantile(0.99))
+	secs("Slowest", (*stats.Sample).Max)
+	fmt.Printf("  Latencies in secs. A is %s, B is %s.\n", a.url, b.url)
+
+	if a.latencies.Len() < 2 || b.latencies.Len() < 2 {
+		fmt.Printf("\nNot enough successful requests to compare latencies.\n")
+		return
+	}
+	_, p := stats.WelchTTest(&a.latencies, &b.latencies)
+	diff := (b.latencies.Mean() - a.latencies.Mean()) / a.latencies.Mean() * 100
+	fmt.Printf("\nDifference:\n")
+	direction := "slower"
+	if diff < 0 {
+		diff, direction = -diff, "faster"
+	}
+	fmt.Printf("  B average is %4.2f%% %s than A's (p = %4.4f).\n", diff, direction, p)
+	if p < significance {
+		fmt.Printf("  The difference is statistically significant.\n")
+	} else {
+		fmt.Printf("  The difference is not statistically significant.\n")
+	}
+}
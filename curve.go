@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// curvePoint is the outcome of a load level of a curve.
+type curvePoint struct {
+	offered  float64
+	achieved float64
+	p99      float64
+	errors   int
+}
+
+// curveLevels returns the offered loads from from to to, both included,
+// every step.
+func curveLevels(from, to, step float64) []float64 {
+	var levels []float64
+	for i := 0; ; i++ {
+		// Multiplying avoids accumulating the error of float additions.
+		level := from + float64(i)*step
+		if level > to+step/1e6 {
+			break
+		}
+		levels = append(levels, level)
+	}
+	return levels
+}
+
+// curve holds every load level for the length of the test, from the lowest
+// to the highest, and reports the 99% latency against the throughput each
+// achieved, characterizing the capacity of the target.
+func curve(url string, levels []float64) {
+	seq := newSequence()
+	var points []curvePoint
+	for _, level := range levels {
+		t := &target{url: url}
+		t.boom = newBoomer(newRequest(url))
+		limit, per := rateLimit(level, time.Second)
+		t.boom.WithRateLimit(limit, per*time.Duration(shards()))
+		if !seq.run(t) {
+			break
+		}
+		p := curvePoint{
+			offered:  level,
+			achieved: float64(t.latencies.Len()+t.errors) / t.total.Seconds(),
+			p99:      t.latencies.Quantile(0.99),
+			errors:   t.errors,
+		}
+		fmt.Printf("Level %4.1f requests/sec: %4.4f achieved, 99%% in %4.4f secs., %d errors\n", p.offered, p.achieved, p.p99, p.errors)
+		points = append(points, p)
+	}
+	if len(points) == 0 {
+		return
+	}
+	if *curveCSV != "" {
+		file, err := os.Create(*curveCSV)
+		if err != nil {
+			usageAndExit(err.Error())
+		}
+		writeCurve(file, points)
+		file.Close()
+	}
+	printCurve(points)
+}
+
+func writeCurve(w io.Writer, points []curvePoint) {
+	fmt.Fprintf(w, "offered_rps,achieved_rps,p99_secs,errors\n")
+	for _, p := range points {
+		fmt.Fprintf(w, "%.4f,%.4f,%.6f,%d\n", p.offered, p.achieved, p.p99, p.errors)
+	}
+}
+
+func printCurve(points []curvePoint) {
+	max := points[0].p99
+	for _, p := range points {
+		if p.p99 > max {
+			max = p.p99
+		}
+	}
+	fmt.Printf("\nLatency curve:\n")
+	fmt.Printf("  Achieved requests/sec against the 99%% latency of every level.\n")
+	for _, p := range points {
+		var barLen int
+		if max > 0 {
+			barLen = int(p.p99 * 40 / max)
+		}
+		fmt.Printf("  %10.1f [%4.4f secs.]\t|%v\n", p.achieved, p.p99, strings.Repeat("∎", barLen))
+	}
+	if *curveCSV != "" {
+		fmt.Printf("  Saved to %s.\n", *curveCSV)
+	}
+}
@@ -0,0 +1,176 @@
+// Package dns load tests DNS resolvers with Boomer, so queries get the same
+// reports and thresholds as HTTP requests.
+package dns
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// defaultTimeout bounds queries without a timeout, as UDP ones may be lost.
+const defaultTimeout = 5 * time.Second
+
+// Types are the query types by name.
+var Types = map[string]uint16{
+	"A": 1, "NS": 2, "CNAME": 5, "SOA": 6, "PTR": 12, "MX": 15,
+	"TXT": 16, "AAAA": 28, "SRV": 33, "ANY": 255,
+}
+
+// statuses are the status codes responses with each rcode get, failing like
+// HTTP ones would: names that don't exist are the client's fault, servers
+// failing to resolve are their own.
+var statuses = []struct {
+	rcode  int
+	status int
+	name   string
+}{
+	{0, fasthttp.StatusOK, "NOERROR"},
+	{1, fasthttp.StatusBadRequest, "FORMERR"},
+	{2, fasthttp.StatusServiceUnavailable, "SERVFAIL"},
+	{3, fasthttp.StatusNotFound, "NXDOMAIN"},
+	{4, fasthttp.StatusNotImplemented, "NOTIMP"},
+	{5, fasthttp.StatusForbidden, "REFUSED"},
+}
+
+// status returns the status code of responses with rcode.
+func status(rcode int) int {
+	for _, s := range statuses {
+		if s.rcode == rcode {
+			return s.status
+		}
+	}
+	return fasthttp.StatusInternalServerError
+}
+
+// RcodeName returns the name of the rcode of responses with status.
+func RcodeName(status int) string {
+	for _, s := range statuses {
+		if s.status == status {
+			return s.name
+		}
+	}
+	return "OTHER"
+}
+
+// Client is a boomer.Doer sending requests as queries of Type to a resolver
+// over UDP, for the name in their path, ex: /www.example.com. Responses get
+// a status code by their rcode, NOERROR being 200 and NXDOMAIN 404.
+type Client struct {
+	addr  string
+	qtype uint16
+	idle  chan net.Conn
+
+	lock sync.Mutex
+	ids  *rand.Rand
+}
+
+// NewClient returns a client of the resolver at addr sending queries of
+// qtype, keeping up to conns idle sockets, which should be the concurrency.
+func NewClient(addr string, qtype uint16, conns int) *Client {
+	return &Client{
+		addr:  addr,
+		qtype: qtype,
+		idle:  make(chan net.Conn, conns),
+		ids:   rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Do sends req as a query and sets the status of its response on resp.
+func (c *Client) Do(req *fasthttp.Request, resp *fasthttp.Response) error {
+	return c.DoTimeout(req, resp, 0)
+}
+
+// DoTimeout sends req as a query and sets the status of its response on
+// resp, failing with fasthttp.ErrTimeout when it isn't received within
+// timeout.
+func (c *Client) DoTimeout(req *fasthttp.Request, resp *fasthttp.Response, timeout time.Duration) error {
+	name := strings.TrimPrefix(string(req.URI().Path()), "/")
+	c.lock.Lock()
+	id := uint16(c.ids.Intn(1 << 16))
+	c.lock.Unlock()
+	query, err := Query(id, name, c.qtype)
+	if err != nil {
+		return err
+	}
+	conn, err := c.conn()
+	if err != nil {
+		return err
+	}
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+	rcode, size, err := exchange(conn, id, query)
+	if err != nil {
+		conn.Close()
+		if ne, ok := err.(net.Error); ok && ne.Timeout() {
+			return fasthttp.ErrTimeout
+		}
+		return err
+	}
+	select {
+	case c.idle <- conn:
+	default:
+		conn.Close()
+	}
+	resp.SetStatusCode(status(rcode))
+	resp.Header.SetContentLength(size)
+	return nil
+}
+
+func (c *Client) conn() (net.Conn, error) {
+	select {
+	case conn := <-c.idle:
+		return conn, nil
+	default:
+	}
+	return net.Dial("udp", c.addr)
+}
+
+// exchange sends query and reads its response, skipping late responses to
+// previous queries, returning its rcode and size.
+func exchange(conn net.Conn, id uint16, query []byte) (int, int, error) {
+	if _, err := conn.Write(query); err != nil {
+		return 0, 0, err
+	}
+	buf := make([]byte, 4096)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return 0, 0, err
+		}
+		// Responses have the id of their query and the QR bit set.
+		if n >= 12 && binary.BigEndian.Uint16(buf) == id && buf[2]&0x80 != 0 {
+			return int(buf[3] & 0x0f), n, nil
+		}
+	}
+}
+
+// Query builds a recursive query of qtype for name, with the given id.
+func Query(id uint16, name string, qtype uint16) ([]byte, error) {
+	name = strings.TrimSuffix(name, ".")
+	if name == "" {
+		return nil, fmt.Errorf("dns queries need a name")
+	}
+	q := make([]byte, 12, 12+len(name)+6)
+	binary.BigEndian.PutUint16(q, id)
+	// Recursion desired, one question.
+	q[2] = 0x01
+	q[5] = 1
+	for _, label := range strings.Split(name, ".") {
+		if len(label) == 0 || len(label) > 63 {
+			return nil, fmt.Errorf("invalid dns name %q", name)
+		}
+		q = append(q, byte(len(label)))
+		q = append(q, label...)
+	}
+	q = append(q, 0, byte(qtype>>8), byte(qtype), 0, 1)
+	return q, nil
+}
@@ -0,0 +1,61 @@
+package dns
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	q, err := Query(0x1234, "www.example.com.", Types["AAAA"])
+	if err != nil {
+		t.Fatalf("Could not build a valid query: %v", err)
+	}
+	expected := append([]byte{0x12, 0x34, 1, 0, 0, 1, 0, 0, 0, 0, 0, 0},
+		"\x03www\x07example\x03com\x00\x00\x1c\x00\x01"...)
+	if !bytes.Equal(q, expected) {
+		t.Errorf("Expected query %v, found %v", expected, q)
+	}
+	for _, name := range []string{"", "a..b"} {
+		if _, err := Query(1, name, Types["A"]); err == nil {
+			t.Errorf("Expected %q to be an invalid name", name)
+		}
+	}
+}
+
+func TestExchange(t *testing.T) {
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	go func() {
+		buf := make([]byte, 512)
+		n, addr, err := server.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		// A late response to another query first, then NXDOMAIN.
+		stale := append([]byte(nil), buf[:n]...)
+		stale[0]++
+		stale[2] |= 0x80
+		server.WriteTo(stale, addr)
+		buf[2] |= 0x80
+		buf[3] = 3
+		server.WriteTo(buf[:n], addr)
+	}()
+
+	conn, err := net.Dial("udp", server.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	q, _ := Query(7, "missing.example.com", Types["A"])
+	rcode, size, err := exchange(conn, 7, q)
+	if err != nil || rcode != 3 || size != len(q) {
+		t.Errorf("Expected NXDOMAIN of %d bytes, found %d of %d bytes %v", len(q), rcode, size, err)
+	}
+	if name := RcodeName(status(rcode)); name != "NXDOMAIN" {
+		t.Errorf("Expected rcode name NXDOMAIN, found %v", name)
+	}
+}
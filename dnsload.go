@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"runtime"
+
+	"github.com/mercadolibre/pla/boomer"
+	"github.com/mercadolibre/pla/dns"
+	"github.com/valyala/fasthttp"
+)
+
+// dnsTest runs a load test of queries for names rendered from template
+// against the resolver at server.
+func dnsTest(server, template string) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	conns := int(*c)
+	if conns == 0 {
+		conns = runtime.NumCPU()
+	}
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI("/" + template)
+	requestMix = []*boomer.WeightedRequest{{Request: req, Weight: 1, Label: *dnsType + " " + template}}
+	doer = dns.NewClient(server, dns.Types[*dnsType], conns)
+	reporters = append(reporters, newRcodes())
+	fmt.Printf("Querying %s for %s records of %s\n", server, *dnsType, template)
+	run("http://" + server + "/")
+}
+
+// rcodes reports the responses of every DNS rcode.
+type rcodes struct {
+	names []string
+	stats map[string]*rcodeStats
+	count int
+}
+
+type rcodeStats struct {
+	count int
+	total float64
+}
+
+func newRcodes() *rcodes {
+	return &rcodes{stats: make(map[string]*rcodeStats)}
+}
+
+func (r *rcodes) Start(b *boomer.Boomer) {}
+
+func (r *rcodes) ProcessResult(res boomer.Result) {
+	if res.StatusCode == 0 {
+		return
+	}
+	name := dns.RcodeName(res.StatusCode)
+	s, ok := r.stats[name]
+	if !ok {
+		s = &rcodeStats{}
+		r.stats[name] = s
+		r.names = append(r.names, name)
+	}
+	s.count++
+	s.total += res.Duration.Seconds()
+	r.count++
+}
+
+func (r *rcodes) End() {
+	if r.count == 0 {
+		return
+	}
+	fmt.Printf("\nResponse codes:\n")
+	for _, name := range r.names {
+		s := r.stats[name]
+		fmt.Printf("  [%s]\t%d responses (%4.2f%%), average %4.4f secs.\n",
+			name, s.count, float64(s.count)*100/float64(r.count), s.total/float64(s.count))
+	}
+}
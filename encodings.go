@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+)
+
+// encodingMatrix runs the test against url once with every encoding as
+// Accept-Encoding, one after the other, and compares their latency and
+// transfer size, ex: to decide whether compression pays off.
+func encodingMatrix(url string, encodings []string) {
+	seq := newSequence()
+	var targets []*target
+	for _, encoding := range encodings {
+		t := &target{url: url}
+		addr, req := newRequest(url)
+		req.Header.Set("Accept-Encoding", encoding)
+		t.boom = newBoomer(addr, req)
+		fmt.Printf("Running with Accept-Encoding: %s...\n", encoding)
+		if !seq.run(t) {
+			break
+		}
+		targets = append(targets, t)
+	}
+	printEncodings(encodings[:len(targets)], targets)
+}
+
+func printEncodings(encodings []string, targets []*target) {
+	fmt.Printf("\nAccept-Encoding comparison:\n")
+	fmt.Printf("  %-12s%14s%14s%14s%14s%14s%10s\n", "Encoding", "Requests/sec", "Average", "50%", "99%", "Avg. bytes", "Errors")
+	for i, t := range targets {
+		var size int64
+		if n := t.latencies.Len(); n > 0 {
+			size = t.bytes / int64(n)
+		}
+		fmt.Printf("  %-12s%14.4f%14.4f%14.4f%14.4f%14d%10d\n", encodings[i],
+			float64(t.latencies.Len()+t.errors)/t.total.Seconds(),
+			t.latencies.Mean(), t.latencies.Quantile(0.5), t.latencies.Quantile(0.99), size, t.errors)
+	}
+	fmt.Printf("  Latencies in secs., bytes are the response bodies as transferred.\n")
+}
@@ -1,6 +1,11 @@
 package main
 
-import "github.com/mercadolibre/pla/boomer"
+import (
+	"fmt"
+	"sync"
+
+	"github.com/mercadolibre/pla/boomer"
+)
 
 // Interface determines the interface for Pla's User Interfaces
 type Interface interface {
@@ -8,3 +13,89 @@ type Interface interface {
 	ProcessResult(res boomer.Result)
 	End()
 }
+
+// fanoutQueue is how many results each interface of a fanout can fall
+// behind before its policy applies.
+const fanoutQueue = 1024
+
+// fanout is an Interface dispatching results to several others, each one
+// on its own goroutine behind a queue, so a slow one doesn't hold back the
+// rest.
+type fanout struct {
+	lock  sync.Mutex
+	ended bool
+	outs  []*fanoutOut
+}
+
+type fanoutOut struct {
+	Interface
+	// drop discards results when the queue is full instead of waiting.
+	drop    bool
+	dropped uint64
+	results chan boomer.Result
+	done    chan struct{}
+}
+
+// add attaches ui, dropping the results it cannot keep up with when drop
+// is set and making the others wait for it otherwise.
+func (f *fanout) add(ui Interface, drop bool) {
+	f.outs = append(f.outs, &fanoutOut{
+		Interface: ui,
+		drop:      drop,
+		results:   make(chan boomer.Result, fanoutQueue),
+		done:      make(chan struct{}),
+	})
+}
+
+func (f *fanout) Start(b *boomer.Boomer) {
+	for _, out := range f.outs {
+		out.Start(b)
+		go func(out *fanoutOut) {
+			for res := range out.results {
+				out.Interface.ProcessResult(res)
+			}
+			close(out.done)
+		}(out)
+	}
+}
+
+func (f *fanout) ProcessResult(res boomer.Result) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.ended {
+		return
+	}
+	for _, out := range f.outs {
+		if !out.drop {
+			out.results <- res
+			continue
+		}
+		select {
+		case out.results <- res:
+		default:
+			out.dropped++
+		}
+	}
+}
+
+// End lets every interface process its queued results, then ends them in
+// the order they were added.
+func (f *fanout) End() {
+	f.lock.Lock()
+	if f.ended {
+		f.lock.Unlock()
+		return
+	}
+	f.ended = true
+	for _, out := range f.outs {
+		close(out.results)
+	}
+	f.lock.Unlock()
+	for _, out := range f.outs {
+		<-out.done
+		out.End()
+		if out.dropped > 0 {
+			fmt.Printf("  %d results were too many for this report and dropped\n", out.dropped)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mercadolibre/pla/boomer"
+)
+
+type countingInterface struct {
+	block   chan struct{}
+	results int
+	ended   bool
+}
+
+func (c *countingInterface) Start(b *boomer.Boomer) {}
+
+func (c *countingInterface) ProcessResult(res boomer.Result) {
+	if c.block != nil {
+		<-c.block
+	}
+	c.results++
+}
+
+func (c *countingInterface) End() { c.ended = true }
+
+func TestFanout(t *testing.T) {
+	fast, slow := &countingInterface{}, &countingInterface{block: make(chan struct{})}
+	f := &fanout{}
+	f.add(fast, false)
+	f.add(slow, true)
+	f.Start(nil)
+	for i := 0; i < 2*fanoutQueue; i++ {
+		f.ProcessResult(boomer.Result{})
+	}
+	close(slow.block)
+	f.End()
+	if fast.results != 2*fanoutQueue || !fast.ended {
+		t.Errorf("Expected the blocking interface to get every result, found %d", fast.results)
+	}
+	if slow.results+int(f.outs[1].dropped) != 2*fanoutQueue || slow.results > fanoutQueue+1 || !slow.ended {
+		t.Errorf("Expected the slow interface to drop what it could not queue, found %d results and %d dropped", slow.results, f.outs[1].dropped)
+	}
+	f.ProcessResult(boomer.Result{})
+	if fast.results != 2*fanoutQueue {
+		t.Errorf("Expected no results after ending, found %d", fast.results)
+	}
+}
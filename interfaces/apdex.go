@@ -0,0 +1,47 @@
+package interfaces
+
+import (
+	"fmt"
+	"time"
+)
+
+// Apdex scores how satisfied users are with latencies against a target T:
+// requests within T satisfy them, those within 4T are tolerated and slower
+// or failed ones frustrate them.
+type Apdex struct {
+	T time.Duration
+
+	satisfied  int
+	tolerating int
+	frustrated int
+}
+
+// add records a request that took sec seconds, or failed.
+func (a *Apdex) add(sec float64, failed bool) {
+	switch {
+	case failed || sec > 4*a.T.Seconds():
+		a.frustrated++
+	case sec > a.T.Seconds():
+		a.tolerating++
+	default:
+		a.satisfied++
+	}
+}
+
+// Score returns the Apdex score, from 0 when every user is frustrated to 1
+// when all are satisfied.
+func (a *Apdex) Score() float64 {
+	total := a.satisfied + a.tolerating + a.frustrated
+	if total == 0 {
+		return 0
+	}
+	return (float64(a.satisfied) + float64(a.tolerating)/2) / float64(total)
+}
+
+func (a *Apdex) print() {
+	fmt.Printf("\nApdex:\n")
+	fmt.Printf("  Score:\t%4.2f [%v]\n", a.Score(), a.T)
+	fmt.Printf("  Satisfied:\t%d requests within %v\n", a.satisfied, a.T)
+	fmt.Printf("  Tolerating:\t%d requests within %v\n", a.tolerating, 4*a.T)
+	fmt.Printf("  Frustrated:\t%d requests slower or failed\n", a.frustrated)
+}
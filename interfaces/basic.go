@@ -20,12 +20,25 @@ import (
 	"time"
 
 	"github.com/mercadolibre/pla/boomer"
+	"github.com/mercadolibre/pla/monitor"
 	"github.com/sschepens/gohistogram"
 	"github.com/sschepens/pb"
 )
 
 const (
 	barChar = "∎"
+
+	// certExpiryWarning is how close to expiry certificates get flagged.
+	certExpiryWarning = 30 * 24 * time.Hour
+
+	// statusRefresh is how often the phase and times after the progress
+	// bar are updated.
+	statusRefresh = 500 * time.Millisecond
+
+	// maxErrorKinds is how many distinct error messages are reported,
+	// further ones are counted together as otherErrors.
+	maxErrorKinds = 100
+	otherErrors   = "other errors"
 )
 
 // BasicInterface is Pla's default text-based terminal interface.
@@ -41,56 +54,275 @@ type BasicInterface struct {
 
 	errorDist      map[string]int
 	statusCodeDist map[int]int
+	labelDist      *breakdown
+	addrDist       *breakdown
+	tenants        *tenants
+	cacheDist      *breakdown
+	condDist       *breakdown
+	preflightDist  *breakdown
+	serverTimings  *serverTimings
+	transactions   *transactions
+	sizeDist       *sizeBreakdown
+	timeline       *timeline
+	schedule       *schedule
+	saturation     *saturation
 	sizeTotal      int64
 
+	streams         int
+	events          int64
+	disconnects     int
+	firstEvents     int
+	firstEventTotal float64
+
+	streamTotal   float64
+	longestStream float64
+
+	attempts   int
+	duplicated int
+
+	backendDist    map[string]int
+	backends       int
+	affinityBreaks int
+
+	outliers *Outliers
+	errorLog *ErrorLog
+	slo      *SLO
+	apdex    *Apdex
+	exact    *exactLatencies
+	memory   *memoryCap
+	overhead float64
+
+	resources *resources
+
 	boom  *boomer.Boomer
 	histo *gohistogram.NumericHistogram
 	bar   *pb.ProgressBar
 	pct   int
+
+	statusDone chan struct{}
 }
 
 // NewBasicInterface instantiates a new BasicInterface.
 func NewBasicInterface() *BasicInterface {
+	start := time.Now()
 	return &BasicInterface{
-		start:          time.Now(),
+		start:          start,
 		statusCodeDist: make(map[int]int),
 		errorDist:      make(map[string]int),
+		labelDist:      newBreakdown(),
+		addrDist:       newBreakdown(),
+		tenants:        newTenants(),
+		cacheDist:      newBreakdown(),
+		condDist:       newBreakdown(),
+		preflightDist:  newBreakdown(),
+		serverTimings:  newServerTimings(),
+		transactions:   newTransactions(),
+		sizeDist:       newSizeBreakdown(),
+		timeline:       &timeline{start: start, step: time.Second},
+		schedule:       newSchedule(),
+		saturation:     &saturation{},
+		backendDist:    make(map[string]int),
 		histo:          gohistogram.NewHistogram(10),
 	}
 }
 
+// WithOutliers makes the interface flag and report latency outliers.
+func (b *BasicInterface) WithOutliers(o *Outliers) *BasicInterface {
+	b.outliers = o
+	return b
+}
+
+// WithErrorLog makes the interface print errors while running, as l
+// does.
+func (b *BasicInterface) WithErrorLog(l *ErrorLog) *BasicInterface {
+	b.errorLog = l
+	return b
+}
+
+// WithTargetResources makes the interface sample the CPU and memory used
+// by the target with s every interval, and report them along with the
+// latency of the requests sent in each interval.
+func (b *BasicInterface) WithTargetResources(s monitor.Sampler, every time.Duration) *BasicInterface {
+	b.timeline.step = every
+	b.resources = &resources{sampler: s, every: every, timeline: b.timeline}
+	return b
+}
+
+// WithSLO makes the interface show, while running and at the end, how many
+// requests violate s and how fast they burn its error budget.
+func (b *BasicInterface) WithSLO(s *SLO) *BasicInterface {
+	b.slo = s
+	return b
+}
+
+// WithApdex makes the interface report the Apdex score of latencies
+// against the target t.
+func (b *BasicInterface) WithApdex(t time.Duration) *BasicInterface {
+	b.apdex = &Apdex{T: t}
+	return b
+}
+
+// WithExactPercentiles makes the interface keep every latency, spilling
+// them to disk on long runs, and report exact percentiles instead of the
+// histogram's approximations.
+func (b *BasicInterface) WithExactPercentiles(exact bool) *BasicInterface {
+	b.exact = nil
+	if exact {
+		b.exact = &exactLatencies{}
+	}
+	return b
+}
+
+// WithMaxMemory makes exact percentiles spill to disk in smaller runs once
+// the heap grows past mb megabytes, and the summary report the peak heap.
+// Nothing else shrinks, so it is no hard cap. 0 means no limit.
+func (b *BasicInterface) WithMaxMemory(mb int64) *BasicInterface {
+	b.memory = nil
+	if mb > 0 {
+		b.memory = &memoryCap{limit: uint64(mb) << 20}
+	}
+	return b
+}
+
+// WithOverhead makes the interface report the latency pla itself adds to
+// every request, as measured by calibration, along with the latencies.
+func (b *BasicInterface) WithOverhead(overhead time.Duration) *BasicInterface {
+	b.overhead = overhead.Seconds()
+	return b
+}
+
 // Start initializes interface
 func (b *BasicInterface) Start(boom *boomer.Boomer) {
 	b.boom = boom
+	b.saturation.start()
+	if b.resources != nil {
+		b.resources.run()
+	}
 	b.initProgressBar()
 }
 
 // ProcessResult increments ProgressBar and keeps track of statistics.
 func (b *BasicInterface) ProcessResult(res boomer.Result) {
-	if res.Err != nil {
-		b.errorDist[res.Err.Error()]++
+	if res.Label != "" {
+		b.labelDist.add(res.Label, res)
+	}
+	b.attempts += res.Attempts
+	if res.Attempts > 1 {
+		b.duplicated++
+	}
+	if res.Addr != "" {
+		b.addrDist.add(res.Addr, res)
+	}
+	if res.Tenant != "" {
+		b.tenants.add(res)
+	}
+	if res.CacheStatus != "" {
+		b.cacheDist.add(res.CacheStatus, res)
+	}
+	if b.boom.Conditional && res.Err == nil {
+		b.condDist.add(conditionalKey(res), res)
+	}
+	if len(res.ServerTimings) > 0 && res.Err == nil {
+		b.serverTimings.add(res)
+	}
+	if res.Preflight > 0 || res.PreflightErr != nil {
+		b.preflightDist.add(preflightKey(res), boomer.Result{Duration: res.Preflight, Err: res.PreflightErr})
+	}
+	b.transactions.add(res)
+	b.saturation.add(res)
+	b.sizeDist.add(res)
+	b.timeline.add(res, failed(res))
+	if b.boom.RateInterval > 0 {
+		b.schedule.add(res, b.boom.RateInterval)
+	}
+	if b.boom.SSE {
+		b.processStream(res)
+	} else if res.Err != nil {
+		b.addError(res.Err.Error())
+		if b.slo != nil {
+			b.slo.add(true)
+		}
+		if b.apdex != nil {
+			b.apdex.add(0, true)
+		}
 	} else {
 		sec := res.Duration.Seconds()
+		if b.boom.Stream {
+			// A long stream is intended, latency is the time to first byte.
+			b.streamTotal += sec
+			if sec > b.longestStream {
+				b.longestStream = sec
+			}
+			sec = res.FirstByte.Seconds()
+		}
 		if b.slowest == 0 || sec > b.slowest {
 			b.slowest = sec
 		}
 		if b.fastest == 0 || b.fastest > sec {
 			b.fastest = sec
 		}
-		b.histo.Add(res.Duration.Seconds())
-		b.avgTotal += res.Duration.Seconds()
+		if b.outliers != nil {
+			b.outliers.check(res, sec, b.histo)
+		}
+		if b.slo != nil {
+			b.slo.add(sec > b.slo.Latency.Seconds())
+		}
+		if b.apdex != nil {
+			b.apdex.add(sec, false)
+		}
+		b.histo.Add(sec)
+		if b.resources != nil {
+			b.timeline.latency(res, sec)
+		}
+		if b.exact != nil {
+			b.exact.constrained = b.memory != nil && b.memory.exceeded()
+			b.exact.add(sec)
+		}
+		b.avgTotal += sec
 		b.statusCodeDist[res.StatusCode]++
+		if res.Backend != "" {
+			b.backendDist[res.Backend]++
+			b.backends++
+		}
+		if res.AffinityBroken {
+			b.affinityBreaks++
+		}
 		if res.ContentLength > 0 {
 			b.sizeTotal += int64(res.ContentLength)
 		}
 	}
-	if b.boom.Duration == 0 {
+	// Scenarios count iterations, started by their first step.
+	if b.boom.Duration == 0 && res.Step <= 1 {
 		b.bar.Increment()
 	}
 }
 
+func (b *BasicInterface) processStream(res boomer.Result) {
+	b.streams++
+	b.events += int64(res.Events)
+	if res.Events > 0 {
+		b.firstEvents++
+		b.firstEventTotal += res.FirstEvent.Seconds()
+	}
+	switch {
+	case res.Err == boomer.ErrStreamClosed:
+		b.disconnects++
+	case res.Err != nil:
+		b.addError(res.Err.Error())
+	default:
+		b.statusCodeDist[res.StatusCode]++
+	}
+}
+
 // End finishes interface.
 func (b *BasicInterface) End() {
+	if b.statusDone != nil {
+		close(b.statusDone)
+		b.statusDone = nil
+	}
+	if b.resources != nil {
+		b.resources.stop()
+	}
 	b.bar.Finish()
 	b.total = time.Now().Sub(b.start)
 	count := float64(b.histo.Count())
@@ -121,9 +353,49 @@ func (b *BasicInterface) initProgressBar() {
 	b.bar.Current = "a"
 	b.bar.CurrentN = "a"
 	b.bar.Start()
+	b.statusDone = make(chan struct{})
+	go b.showStatus()
+}
+
+// showStatus keeps the phase, elapsed and remaining time, and the live rate
+// and errors after the bar.
+func (b *BasicInterface) showStatus() {
+	ticker := time.NewTicker(statusRefresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.statusDone:
+			return
+		case <-ticker.C:
+			if b.memory != nil {
+				b.memory.check()
+			}
+			line := statusLine(b.boom.Snapshot())
+			if b.slo != nil {
+				line += b.slo.status()
+			}
+			b.bar.Postfix(line)
+		}
+	}
+}
+
+func statusLine(s boomer.Snapshot) string {
+	line := fmt.Sprintf(" %s, %v elapsed", s.Phase, s.Elapsed/time.Second*time.Second)
+	if s.Remaining >= 0 {
+		line += fmt.Sprintf(", %v left", s.Remaining/time.Second*time.Second)
+	}
+	return line + fmt.Sprintf(", %4.1f req/s, %4.2f%% errors", s.RPS, s.ErrorRate*100)
 }
 
 func (b *BasicInterface) print() {
+	if b.boom.TLS() {
+		b.printTLS()
+	}
+
+	if b.streams > 0 {
+		b.printStreams()
+	}
+
 	if b.histo.Count() > 0 {
 		fmt.Printf("\nSummary:\n")
 		fmt.Printf("  Total:\t%4.4f secs.\n", b.total.Seconds())
@@ -131,6 +403,17 @@ func (b *BasicInterface) print() {
 		fmt.Printf("  Fastest:\t%4.4f secs.\n", b.fastest)
 		fmt.Printf("  Average:\t%4.4f secs.\n", b.average)
 		fmt.Printf("  Requests/sec:\t%4.4f\n", b.rps)
+		if b.overhead > 0 {
+			fmt.Printf("  Overhead:\t%4.4f secs. per request at most added by pla, as calibrated, not subtracted from latencies\n", b.overhead)
+		}
+		if b.boom.Stream {
+			fmt.Printf("  Average stream:\t%4.4f secs.\n", b.streamTotal/float64(b.histo.Count()))
+			fmt.Printf("  Longest stream:\t%4.4f secs.\n", b.longestStream)
+			fmt.Printf("  Latencies measure time to first byte.\n")
+		}
+		if b.boom.Pipeline > 0 {
+			fmt.Printf("  Pipelining:\t%d requests per connection, latencies include pipeline queueing.\n", b.boom.Pipeline)
+		}
 		if b.sizeTotal > 0 {
 			fmt.Printf("  Total Data Received:\t%d bytes.\n", b.sizeTotal)
 			fmt.Printf("  Response Size per Request:\t%d bytes.\n", b.sizeTotal/int64(b.histo.Count()))
@@ -138,19 +421,167 @@ func (b *BasicInterface) print() {
 		b.printStatusCodes()
 	}
 
+	if symptoms := b.saturation.symptoms(b.boom, b.total); len(symptoms) > 0 {
+		b.saturation.print(symptoms)
+	}
+
+	if b.labelDist.count > 0 && b.boom.Scenarios() != nil {
+		b.labelDist.print("Scenario steps")
+	} else if b.labelDist.count > 0 {
+		b.labelDist.print("Request mix")
+	}
+
+	if b.transactions.count > 0 {
+		b.transactions.print(b.total)
+	}
+
+	if b.addrDist.count > 0 {
+		b.addrDist.print("Resolved addresses")
+	}
+
+	if b.tenants.count > 0 {
+		b.tenants.print()
+	}
+
+	if b.cacheDist.count > 0 {
+		b.cacheDist.print("Cache status")
+	}
+
+	if b.condDist.count > 0 {
+		b.condDist.print("Conditional requests")
+	}
+
+	if b.preflightDist.count > 0 {
+		b.preflightDist.print("CORS preflights")
+	}
+
+	if len(b.serverTimings.names) > 0 {
+		b.serverTimings.print(int(b.histo.Count()))
+	}
+
+	if b.sizeDist.varied() {
+		b.sizeDist.print()
+	}
+
+	if b.schedule.count > 0 {
+		b.schedule.print(b.boom.RateInterval, b.total)
+	}
+
+	if b.backends > 0 {
+		b.printAffinity()
+	}
+
+	if b.boom.HappyEyeballs {
+		b.printDials()
+	}
+
+	if stats := b.boom.ConnStats(); stats.Opened > 0 {
+		b.printKeepAlive(stats)
+	}
+
+	if b.duplicated > 0 {
+		b.printRetries()
+	}
+
+	if trips := b.boom.BreakerTrips(); trips > 0 {
+		fmt.Printf("\nCircuit breaker:\n")
+		fmt.Printf("  Opened:\t%d times\n", trips)
+	}
+
+	if stats := b.boom.AIMDStats(); stats.Peak > 0 {
+		fmt.Printf("\nAIMD load model:\n")
+		fmt.Printf("  Rate:\t%4.4f requests/sec at the end, %4.4f at the peak\n", stats.Rate, stats.Peak)
+		fmt.Printf("  Decreases:\t%d times\n", stats.Decreases)
+		if stats.Decreases > 0 {
+			fmt.Printf("  Capacity:\t%4.4f requests/sec, average rate when breaches started\n", stats.Capacity)
+		}
+	}
+
+	if stats := b.boom.QuotaStats(); stats.Limited > 0 {
+		fmt.Printf("\nQuota backoff:\n")
+		fmt.Printf("  Limited:\t%d responses were 429 Too Many Requests\n", stats.Limited)
+		fmt.Printf("  Backed off:\t%d times, %4.4f secs. paused for Retry-After\n", stats.Backoffs, stats.Paused.Seconds())
+		fmt.Printf("  Effective rate:\t%4.4f accepted requests/sec, ended at %4.2f%% of the configured rate\n", float64(stats.Accepted)/b.total.Seconds(), stats.Scale*100)
+	}
+
+	if stats := b.boom.DialStats(); stats.Backoffs > 0 {
+		fmt.Printf("\nConnect backoff:\n")
+		fmt.Printf("  Backed off:\t%d times, %4.4f secs. in total\n", stats.Backoffs, stats.BackoffTime.Seconds())
+	}
+
+	if panics := b.boom.WorkerPanics(); panics > 0 {
+		fmt.Printf("\nWorker panics:\n")
+		fmt.Printf("  Recovered:\t%d times, workers were restarted\n", panics)
+	}
+
+	if errs := b.boom.LifecycleErrors(); len(errs) > 0 {
+		fmt.Printf("\nSetup and teardown:\n")
+		for _, err := range errs {
+			fmt.Printf("  Failed:\t%v\n", err)
+		}
+	}
+
+	if stats := b.boom.ResultsStats(); stats.Dropped > 0 || stats.Spilled > 0 || stats.Blocked > 0 {
+		fmt.Printf("\nResults delivery:\n")
+		fmt.Printf("  Blocked:\t%4.4f secs. workers waited for the reporter\n", stats.Blocked.Seconds())
+		fmt.Printf("  Dropped:\t%d results were not reported\n", stats.Dropped)
+		fmt.Printf("  Spilled:\t%d results were buffered to disk\n", stats.Spilled)
+	}
+
 	if len(b.errorDist) > 0 {
 		b.printErrors()
 	}
 
+	if b.memory != nil {
+		b.memory.print()
+	}
+
+	if err := b.boom.CaptureErr(); err != nil {
+		fmt.Printf("\nCapture:\n")
+		fmt.Printf("  Stopped saving requests:\t%v\n", err)
+	}
+
+	if stats := b.boom.FailureSamples(); stats.Saved > 0 || stats.Err != nil {
+		fmt.Printf("\nFailure samples:\n")
+		fmt.Printf("  Saved:\t%d responses to %s\n", stats.Saved, stats.Dir)
+		if stats.Err != nil {
+			fmt.Printf("  Stopped saving:\t%v\n", stats.Err)
+		}
+	}
+
+	if b.timeline.failed > 0 {
+		b.timeline.print()
+	}
+
+	if b.resources != nil {
+		b.resources.print()
+	}
+
 	if b.histo.Count() > 0 {
 		b.printHistogram()
 		b.printLatencies()
 	}
+
+	if b.outliers != nil && b.histo.Count() > 0 {
+		b.outliers.print(b.histo.Count())
+	}
+
+	if b.slo != nil {
+		b.slo.print()
+	}
+
+	if b.apdex != nil {
+		b.apdex.print()
+	}
 }
 
 // Prints percentile latencies.
 func (b *BasicInterface) printLatencies() {
 	pctls := []int{10, 25, 50, 75, 90, 95, 99}
+	if b.exact != nil {
+		b.printExactLatencies(pctls)
+		return
+	}
 	fmt.Printf("\nLatency distribution:\n")
 	cent := float64(100)
 	for _, p := range pctls {
@@ -161,6 +592,23 @@ func (b *BasicInterface) printLatencies() {
 	}
 }
 
+func (b *BasicInterface) printExactLatencies(pctls []int) {
+	defer b.exact.close()
+	qs := make([]float64, len(pctls))
+	for i, p := range pctls {
+		qs[i] = float64(p) / 100
+	}
+	latencies, err := b.exact.quantiles(qs)
+	fmt.Printf("\nLatency distribution (exact):\n")
+	if err != nil {
+		fmt.Printf("  Could not read spilled latencies: %v\n", err)
+		return
+	}
+	for i, l := range latencies {
+		fmt.Printf("  %v%% in %4.4f secs.\n", pctls[i], l)
+	}
+}
+
 func (b *BasicInterface) printHistogram() {
 	fmt.Printf("\nResponse time histogram:\n")
 	bins := b.histo.Bins()
@@ -180,6 +628,53 @@ func (b *BasicInterface) printHistogram() {
 	}
 }
 
+// Prints details of the TLS connection negotiated with the target.
+func (b *BasicInterface) printTLS() {
+	fmt.Printf("\nTLS:\n")
+	info, err := b.boom.TLSInfo()
+	if err != nil {
+		fmt.Printf("  Handshake failed:\t%v\n", err)
+		return
+	}
+	fmt.Printf("  Version:\t%s\n", info.Version)
+	fmt.Printf("  Cipher suite:\t%s\n", info.CipherSuite)
+	if info.Protocol != "" {
+		fmt.Printf("  ALPN protocol:\t%s\n", info.Protocol)
+	}
+	if info.Subject != "" {
+		fmt.Printf("  Certificate:\t%s\n", info.Subject)
+	}
+	if info.OCSPStapled {
+		fmt.Printf("  OCSP stapling:\tyes\n")
+	} else {
+		fmt.Printf("  OCSP stapling:\tno\n")
+	}
+	if !info.NotAfter.IsZero() {
+		left := info.NotAfter.Sub(time.Now())
+		fmt.Printf("  Expires:\t%s\n", info.NotAfter.Format(time.RFC3339))
+		if left < certExpiryWarning {
+			fmt.Printf("  WARNING:\tcertificate expires in %d days!\n", int(left.Hours()/24))
+		}
+	}
+}
+
+// Prints event stream statistics, only populated in SSE mode.
+func (b *BasicInterface) printStreams() {
+	fmt.Printf("\nStreams:\n")
+	fmt.Printf("  Total:\t%4.4f secs.\n", b.total.Seconds())
+	fmt.Printf("  Streams:\t%d\n", b.streams)
+	fmt.Printf("  Events:\t%d\n", b.events)
+	fmt.Printf("  Events/sec:\t%4.4f\n", float64(b.events)/b.total.Seconds())
+	if b.firstEvents > 0 {
+		fmt.Printf("  Time to first event:\t%4.4f secs.\n", b.firstEventTotal/float64(b.firstEvents))
+	}
+	fmt.Printf("  Disconnect rate:\t%4.2f%%\n", float64(b.disconnects)*100/float64(b.streams))
+	fmt.Printf("  Error rate:\t%4.2f%%\n", float64(b.errorCount())*100/float64(b.streams))
+	if len(b.statusCodeDist) > 0 {
+		b.printStatusCodes()
+	}
+}
+
 // Prints status code distribution.
 func (b *BasicInterface) printStatusCodes() {
 	fmt.Printf("\nStatus code distribution:\n")
@@ -188,6 +683,109 @@ func (b *BasicInterface) printStatusCodes() {
 	}
 }
 
+// Prints backend distribution and how often workers lost their backend.
+func (b *BasicInterface) printAffinity() {
+	fmt.Printf("\nAffinity:\n")
+	fmt.Printf("  Broken:\t%d times (%4.2f%% of requests)\n", b.affinityBreaks, float64(b.affinityBreaks)*100/float64(b.backends))
+	for backend, num := range b.backendDist {
+		fmt.Printf("  [%s]\t%d responses\n", backend, num)
+	}
+}
+
+// Prints how many logical requests were sent more than once.
+func (b *BasicInterface) printRetries() {
+	fmt.Printf("\nRetries:\n")
+	fmt.Printf("  Attempts:\t%d\n", b.attempts)
+	fmt.Printf("  Duplicated:\t%d requests were sent more than once\n", b.duplicated)
+	if b.boom.IdempotencyHeader != "" {
+		fmt.Printf("  Retries carried the same %s header.\n", b.boom.IdempotencyHeader)
+	}
+}
+
+// Prints which address family connections ended up using.
+func (b *BasicInterface) printDials() {
+	stats := b.boom.DialStats()
+	fmt.Printf("\nConnections:\n")
+	if stats.IPv6 > 0 {
+		fmt.Printf("  IPv6:\t%d connections, average connect %4.4f secs.\n", stats.IPv6, stats.IPv6Time.Seconds()/float64(stats.IPv6))
+	}
+	if stats.IPv4 > 0 {
+		fmt.Printf("  IPv4:\t%d connections, average connect %4.4f secs.\n", stats.IPv4, stats.IPv4Time.Seconds()/float64(stats.IPv4))
+	}
+	if stats.Fallbacks > 0 {
+		fmt.Printf("  Fallbacks:\t%d connections fell back to IPv4, IPv6 may be broken or slow.\n", stats.Fallbacks)
+	}
+	if stats.Failures > 0 {
+		fmt.Printf("  Failures:\t%d connections\n", stats.Failures)
+	}
+	for addr, num := range stats.Addrs {
+		fmt.Printf("  [%s]\t%d connections\n", addr, num)
+	}
+}
+
+// conditionalKey groups res by whether it was a conditional request, and
+// whether it then validated the response the worker had.
+func conditionalKey(res boomer.Result) string {
+	switch {
+	case !res.Validated:
+		return "unconditional"
+	case res.StatusCode == 304:
+		return "304 not modified"
+	default:
+		return "conditional, full response"
+	}
+}
+
+// preflightKey groups the preflights of results by the request they were
+// sent before.
+func preflightKey(res boomer.Result) string {
+	if res.Label == "" {
+		return "OPTIONS"
+	}
+	return "OPTIONS before " + res.Label
+}
+
+// failed tells whether res is an error or a server failure, streams closed
+// by the server are not.
+func failed(res boomer.Result) bool {
+	if res.Err != nil {
+		return res.Err != boomer.ErrStreamClosed
+	}
+	return res.StatusCode >= 500
+}
+
+func (b *BasicInterface) printKeepAlive(stats boomer.ConnStats) {
+	fmt.Printf("\nKeep-alive:\n")
+	fmt.Printf("  Connections:\t%d opened, %d closed, %d closed by server\n", stats.Opened, stats.Closed, stats.ClosedByServer)
+	fmt.Printf("  Requests per connection:\t%4.2f\n", float64(stats.Requests)/float64(stats.Opened))
+	if stats.Closed > 0 {
+		for i, p := range boomer.LifetimePercentiles {
+			fmt.Printf("  %v%% of connections lived %4.4f secs.\n", p, stats.Lifetimes[i].Seconds())
+		}
+		fmt.Printf("  Longest lived %4.4f secs.\n", stats.LongestLifetime.Seconds())
+	}
+}
+
+// addError counts an error, folding new kinds of them once there are
+// maxErrorKinds so unique messages don't grow memory with the requests.
+func (b *BasicInterface) addError(msg string) {
+	if _, ok := b.errorDist[msg]; !ok && len(b.errorDist) >= maxErrorKinds {
+		msg = otherErrors
+	}
+	b.errorDist[msg]++
+	if b.errorLog != nil {
+		b.errorLog.log(time.Now().Sub(b.start), msg, b.errorDist[msg])
+	}
+}
+
+func (b *BasicInterface) errorCount() int {
+	var count int
+	for _, num := range b.errorDist {
+		count += num
+	}
+	return count
+}
+
 func (b *BasicInterface) printErrors() {
 	fmt.Printf("\nError distribution:\n")
 	for err, num := range b.errorDist {
@@ -0,0 +1,70 @@
+package interfaces
+
+import (
+	"fmt"
+
+	"github.com/mercadolibre/pla/boomer"
+)
+
+type groupStats struct {
+	count      int
+	errors     int
+	overBudget int
+	total      float64
+}
+
+func (s *groupStats) add(res boomer.Result) {
+	s.count++
+	if res.OverBudget {
+		s.overBudget++
+	}
+	if res.Err != nil {
+		s.errors++
+	} else {
+		s.total += res.Duration.Seconds()
+	}
+}
+
+// print prints the statistics of the group named key, out of count results.
+func (s *groupStats) print(key string, count int) {
+	var avg float64
+	if ok := s.count - s.errors; ok > 0 {
+		avg = s.total / float64(ok)
+	}
+	fmt.Printf("  [%s]\t%d requests (%4.2f%%), %d errors, average %4.4f secs.",
+		key, s.count, float64(s.count)*100/float64(count), s.errors, avg)
+	if s.overBudget > 0 {
+		fmt.Printf(", breached its budget %d times (%4.2f%%)", s.overBudget, float64(s.overBudget)*100/float64(s.count))
+	}
+	fmt.Printf("\n")
+}
+
+// breakdown keeps statistics of results grouped by some key, in the order
+// keys were first seen.
+type breakdown struct {
+	keys  []string
+	stats map[string]*groupStats
+	count int
+}
+
+func newBreakdown() *breakdown {
+	return &breakdown{stats: make(map[string]*groupStats)}
+}
+
+func (d *breakdown) add(key string, res boomer.Result) {
+	stats, ok := d.stats[key]
+	if !ok {
+		stats = &groupStats{}
+		d.stats[key] = stats
+		d.keys = append(d.keys, key)
+	}
+	d.count++
+	stats.add(res)
+}
+
+func (d *breakdown) print(title string) {
+	fmt.Printf("\n%s:\n", title)
+	for _, key := range d.keys {
+		d.stats[key].print(key, d.count)
+	}
+}
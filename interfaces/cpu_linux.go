@@ -0,0 +1,15 @@
+package interfaces
+
+import (
+	"syscall"
+	"time"
+)
+
+// cpuTime returns the CPU time used by pla so far.
+func cpuTime() (time.Duration, bool) {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return 0, false
+	}
+	return time.Duration(usage.Utime.Nano() + usage.Stime.Nano()), true
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package interfaces
+
+import "time"
+
+// cpuTime is only supported on Linux.
+func cpuTime() (time.Duration, bool) {
+	return 0, false
+}
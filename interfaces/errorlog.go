@@ -0,0 +1,29 @@
+package interfaces
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// ErrorLog prints errors while running, every distinct one the first time
+// it happens and then once every Every occurrences, so problems show up
+// right away instead of only in the final error distribution.
+type ErrorLog struct {
+	Every int
+	Out   io.Writer
+}
+
+// log prints the error msg, seen count times so far, when due.
+func (l *ErrorLog) log(elapsed time.Duration, msg string, count int) {
+	if msg == otherErrors {
+		return
+	}
+	switch {
+	case count == 1:
+		// The carriage return starts the line over the progress bar.
+		fmt.Fprintf(l.Out, "\r[%v] New error: %s\n", elapsed/time.Second*time.Second, msg)
+	case l.Every > 0 && count%l.Every == 0:
+		fmt.Fprintf(l.Out, "\r[%v] Error: %s, %d occurrences\n", elapsed/time.Second*time.Second, msg, count)
+	}
+}
@@ -0,0 +1,232 @@
+package interfaces
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"sort"
+)
+
+const (
+	// exactRun is how many latencies are kept in memory before they are
+	// sorted and spilled to disk as a run, 8MB worth of them.
+	exactRun = 1 << 20
+
+	// exactSmallRun is the run used once memory is constrained.
+	exactSmallRun = exactRun / 64
+
+	// exactTier is how many runs of the same tier are compacted into one
+	// of the next, so every latency is rewritten once per tier.
+	exactTier = 8
+)
+
+// exactLatencies keeps every latency to compute exact percentiles, spilling
+// sorted runs of them to disk so memory stays bounded. The percentiles are
+// then found merging the runs.
+type exactLatencies struct {
+	values      []float64
+	runs        []*os.File
+	tiers       []int
+	count       int
+	constrained bool
+
+	// limit is how many latencies are kept in memory before they are
+	// spilled, exactRun or exactSmallRun when 0.
+	limit int
+}
+
+func (e *exactLatencies) add(sec float64) {
+	e.values = append(e.values, sec)
+	e.count++
+	limit := e.limit
+	if limit == 0 {
+		limit = exactRun
+		if e.constrained {
+			limit = exactSmallRun
+		}
+	}
+	if len(e.values) >= limit {
+		// Memory permitting, keep going without spilling when it fails.
+		if e.spill() == nil {
+			e.values = e.values[:0]
+			if e.constrained {
+				// Let the large buffer go.
+				e.values = nil
+			}
+		}
+	}
+}
+
+// spill writes the in memory latencies sorted to a new run.
+func (e *exactLatencies) spill() error {
+	file, err := ioutil.TempFile("", "pla-latencies")
+	if err != nil {
+		return err
+	}
+	sort.Float64s(e.values)
+	w := bufio.NewWriter(file)
+	err = binary.Write(w, binary.LittleEndian, e.values)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+	e.runs = append(e.runs, file)
+	e.tiers = append(e.tiers, 0)
+	// The new run is already safe on disk, failing to compact only keeps
+	// more files open.
+	for e.compact() {
+	}
+	return nil
+}
+
+// quantiles returns the latencies at the nearest rank of each of qs, which
+// must be ascending.
+func (e *exactLatencies) quantiles(qs []float64) ([]float64, error) {
+	ranks := make([]int, len(qs))
+	for i, q := range qs {
+		ranks[i] = int(math.Ceil(q * float64(e.count)))
+		if ranks[i] < 1 {
+			ranks[i] = 1
+		}
+	}
+	result := make([]float64, 0, len(qs))
+	var rank int
+	err := merge(e.runs, e.values, func(v float64) bool {
+		rank++
+		for len(result) < len(ranks) && ranks[len(result)] == rank {
+			result = append(result, v)
+		}
+		return len(result) < len(ranks)
+	})
+	return result, err
+}
+
+// compact merges the last runs into one of the next tier when there are
+// exactTier of the same tier, so long runs don't keep too many files open.
+// It reports whether it did.
+func (e *exactLatencies) compact() bool {
+	n := len(e.runs)
+	if n < exactTier || e.tiers[n-exactTier] != e.tiers[n-1] {
+		return false
+	}
+	file, err := ioutil.TempFile("", "pla-latencies")
+	if err != nil {
+		return false
+	}
+	w := bufio.NewWriter(file)
+	var werr error
+	err = merge(e.runs[n-exactTier:], nil, func(v float64) bool {
+		werr = binary.Write(w, binary.LittleEndian, v)
+		return werr == nil
+	})
+	if err == nil {
+		err = werr
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return false
+	}
+	for _, f := range e.runs[n-exactTier:] {
+		f.Close()
+		os.Remove(f.Name())
+	}
+	tier := e.tiers[n-1] + 1
+	e.runs = append(e.runs[:n-exactTier], file)
+	e.tiers = append(e.tiers[:n-exactTier], tier)
+	return true
+}
+
+// merge calls visit with the latencies of the spilled runs and values in
+// ascending order, until it returns false.
+func merge(files []*os.File, values []float64, visit func(float64) bool) error {
+	runs := make(runHeap, 0, len(files)+1)
+	for _, file := range files {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		r := &run{reader: bufio.NewReader(file)}
+		switch err := r.next(); err {
+		case nil:
+			runs = append(runs, r)
+		case io.EOF:
+		default:
+			return err
+		}
+	}
+	sort.Float64s(values)
+	if len(values) > 0 {
+		r := &run{values: values}
+		r.next()
+		runs = append(runs, r)
+	}
+	heap.Init(&runs)
+	for len(runs) > 0 {
+		r := runs[0]
+		if !visit(r.head) {
+			return nil
+		}
+		switch err := r.next(); err {
+		case nil:
+			heap.Fix(&runs, 0)
+		case io.EOF:
+			heap.Pop(&runs)
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// close removes the spilled runs.
+func (e *exactLatencies) close() {
+	for _, file := range e.runs {
+		file.Close()
+		os.Remove(file.Name())
+	}
+	e.runs = nil
+	e.tiers = nil
+}
+
+// run is a sorted sequence of latencies, read from disk or memory.
+type run struct {
+	head   float64
+	reader *bufio.Reader
+	values []float64
+}
+
+func (r *run) next() error {
+	if r.reader == nil {
+		if len(r.values) == 0 {
+			return io.EOF
+		}
+		r.head, r.values = r.values[0], r.values[1:]
+		return nil
+	}
+	return binary.Read(r.reader, binary.LittleEndian, &r.head)
+}
+
+// runHeap orders runs by their head.
+type runHeap []*run
+
+func (h runHeap) Len() int            { return len(h) }
+func (h runHeap) Less(i, j int) bool  { return h[i].head < h[j].head }
+func (h runHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *runHeap) Push(x interface{}) { *h = append(*h, x.(*run)) }
+func (h *runHeap) Pop() interface{} {
+	old := *h
+	r := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return r
+}
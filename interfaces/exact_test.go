@@ -0,0 +1,57 @@
+package interfaces
+
+import (
+	"math"
+	"math/rand"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestExactLatencies(t *testing.T) {
+	// Runs of 10 latencies, so 1005 of them spill 100 runs, compacted into
+	// one of tier 2, four of tier 1 and four of tier 0, and keep 5 in memory.
+	e := &exactLatencies{limit: 10}
+	r := rand.New(rand.NewSource(1))
+	var all []float64
+	for i := 0; i < 1005; i++ {
+		// Repeat some latencies, as coarse clocks do.
+		sec := float64(r.Intn(500)) / 1000
+		all = append(all, sec)
+		e.add(sec)
+	}
+	if want := []int{2, 1, 1, 1, 1, 0, 0, 0, 0}; !reflect.DeepEqual(e.tiers, want) {
+		t.Errorf("Expected runs of tiers %v, found %v", want, e.tiers)
+	}
+	if len(e.values) != 5 {
+		t.Errorf("Expected 5 latencies in memory, found %d", len(e.values))
+	}
+	names := make([]string, len(e.runs))
+	for i, file := range e.runs {
+		names[i] = file.Name()
+	}
+
+	sort.Float64s(all)
+	qs := []float64{0, 0.1, 0.5, 0.9, 0.99, 0.999, 1}
+	got, err := e.quantiles(qs)
+	if err != nil {
+		t.Fatalf("Unexpected error merging runs: %v", err)
+	}
+	for i, q := range qs {
+		rank := int(math.Ceil(q * float64(len(all))))
+		if rank < 1 {
+			rank = 1
+		}
+		if i >= len(got) || got[i] != all[rank-1] {
+			t.Errorf("Expected percentile %v to be %v, found %v", q*100, all[rank-1], got)
+		}
+	}
+
+	e.close()
+	for _, name := range names {
+		if _, err := os.Stat(name); !os.IsNotExist(err) {
+			t.Errorf("Expected run %v to be removed, found %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package interfaces
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/mercadolibre/pla/boomer"
+)
+
+// Formats results can be exported in.
+const (
+	ExportJSON = "ndjson"
+	ExportCSV  = "csv"
+)
+
+// Compressions of exported results.
+const (
+	CompressNone = "none"
+	CompressGzip = "gzip"
+	CompressZstd = "zstd"
+)
+
+const (
+	// exportQueue is how many results can wait for the export writer before
+	// the reporter does.
+	exportQueue = 4096
+
+	// exportBuffer is the size of the buffer in front of the file.
+	exportBuffer = 256 << 10
+)
+
+// Export writes every result as a JSON line or a CSV row, optionally gzip
+// or zstd compressed. Writing happens on its own goroutine behind a queue
+// and a buffer, so a slow disk doesn't hold back the reporter, and through
+// it the workers.
+type Export struct {
+	results chan boomer.Result
+	done    chan struct{}
+	closing sync.Once
+	count   int
+	err     error
+}
+
+// exportRecord is the JSON line or CSV row a result is exported as, times
+// in seconds. Durations are measured with the monotonic clock, so only
+// Start and End are wall clock times, End being Start plus Duration.
+type exportRecord struct {
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	Label      string    `json:"label,omitempty"`
+	Tenant     string    `json:"tenant,omitempty"`
+	Scenario   string    `json:"scenario,omitempty"`
+	Step       int       `json:"step,omitempty"`
+	OverBudget bool      `json:"over_budget,omitempty"`
+	Addr       string    `json:"addr,omitempty"`
+	StatusCode int       `json:"status,omitempty"`
+	Duration   float64   `json:"duration"`
+	FirstByte  float64   `json:"first_byte,omitempty"`
+	Size       int       `json:"size,omitempty"`
+	Attempts   int       `json:"attempts,omitempty"`
+	Err        string    `json:"error,omitempty"`
+
+	// Transaction is the duration of the iteration the result completes.
+	Transaction       float64 `json:"transaction,omitempty"`
+	TransactionFailed bool    `json:"transaction_failed,omitempty"`
+
+	CacheStatus string `json:"cache,omitempty"`
+	Validated   bool   `json:"validated,omitempty"`
+
+	Preflight    float64 `json:"preflight,omitempty"`
+	PreflightErr string  `json:"preflight_error,omitempty"`
+
+	TraceID string `json:"trace_id,omitempty"`
+}
+
+// csvHeader names the columns of CSV rows, as the fields of JSON lines.
+var csvHeader = []string{
+	"start", "end", "label", "tenant", "scenario", "step", "over_budget",
+	"addr", "status", "duration", "first_byte", "size", "attempts", "error",
+	"transaction", "transaction_failed", "cache", "validated", "preflight",
+	"preflight_error", "trace_id",
+}
+
+func (r exportRecord) csv() []string {
+	secs := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return []string{
+		r.Start.Format(time.RFC3339Nano), r.End.Format(time.RFC3339Nano),
+		r.Label, r.Tenant, r.Scenario, strconv.Itoa(r.Step),
+		strconv.FormatBool(r.OverBudget), r.Addr, strconv.Itoa(r.StatusCode),
+		secs(r.Duration), secs(r.FirstByte), strconv.Itoa(r.Size),
+		strconv.Itoa(r.Attempts), r.Err, secs(r.Transaction),
+		strconv.FormatBool(r.TransactionFailed), r.CacheStatus,
+		strconv.FormatBool(r.Validated), secs(r.Preflight), r.PreflightErr,
+		r.TraceID,
+	}
+}
+
+// NewExport starts exporting results to w in format, ExportJSON or
+// ExportCSV, compressed with compression, one of the Compress constants.
+// Close, or End, must be called to flush them.
+func NewExport(w io.Writer, format, compression string) *Export {
+	e := &Export{
+		results: make(chan boomer.Result, exportQueue),
+		done:    make(chan struct{}),
+	}
+	go e.write(w, format, compression)
+	return e
+}
+
+func (e *Export) write(w io.Writer, format, compression string) {
+	defer close(e.done)
+	buf := bufio.NewWriterSize(w, exportBuffer)
+	out := io.Writer(buf)
+	var zw io.WriteCloser
+	switch compression {
+	case CompressGzip:
+		zw = gzip.NewWriter(buf)
+	case CompressZstd:
+		zw, e.err = zstd.NewWriter(buf)
+	}
+	if zw != nil {
+		out = zw
+	}
+	encode := json.NewEncoder(out).Encode
+	var cw *csv.Writer
+	if format == ExportCSV && e.err == nil {
+		cw = csv.NewWriter(out)
+		e.err = cw.Write(csvHeader)
+		encode = func(v interface{}) error {
+			return cw.Write(v.(exportRecord).csv())
+		}
+	}
+	for res := range e.results {
+		if e.err != nil {
+			// Keep draining so the reporter never waits on a broken export.
+			continue
+		}
+		record := exportRecord{
+			Start:      res.Start,
+			End:        res.Start.Add(res.Duration),
+			Label:      res.Label,
+			Tenant:     res.Tenant,
+			Scenario:   res.Scenario,
+			Step:       res.Step,
+			OverBudget: res.OverBudget,
+			Addr:       res.Addr,
+			StatusCode: res.StatusCode,
+			Duration:   res.Duration.Seconds(),
+			FirstByte:  res.FirstByte.Seconds(),
+			Size:       res.ContentLength,
+			Attempts:   res.Attempts,
+		}
+		if res.Err != nil {
+			record.Err = res.Err.Error()
+		}
+		record.CacheStatus, record.Validated = res.CacheStatus, res.Validated
+		record.Preflight = res.Preflight.Seconds()
+		record.TraceID = res.TraceID
+		if res.PreflightErr != nil {
+			record.PreflightErr = res.PreflightErr.Error()
+		}
+		if res.Transaction > 0 {
+			record.Transaction = res.Transaction.Seconds()
+			record.TransactionFailed = res.TransactionFailed
+		}
+		if e.err = encode(record); e.err == nil {
+			e.count++
+		}
+	}
+	if cw != nil && e.err == nil {
+		cw.Flush()
+		e.err = cw.Error()
+	}
+	if zw != nil && e.err == nil {
+		e.err = zw.Close()
+	}
+	if e.err == nil {
+		e.err = buf.Flush()
+	}
+}
+
+// Start implements the interface of pla's user interfaces, there is
+// nothing to start.
+func (e *Export) Start(b *boomer.Boomer) {}
+
+// ProcessResult queues res to be written.
+func (e *Export) ProcessResult(res boomer.Result) {
+	e.results <- res
+}
+
+// End flushes the exported results and reports how many were written.
+func (e *Export) End() {
+	e.Close()
+	e.print()
+}
+
+// Close flushes the exported results and returns the first error writing
+// them, if any.
+func (e *Export) Close() error {
+	e.closing.Do(func() { close(e.results) })
+	<-e.done
+	return e.err
+}
+
+func (e *Export) print() {
+	fmt.Printf("\nExport:\n")
+	fmt.Printf("  Written:\t%d results\n", e.count)
+	if e.err != nil {
+		fmt.Printf("  Stopped writing:\t%v\n", e.err)
+	}
+}
@@ -0,0 +1,135 @@
+package interfaces
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/mercadolibre/pla/boomer"
+)
+
+func exportResults() []boomer.Result {
+	start := time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC)
+	return []boomer.Result{
+		{Start: start, Duration: 25 * time.Millisecond, StatusCode: 200, ContentLength: 512, Label: "get", Attempts: 1},
+		{Start: start.Add(time.Second), Duration: time.Second, Err: errors.New("timeout"), Label: "post, \"quoted\"", Attempts: 2},
+	}
+}
+
+// decompress returns a reader of data compressed with compression.
+func decompress(t *testing.T, data []byte, compression string) io.Reader {
+	switch compression {
+	case CompressGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	case CompressZstd:
+		r, err := zstd.NewReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	return bytes.NewReader(data)
+}
+
+func TestExport(t *testing.T) {
+	results := exportResults()
+	for _, format := range []string{ExportJSON, ExportCSV} {
+		for _, compression := range []string{CompressNone, CompressGzip, CompressZstd} {
+			var out bytes.Buffer
+			e := NewExport(&out, format, compression)
+			for _, res := range results {
+				e.ProcessResult(res)
+			}
+			if err := e.Close(); err != nil {
+				t.Fatalf("Unexpected error exporting %v %v: %v", format, compression, err)
+			}
+			if e.count != len(results) {
+				t.Errorf("Expected %d results written as %v %v, found %d", len(results), format, compression, e.count)
+			}
+
+			var rows [][]string
+			r := decompress(t, out.Bytes(), compression)
+			if format == ExportCSV {
+				records, err := csv.NewReader(r).ReadAll()
+				if err != nil {
+					t.Fatalf("Invalid CSV compressed with %v: %v", compression, err)
+				}
+				if len(records) == 0 || !reflect.DeepEqual(records[0], csvHeader) {
+					t.Fatalf("Expected the CSV header first with %v, found %v", compression, records)
+				}
+				rows = records[1:]
+			} else {
+				scanner := bufio.NewScanner(r)
+				for scanner.Scan() {
+					var record exportRecord
+					if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+						t.Fatalf("Invalid JSON line compressed with %v: %v", compression, err)
+					}
+					rows = append(rows, record.csv())
+				}
+			}
+			if len(rows) != len(results) {
+				t.Fatalf("Expected %d %v rows with %v, found %d", len(results), format, compression, len(rows))
+			}
+			for i, res := range results {
+				want := exportRecord{
+					Start:      res.Start,
+					End:        res.Start.Add(res.Duration),
+					Label:      res.Label,
+					StatusCode: res.StatusCode,
+					Duration:   res.Duration.Seconds(),
+					Size:       res.ContentLength,
+					Attempts:   res.Attempts,
+				}
+				if res.Err != nil {
+					want.Err = res.Err.Error()
+				}
+				if !reflect.DeepEqual(rows[i], want.csv()) {
+					t.Errorf("Expected result %d exported as %v with %v to be %v, found %v", i, format, compression, want.csv(), rows[i])
+				}
+			}
+		}
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestExportError(t *testing.T) {
+	e := NewExport(failingWriter{}, ExportJSON, CompressNone)
+	// Results past the queue and the buffer keep being taken after the
+	// write fails, so the reporter never waits on a broken export.
+	results := make(chan struct{})
+	go func() {
+		for i := 0; i < exportQueue+exportBuffer; i++ {
+			e.ProcessResult(exportResults()[0])
+		}
+		close(results)
+	}()
+	select {
+	case <-results:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected a failed export to keep draining results")
+	}
+	if err := e.Close(); err == nil || err.Error() != "disk full" {
+		t.Errorf("Expected the write error, found %v", err)
+	}
+	if e.count >= exportQueue+exportBuffer {
+		t.Errorf("Expected results after the error not to count as written, found %d", e.count)
+	}
+}
@@ -0,0 +1,41 @@
+package interfaces
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync/atomic"
+)
+
+// memoryCap tells when the heap grows past a limit, so exact percentiles
+// keep less of it, and the peak heap.
+type memoryCap struct {
+	limit   uint64
+	reached int32
+	peak    uint64
+}
+
+// check compares the heap against the limit, it is called periodically
+// from the status goroutine.
+func (m *memoryCap) check() {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	if stats.HeapAlloc > atomic.LoadUint64(&m.peak) {
+		atomic.StoreUint64(&m.peak, stats.HeapAlloc)
+	}
+	if stats.HeapAlloc > m.limit && atomic.CompareAndSwapInt32(&m.reached, 0, 1) {
+		debug.FreeOSMemory()
+	}
+}
+
+func (m *memoryCap) exceeded() bool {
+	return atomic.LoadInt32(&m.reached) == 1
+}
+
+func (m *memoryCap) print() {
+	fmt.Printf("\nMemory:\n")
+	fmt.Printf("  Limit:\t%d MB, peak heap %d MB\n", m.limit>>20, atomic.LoadUint64(&m.peak)>>20)
+	if m.exceeded() {
+		fmt.Printf("  Reached:\texact latencies, if kept, were spilled to disk in smaller runs from then on\n")
+	}
+}
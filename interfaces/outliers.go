@@ -0,0 +1,94 @@
+package interfaces
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/mercadolibre/pla/boomer"
+	"github.com/sschepens/gohistogram"
+)
+
+// outlierMinSamples is how many latencies are needed before flagging
+// outliers, so the first requests don't get flagged against noise.
+const outlierMinSamples = 100
+
+// Outliers flags requests whose latency is far above the median of those
+// seen so far.
+type Outliers struct {
+	// StdDevs flags latencies more than this many standard deviations
+	// above the median, P99 those above this multiple of the 99th
+	// percentile. Only one of them is expected to be set.
+	StdDevs float64
+	P99     float64
+
+	// Dump receives the details of every outlier when set.
+	Dump io.Writer
+
+	count int
+	total float64
+}
+
+// threshold returns the latency above which requests are outliers.
+func (o *Outliers) threshold(h *gohistogram.NumericHistogram) float64 {
+	if o.P99 > 0 {
+		return h.Quantile(0.99) * o.P99
+	}
+	return h.Quantile(0.5) + o.StdDevs*math.Sqrt(h.Variance())
+}
+
+// check flags res when its latency of sec seconds is an outlier.
+func (o *Outliers) check(res boomer.Result, sec float64, h *gohistogram.NumericHistogram) {
+	if h.Count() < outlierMinSamples {
+		return
+	}
+	threshold := o.threshold(h)
+	if sec <= threshold {
+		return
+	}
+	o.count++
+	o.total += sec
+	if o.Dump != nil {
+		o.dump(res, sec, threshold)
+	}
+}
+
+func (o *Outliers) dump(res boomer.Result, sec, threshold float64) {
+	fmt.Fprintf(o.Dump, "%s latency=%4.4fs threshold=%4.4fs status=%d",
+		res.Start.Format(time.RFC3339Nano), sec, threshold, res.StatusCode)
+	if res.FirstByte > 0 {
+		fmt.Fprintf(o.Dump, " first_byte=%4.4fs", res.FirstByte.Seconds())
+	}
+	if res.Attempts > 1 {
+		fmt.Fprintf(o.Dump, " attempts=%d", res.Attempts)
+	}
+	if res.Label != "" {
+		fmt.Fprintf(o.Dump, " label=%s", res.Label)
+	}
+	if res.Addr != "" {
+		fmt.Fprintf(o.Dump, " addr=%s", res.Addr)
+	}
+	if res.Backend != "" {
+		fmt.Fprintf(o.Dump, " backend=%s", res.Backend)
+	}
+	if res.TraceID != "" {
+		fmt.Fprintf(o.Dump, " trace_id=%s", res.TraceID)
+	}
+	fmt.Fprintf(o.Dump, "\n%s\n", res.Header)
+}
+
+func (o *Outliers) print(requests uint64) {
+	fmt.Printf("\nOutliers:\n")
+	if o.P99 > 0 {
+		fmt.Printf("  Threshold:\t%v times the 99%% latency\n", o.P99)
+	} else {
+		fmt.Printf("  Threshold:\t%v standard deviations above the median\n", o.StdDevs)
+	}
+	if o.count == 0 {
+		fmt.Printf("  Flagged:\tnone\n")
+		return
+	}
+	fmt.Printf("  Flagged:\t%d requests (%4.2f%%)\n", o.count, float64(o.count)*100/float64(requests))
+	fmt.Printf("  Average:\t%4.4f secs.\n", o.total/float64(o.count))
+}
@@ -0,0 +1,82 @@
+package interfaces
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mercadolibre/pla/monitor"
+)
+
+// resourceRows is the maximum amount of rows the target resources are
+// summarized in.
+const resourceRows = 720
+
+// resources samples, every interval of the test, the CPU and memory the
+// target used into the timeline, correlating them with the latency pla saw.
+type resources struct {
+	sampler  monitor.Sampler
+	every    time.Duration
+	timeline *timeline
+
+	lock     sync.Mutex
+	failures int
+	lastErr  error
+	done     chan struct{}
+}
+
+func (r *resources) run() {
+	r.done = make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(r.every)
+		defer ticker.Stop()
+		for {
+			u, err := r.sampler.Sample()
+			if err != nil {
+				r.lock.Lock()
+				r.failures++
+				r.lastErr = err
+				r.lock.Unlock()
+			} else {
+				r.timeline.sample(time.Now(), u)
+			}
+			select {
+			case <-r.done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (r *resources) stop() {
+	if r.done != nil {
+		close(r.done)
+		r.done = nil
+	}
+}
+
+func (r *resources) print() {
+	fmt.Printf("\nTarget resources:\n")
+	r.timeline.lock.Lock()
+	r.timeline.rows(resourceRows, func(from, to time.Duration, b timelineBucket) {
+		secs := (to - from).Seconds()
+		fmt.Printf("  [%gs - %gs]\t%4.1f req/s", from.Seconds(), to.Seconds(), float64(b.latencies)/secs)
+		if b.latencies > 0 {
+			fmt.Printf(", average %4.4f secs., slowest %4.4f secs.", b.latency/float64(b.latencies), b.slowest)
+		}
+		if b.samples > 0 {
+			fmt.Printf(", target CPU %4.1f%%, memory %4.1f MB", b.cpu/float64(b.samples), b.memory/float64(b.samples)/(1<<20))
+		}
+		if b.netIn > 0 || b.netOut > 0 {
+			fmt.Printf(", network %4.1f MB in, %4.1f MB out so far", b.netIn/(1<<20), b.netOut/(1<<20))
+		}
+		fmt.Printf("\n")
+	})
+	r.timeline.lock.Unlock()
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.failures > 0 {
+		fmt.Printf("  Failed samples:\t%d, last one: %v\n", r.failures, r.lastErr)
+	}
+}
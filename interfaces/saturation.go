@@ -0,0 +1,87 @@
+package interfaces
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/mercadolibre/pla/boomer"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	// saturationLag is how late a request may be sent before it counts as
+	// delayed by pla itself, and saturationLate the fraction of them which
+	// is a symptom of saturation.
+	saturationLag  = 10 * time.Millisecond
+	saturationLate = 0.01
+
+	// saturationBlocked is the fraction of the time of workers they may
+	// wait for the reporter, saturationCPU the fraction of the CPUs pla may
+	// use, before they are symptoms of saturation.
+	saturationBlocked = 0.01
+	saturationCPU     = 0.9
+)
+
+// saturation looks for symptoms of pla saturating before the target, when
+// latencies measure the load generator rather than the target.
+type saturation struct {
+	scheduled int
+	late      int
+	noConns   int
+
+	cpuStart time.Duration
+	cpuOK    bool
+}
+
+func (s *saturation) start() {
+	s.cpuStart, s.cpuOK = cpuTime()
+}
+
+func (s *saturation) add(res boomer.Result) {
+	if res.Lag != 0 {
+		s.scheduled++
+		if res.Lag > saturationLag {
+			s.late++
+		}
+	}
+	if res.Err == fasthttp.ErrNoFreeConns {
+		s.noConns++
+	}
+}
+
+// symptoms returns the symptoms found in a test of total time.
+func (s *saturation) symptoms(boom *boomer.Boomer, total time.Duration) []string {
+	var found []string
+	if s.scheduled > 0 && float64(s.late) > float64(s.scheduled)*saturationLate {
+		found = append(found, fmt.Sprintf("%4.2f%% of requests were sent more than %v after scheduled",
+			float64(s.late)*100/float64(s.scheduled), saturationLag))
+	}
+	stats := boom.ResultsStats()
+	if stats.Dropped > 0 {
+		found = append(found, fmt.Sprintf("%d results were dropped, the reporter could not keep up", stats.Dropped))
+	}
+	if workers := total * time.Duration(boom.C); workers > 0 && float64(stats.Blocked) > float64(workers)*saturationBlocked {
+		found = append(found, fmt.Sprintf("workers spent %4.2f%% of their time waiting for the reporter",
+			float64(stats.Blocked)*100/float64(workers)))
+	}
+	if s.noConns > 0 {
+		found = append(found, fmt.Sprintf("%d requests found no free connection", s.noConns))
+	}
+	if s.cpuOK && total > 0 {
+		if end, ok := cpuTime(); ok {
+			used := float64(end-s.cpuStart) / float64(total) / float64(runtime.GOMAXPROCS(0))
+			if used > saturationCPU {
+				found = append(found, fmt.Sprintf("pla used %4.2f%% of its CPUs", used*100))
+			}
+		}
+	}
+	return found
+}
+
+func (s *saturation) print(symptoms []string) {
+	fmt.Printf("\nWARNING: pla may have saturated, latencies may be limited by the load generator rather than the target:\n")
+	for _, symptom := range symptoms {
+		fmt.Printf("  - %s\n", symptom)
+	}
+}
@@ -0,0 +1,47 @@
+package interfaces
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mercadolibre/pla/boomer"
+	"github.com/sschepens/gohistogram"
+)
+
+// schedule keeps track of how far send times deviated from the ones
+// scheduled by the rate limit.
+type schedule struct {
+	lags  *gohistogram.NumericHistogram
+	max   time.Duration
+	late  int
+	count int
+}
+
+func newSchedule() *schedule {
+	return &schedule{lags: gohistogram.NewHistogram(10)}
+}
+
+// add records the lag of res, which is late when it was sent more than one
+// interval after it was due.
+func (s *schedule) add(res boomer.Result, interval time.Duration) {
+	s.count++
+	s.lags.Add(res.Lag.Seconds())
+	if res.Lag > s.max {
+		s.max = res.Lag
+	}
+	if res.Lag > interval {
+		s.late++
+	}
+}
+
+func (s *schedule) print(interval, total time.Duration) {
+	fmt.Printf("\nScheduling accuracy:\n")
+	fmt.Printf("  Intended rate:\t%4.4f requests/sec\n", float64(time.Second)/float64(interval))
+	fmt.Printf("  Achieved rate:\t%4.4f requests/sec\n", float64(s.count)/total.Seconds())
+	for _, p := range []int{50, 90, 99} {
+		fmt.Printf("  %v%% lag:\t%4.4f secs.\n", p, s.lags.Quantile(float64(p)/100))
+	}
+	fmt.Printf("  Max lag:\t%4.4f secs.\n", s.max.Seconds())
+	fmt.Printf("  Late:\t%d requests (%4.2f%%) were sent more than one interval after scheduled\n",
+		s.late, float64(s.late)*100/float64(s.count))
+}
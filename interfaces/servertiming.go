@@ -0,0 +1,58 @@
+package interfaces
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mercadolibre/pla/boomer"
+	"github.com/sschepens/gohistogram"
+)
+
+// serverTimings keeps the distributions of the phases servers reported in
+// Server-Timing headers, in the order they were first seen.
+type serverTimings struct {
+	names  []string
+	phases map[string]*serverPhase
+}
+
+// serverPhase is the distribution of a phase, along with the latency of the
+// responses reporting it, to tell its share of it.
+type serverPhase struct {
+	histo  *gohistogram.NumericHistogram
+	count  int
+	total  time.Duration
+	client time.Duration
+}
+
+func newServerTimings() *serverTimings {
+	return &serverTimings{phases: make(map[string]*serverPhase)}
+}
+
+func (s *serverTimings) add(res boomer.Result) {
+	for _, t := range res.ServerTimings {
+		p, ok := s.phases[t.Name]
+		if !ok {
+			p = &serverPhase{histo: gohistogram.NewHistogram(10)}
+			s.phases[t.Name] = p
+			s.names = append(s.names, t.Name)
+		}
+		p.histo.Add(t.Duration.Seconds())
+		p.count++
+		p.total += t.Duration
+		p.client += res.Duration
+	}
+}
+
+func (s *serverTimings) print(count int) {
+	fmt.Printf("\nServer timing:\n")
+	for _, name := range s.names {
+		p := s.phases[name]
+		fmt.Printf("  [%s]\t%d responses (%4.2f%%), average %4.4f secs., 50%% in %4.4f secs., 99%% in %4.4f secs.",
+			name, p.count, float64(p.count)*100/float64(count), p.total.Seconds()/float64(p.count),
+			p.histo.Quantile(0.5), p.histo.Quantile(0.99))
+		if p.client > 0 {
+			fmt.Printf(", %4.2f%% of their latency", float64(p.total)*100/float64(p.client))
+		}
+		fmt.Printf("\n")
+	}
+}
@@ -0,0 +1,74 @@
+package interfaces
+
+import (
+	"fmt"
+
+	"github.com/mercadolibre/pla/boomer"
+)
+
+// sizeBuckets are the upper bounds of the request body sizes latencies are
+// grouped by, larger bodies fall in a last bucket.
+var sizeBuckets = []int{0, 1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20}
+
+// sizeBreakdown keeps statistics of results grouped by request body size.
+type sizeBreakdown struct {
+	buckets []groupStats
+	count   int
+}
+
+func newSizeBreakdown() *sizeBreakdown {
+	return &sizeBreakdown{buckets: make([]groupStats, len(sizeBuckets)+1)}
+}
+
+func (d *sizeBreakdown) add(res boomer.Result) {
+	i := 0
+	for i < len(sizeBuckets) && res.RequestSize > sizeBuckets[i] {
+		i++
+	}
+	d.buckets[i].add(res)
+	d.count++
+}
+
+// varied tells whether requests fell in more than one bucket, otherwise
+// there is nothing to correlate.
+func (d *sizeBreakdown) varied() bool {
+	used := 0
+	for _, b := range d.buckets {
+		if b.count > 0 {
+			used++
+		}
+	}
+	return used > 1
+}
+
+func (d *sizeBreakdown) print() {
+	fmt.Printf("\nLatency by request size:\n")
+	for i, b := range d.buckets {
+		if b.count == 0 {
+			continue
+		}
+		b.print(sizeLabel(i), d.count)
+	}
+}
+
+func sizeLabel(i int) string {
+	switch {
+	case i == 0:
+		return "empty"
+	case i == len(sizeBuckets):
+		return "> " + formatSize(sizeBuckets[i-1])
+	default:
+		return formatSize(sizeBuckets[i-1]) + " - " + formatSize(sizeBuckets[i])
+	}
+}
+
+func formatSize(size int) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%d MB", size>>20)
+	case size >= 1<<10:
+		return fmt.Sprintf("%d KB", size>>10)
+	default:
+		return fmt.Sprintf("%d B", size)
+	}
+}
@@ -0,0 +1,55 @@
+package interfaces
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// SLO is a latency service level objective, like 99% of requests answered
+// within 200ms. Failed requests violate it too.
+type SLO struct {
+	Latency time.Duration
+	Target  float64
+
+	requests int64
+	violated int64
+}
+
+// add records a request, violated when it failed or was too slow.
+func (s *SLO) add(violated bool) {
+	atomic.AddInt64(&s.requests, 1)
+	if violated {
+		atomic.AddInt64(&s.violated, 1)
+	}
+}
+
+// rates returns the fraction of requests violating the objective so far
+// and the rate at which they burn the error budget, 1 spending it exactly
+// over the objective's window.
+func (s *SLO) rates() (violating, burn float64) {
+	requests := atomic.LoadInt64(&s.requests)
+	if requests == 0 {
+		return 0, 0
+	}
+	violating = float64(atomic.LoadInt64(&s.violated)) / float64(requests)
+	return violating, violating / (1 - s.Target)
+}
+
+func (s *SLO) status() string {
+	violating, burn := s.rates()
+	return fmt.Sprintf(", SLO %4.2f%% violating, %3.1fx burn", violating*100, burn)
+}
+
+func (s *SLO) print() {
+	violating, burn := s.rates()
+	fmt.Printf("\nSLO:\n")
+	fmt.Printf("  Objective:\t%4.2f%% of requests within %v\n", s.Target*100, s.Latency)
+	fmt.Printf("  Violating:\t%d requests (%4.2f%%)\n", atomic.LoadInt64(&s.violated), violating*100)
+	fmt.Printf("  Budget burn:\t%3.1fx", burn)
+	if burn > 1 {
+		fmt.Printf(", the error budget would run out %3.1f times faster than allowed\n", burn)
+	} else {
+		fmt.Printf(", within the error budget\n")
+	}
+}
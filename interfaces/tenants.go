@@ -0,0 +1,82 @@
+package interfaces
+
+import (
+	"fmt"
+
+	"github.com/mercadolibre/pla/boomer"
+	"github.com/sschepens/gohistogram"
+)
+
+// maxTenants is how many tenants are reported apart, the rest are merged
+// so unique ids don't grow memory with the requests.
+const maxTenants = 100
+
+// otherTenants is the tenant of results past maxTenants.
+const otherTenants = "other tenants"
+
+type tenantStats struct {
+	groupStats
+	histo *gohistogram.NumericHistogram
+}
+
+// tenants keeps statistics of results per tenant, in the order tenants were
+// first seen, to compare how each of them is served.
+type tenants struct {
+	keys  []string
+	stats map[string]*tenantStats
+	count int
+}
+
+func newTenants() *tenants {
+	return &tenants{stats: make(map[string]*tenantStats)}
+}
+
+func (t *tenants) add(res boomer.Result) {
+	key := res.Tenant
+	stats, ok := t.stats[key]
+	if !ok {
+		if len(t.keys) >= maxTenants {
+			key = otherTenants
+		}
+		if stats, ok = t.stats[key]; !ok {
+			stats = &tenantStats{histo: gohistogram.NewHistogram(10)}
+			t.stats[key] = stats
+			t.keys = append(t.keys, key)
+		}
+	}
+	t.count++
+	stats.add(res)
+	if res.Err == nil {
+		stats.histo.Add(res.Duration.Seconds())
+	}
+}
+
+func (t *tenants) print() {
+	fmt.Printf("\nTenants:\n")
+	var slowest, fastest string
+	for _, key := range t.keys {
+		s := t.stats[key]
+		var avg float64
+		if ok := s.count - s.errors; ok > 0 {
+			avg = s.total / float64(ok)
+		}
+		fmt.Printf("  [%s]\t%d requests (%4.2f%%), %4.2f%% errors, average %4.4f secs., 50%% in %4.4f secs., 99%% in %4.4f secs.\n",
+			key, s.count, float64(s.count)*100/float64(t.count), float64(s.errors)*100/float64(s.count),
+			avg, s.histo.Quantile(0.5), s.histo.Quantile(0.99))
+		if s.histo.Count() == 0 || key == otherTenants {
+			continue
+		}
+		if slowest == "" || s.histo.Quantile(0.99) > t.stats[slowest].histo.Quantile(0.99) {
+			slowest = key
+		}
+		if fastest == "" || s.histo.Quantile(0.99) < t.stats[fastest].histo.Quantile(0.99) {
+			fastest = key
+		}
+	}
+	if slowest != fastest && t.stats[fastest].histo.Quantile(0.99) > 0 {
+		fmt.Printf("  Spread:\t99%% of %s in %4.4f secs., %4.2fx the %4.4f secs. of %s\n",
+			slowest, t.stats[slowest].histo.Quantile(0.99),
+			t.stats[slowest].histo.Quantile(0.99)/t.stats[fastest].histo.Quantile(0.99),
+			t.stats[fastest].histo.Quantile(0.99), fastest)
+	}
+}
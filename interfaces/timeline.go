@@ -0,0 +1,172 @@
+package interfaces
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mercadolibre/pla/boomer"
+	"github.com/mercadolibre/pla/monitor"
+)
+
+const (
+	// timelineRows is the maximum amount of rows the error timeline is
+	// summarized in.
+	timelineRows = 10
+
+	// timelineBuckets is the maximum amount of buckets kept, once reached
+	// adjacent ones are merged so memory doesn't grow with long runs.
+	timelineBuckets = 3600
+)
+
+// timeline keeps counts of requests and errors every step since the start
+// of the test, telling warm-up issues apart from resource exhaustion, along
+// with the latencies and the resources of the target when sampled.
+type timeline struct {
+	lock    sync.Mutex
+	start   time.Time
+	step    time.Duration
+	buckets []timelineBucket
+	failed  int
+	first   time.Duration
+}
+
+type timelineBucket struct {
+	requests int
+	errors   int
+
+	// latencies is the amount of successful requests, latency the sum of
+	// their latencies and slowest the largest one.
+	latencies int
+	latency   float64
+	slowest   float64
+
+	// samples is the amount of samples of the target resources, netIn and
+	// netOut are the network bytes of the last one.
+	samples int
+	cpu     float64
+	memory  float64
+	netIn   float64
+	netOut  float64
+}
+
+func (a timelineBucket) merge(b timelineBucket) timelineBucket {
+	a.requests += b.requests
+	a.errors += b.errors
+	a.latencies += b.latencies
+	a.latency += b.latency
+	if b.slowest > a.slowest {
+		a.slowest = b.slowest
+	}
+	a.samples += b.samples
+	a.cpu += b.cpu
+	a.memory += b.memory
+	if b.samples > 0 {
+		a.netIn, a.netOut = b.netIn, b.netOut
+	}
+	return a
+}
+
+// coarsen halves the resolution of the buckets.
+func (t *timeline) coarsen() {
+	for i := 0; i < len(t.buckets); i += 2 {
+		merged := t.buckets[i]
+		if i+1 < len(t.buckets) {
+			merged = merged.merge(t.buckets[i+1])
+		}
+		t.buckets[i/2] = merged
+	}
+	t.buckets = t.buckets[:(len(t.buckets)+1)/2]
+	t.step *= 2
+}
+
+// bucket returns the bucket at offset from the start, coarsening them when
+// there would be too many.
+func (t *timeline) bucket(offset time.Duration) *timelineBucket {
+	if offset < 0 {
+		offset = 0
+	}
+	i := int(offset / t.step)
+	for i >= timelineBuckets {
+		t.coarsen()
+		i = int(offset / t.step)
+	}
+	for len(t.buckets) <= i {
+		t.buckets = append(t.buckets, timelineBucket{})
+	}
+	return &t.buckets[i]
+}
+
+func (t *timeline) add(res boomer.Result, failed bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	offset := res.Start.Sub(t.start)
+	b := t.bucket(offset)
+	b.requests++
+	if !failed {
+		return
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	if t.failed == 0 || offset < t.first {
+		t.first = offset
+	}
+	t.failed++
+	b.errors++
+}
+
+// latency adds the latency, in seconds, of the successful request res.
+func (t *timeline) latency(res boomer.Result, sec float64) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	b := t.bucket(res.Start.Sub(t.start))
+	b.latencies++
+	b.latency += sec
+	if sec > b.slowest {
+		b.slowest = sec
+	}
+}
+
+// sample adds the resources the target used at now.
+func (t *timeline) sample(now time.Time, u monitor.Usage) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	b := t.bucket(now.Sub(t.start))
+	b.samples++
+	b.cpu += u.CPU
+	b.memory += u.Memory
+	b.netIn, b.netOut = u.NetworkIn, u.NetworkOut
+}
+
+// rows calls row with the buckets merged into at most n rows, along with
+// the offsets each one spans.
+func (t *timeline) rows(n int, row func(from, to time.Duration, b timelineBucket)) {
+	width := (len(t.buckets) + n - 1) / n
+	for from := 0; from < len(t.buckets); from += width {
+		to := from + width
+		if to > len(t.buckets) {
+			to = len(t.buckets)
+		}
+		var merged timelineBucket
+		for i := from; i < to; i++ {
+			merged = merged.merge(t.buckets[i])
+		}
+		row(time.Duration(from)*t.step, time.Duration(to)*t.step, merged)
+	}
+}
+
+func (t *timeline) print() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	fmt.Printf("\nError timeline:\n")
+	fmt.Printf("  First error:\t%4.4f secs. after start\n", t.first.Seconds())
+	t.rows(timelineRows, func(from, to time.Duration, b timelineBucket) {
+		var pct float64
+		if b.requests > 0 {
+			pct = float64(b.errors) * 100 / float64(b.requests)
+		}
+		fmt.Printf("  [%gs - %gs]\t%d errors (%4.2f%% of requests), %4.2f errors/sec\n",
+			from.Seconds(), to.Seconds(), b.errors, pct, float64(b.errors)/(to-from).Seconds())
+	})
+}
@@ -0,0 +1,92 @@
+package interfaces
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mercadolibre/pla/boomer"
+	"github.com/sschepens/gohistogram"
+)
+
+type transactionStats struct {
+	count   int
+	failed  int
+	total   float64
+	slowest float64
+	fastest float64
+	histo   *gohistogram.NumericHistogram
+}
+
+func (t *transactionStats) add(res boomer.Result) {
+	t.count++
+	if res.TransactionFailed {
+		t.failed++
+		return
+	}
+	sec := res.Transaction.Seconds()
+	t.total += sec
+	t.histo.Add(sec)
+	if sec > t.slowest {
+		t.slowest = sec
+	}
+	if t.fastest == 0 || sec < t.fastest {
+		t.fastest = sec
+	}
+}
+
+// print prints the statistics of the transactions, out of count of every
+// scenario.
+func (t *transactionStats) print(count int, total time.Duration) {
+	fmt.Printf("  Completed:\t%d iterations (%4.2f%%), %d failed (%4.2f%%)\n",
+		t.count, float64(t.count)*100/float64(count), t.failed, float64(t.failed)*100/float64(t.count))
+	fmt.Printf("  Iterations/sec:\t%4.4f\n", float64(t.count)/total.Seconds())
+	ok := t.count - t.failed
+	if ok == 0 {
+		return
+	}
+	fmt.Printf("  Slowest:\t%4.4f secs.\n", t.slowest)
+	fmt.Printf("  Fastest:\t%4.4f secs.\n", t.fastest)
+	fmt.Printf("  Average:\t%4.4f secs.\n", t.total/float64(ok))
+	for _, q := range []float64{0.5, 0.9, 0.95, 0.99} {
+		fmt.Printf("  %v%% in %4.4f secs.\n", q*100, t.histo.Quantile(q))
+	}
+}
+
+// transactions keeps statistics of whole scenario iterations, as users
+// experience their journeys, per scenario in the order they were first
+// seen.
+type transactions struct {
+	keys  []string
+	stats map[string]*transactionStats
+	count int
+}
+
+func newTransactions() *transactions {
+	return &transactions{stats: make(map[string]*transactionStats)}
+}
+
+// add accounts for the transaction completed by res, if any.
+func (t *transactions) add(res boomer.Result) {
+	if res.Transaction == 0 {
+		return
+	}
+	stats, ok := t.stats[res.Scenario]
+	if !ok {
+		stats = &transactionStats{histo: gohistogram.NewHistogram(10)}
+		t.stats[res.Scenario] = stats
+		t.keys = append(t.keys, res.Scenario)
+	}
+	t.count++
+	stats.add(res)
+}
+
+func (t *transactions) print(total time.Duration) {
+	for _, key := range t.keys {
+		if key == "" {
+			fmt.Printf("\nTransactions:\n")
+		} else {
+			fmt.Printf("\nTransactions of %s:\n", key)
+		}
+		t.stats[key].print(t.count, total)
+	}
+}
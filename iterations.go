@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"github.com/mercadolibre/pla/stats"
+)
+
+// confidence is the level of the intervals reported across iterations.
+const confidence = 0.95
+
+// iterationMetric is a figure tracked across iterations.
+type iterationMetric struct {
+	name   string
+	format string
+	value  func(t *target) float64
+	sample stats.Sample
+}
+
+func latencyQuantile(q float64) func(t *target) float64 {
+	return func(t *target) float64 {
+		return t.latencies.Quantile(q)
+	}
+}
+
+// sequence runs tests one after the other, stopping the current one and
+// skipping the rest when interrupted.
+type sequence struct {
+	lock    sync.Mutex
+	current *target
+	stopped bool
+}
+
+func newSequence() *sequence {
+	s := &sequence{}
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	go func() {
+		<-c
+		s.lock.Lock()
+		s.stopped = true
+		if s.current != nil {
+			s.current.boom.Stop()
+		}
+		s.lock.Unlock()
+	}()
+	return s
+}
+
+// run runs the test of t, returning false without running it when the
+// sequence was interrupted.
+func (s *sequence) run(t *target) bool {
+	prepare(t.boom)
+	waitForStart()
+	s.lock.Lock()
+	if s.stopped {
+		s.lock.Unlock()
+		return false
+	}
+	s.current = t
+	s.lock.Unlock()
+
+	t.boom.Run()
+	t.process(time.Now())
+	return true
+}
+
+// iterate runs the test against url the given amount of times, discarding
+// the first warmup ones, and reports how stable key metrics were.
+func iterate(url string, iterations, warmup uint) {
+	metrics := []*iterationMetric{
+		{name: "Requests/sec", format: "%4.4f", value: func(t *target) float64 {
+			return float64(t.latencies.Len()+t.errors) / t.total.Seconds()
+		}},
+		{name: "Average", format: "%4.4f secs.", value: func(t *target) float64 { return t.latencies.Mean() }},
+		{name: "50%", format: "%4.4f secs.", value: latencyQuantile(0.5)},
+		{name: "90%", format: "%4.4f secs.", value: latencyQuantile(0.9)},
+		{name: "99%", format: "%4.4f secs.", value: latencyQuantile(0.99)},
+		{name: "Errors", format: "%4.2f", value: func(t *target) float64 { return float64(t.errors) }},
+	}
+
+	seq := newSequence()
+	for i := uint(1); i <= iterations; i++ {
+		t := &target{url: url}
+		t.boom = newBoomer(newRequest(url))
+		if !seq.run(t) {
+			break
+		}
+		kind := ""
+		if i <= warmup {
+			kind = " (warm-up, discarded)"
+		}
+		fmt.Printf("Iteration %d/%d%s: %4.4f requests/sec, average %4.4f secs., 99%% in %4.4f secs., %d errors\n",
+			i, iterations, kind, metrics[0].value(t), t.latencies.Mean(), t.latencies.Quantile(0.99), t.errors)
+		if i <= warmup {
+			continue
+		}
+		for _, m := range metrics {
+			if v := m.value(t); !math.IsNaN(v) {
+				m.sample.Add(v)
+			}
+		}
+	}
+	printIterations(metrics)
+}
+
+func printIterations(metrics []*iterationMetric) {
+	runs := metrics[0].sample.Len()
+	fmt.Printf("\nIterations:\n")
+	fmt.Printf("  Measured:\t%d iterations\n", runs)
+	if runs == 0 {
+		return
+	}
+	for _, m := range metrics {
+		lo, hi := m.sample.ConfidenceInterval(confidence)
+		fmt.Printf("  %s:\t"+m.format+" ± "+m.format+", %v%% CI ["+m.format+", "+m.format+"]\n",
+			m.name, m.sample.Mean(), m.sample.StdDev(), confidence*100, lo, hi)
+	}
+	if runs < 2 {
+		fmt.Printf("  At least 2 measured iterations are needed for confidence intervals.\n")
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/mercadolibre/pla/kv"
+)
+
+// kvTest runs a load test of GET and SET commands against the key-value
+// server at addr.
+func kvTest(addr string) {
+	min, max, err := kv.ParseSize(*kvValueSize)
+	if err != nil {
+		usageAndExit(err.Error())
+	}
+	if *kvGets > 100 {
+		usageAndExit("gets must be a percentage between 0 and 100")
+	}
+	if *kvKeys <= 0 {
+		usageAndExit("keys must be positive")
+	}
+	w := kv.Workload{
+		Gets:         *kvGets,
+		Keys:         *kvKeys,
+		Distribution: *kvDistribution,
+		MinSize:      min,
+		MaxSize:      max,
+		Seed:         *seed,
+	}
+	conns := int(*c)
+	if conns == 0 {
+		conns = runtime.NumCPU()
+	}
+	requestMix, requestFilter = w.Mix()
+	doer = kv.NewClient(*kvProtocol, addr, conns)
+	fmt.Printf("Testing %s at %s, %d%% GET over %d keys\n", *kvProtocol, addr, w.Gets, w.Keys)
+	run("http://" + addr + "/")
+}
@@ -0,0 +1,229 @@
+// Package kv load tests Redis and Memcached servers with Boomer, so cache
+// tiers get the same reports and thresholds as HTTP services.
+package kv
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Protocols of the servers.
+const (
+	Redis     = "redis"
+	Memcached = "memcached"
+)
+
+// dialTimeout bounds opening connections, as requests without a timeout
+// would otherwise wait on unreachable servers forever.
+const dialTimeout = 10 * time.Second
+
+// Client is a boomer.Doer sending requests as commands to a key-value
+// server: GET /key reads key, PUT or POST /key sets it to the body and
+// DELETE /key removes it. Responses are 200, with the value of reads, 404
+// for missing keys, and 500 with the reply of the server when it fails.
+type Client struct {
+	addr     string
+	protocol string
+	idle     chan *conn
+}
+
+type conn struct {
+	net.Conn
+	r *bufio.Reader
+	w *bufio.Writer
+}
+
+// NewClient returns a client of the server at addr speaking protocol,
+// keeping up to conns idle connections, which should be the concurrency.
+func NewClient(protocol, addr string, conns int) *Client {
+	return &Client{addr: addr, protocol: protocol, idle: make(chan *conn, conns)}
+}
+
+// Do sends req as a command and sets its reply on resp.
+func (c *Client) Do(req *fasthttp.Request, resp *fasthttp.Response) error {
+	return c.DoTimeout(req, resp, 0)
+}
+
+// DoTimeout sends req as a command and sets its reply on resp, failing with
+// fasthttp.ErrTimeout when it isn't received within timeout.
+func (c *Client) DoTimeout(req *fasthttp.Request, resp *fasthttp.Response, timeout time.Duration) error {
+	method := string(req.Header.Method())
+	key := string(bytes.TrimPrefix(req.URI().Path(), []byte("/")))
+	if key == "" {
+		return fmt.Errorf("kv requests need a key as path")
+	}
+	cn, err := c.conn()
+	if err != nil {
+		return err
+	}
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	cn.SetDeadline(deadline)
+
+	var status int
+	var value []byte
+	if c.protocol == Memcached {
+		status, value, err = cn.memcached(method, key, req.Body())
+	} else {
+		status, value, err = cn.redis(method, key, req.Body())
+	}
+	if err != nil {
+		cn.Close()
+		if ne, ok := err.(net.Error); ok && ne.Timeout() {
+			return fasthttp.ErrTimeout
+		}
+		return err
+	}
+	select {
+	case c.idle <- cn:
+	default:
+		cn.Close()
+	}
+	resp.SetStatusCode(status)
+	resp.SetBody(value)
+	return nil
+}
+
+func (c *Client) conn() (*conn, error) {
+	select {
+	case cn := <-c.idle:
+		return cn, nil
+	default:
+	}
+	nc, err := net.DialTimeout("tcp", c.addr, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: nc, r: bufio.NewReader(nc), w: bufio.NewWriter(nc)}, nil
+}
+
+// redis sends a command with the RESP protocol and reads its reply.
+func (cn *conn) redis(method, key string, value []byte) (int, []byte, error) {
+	var args [][]byte
+	switch method {
+	case "GET":
+		args = [][]byte{[]byte("GET"), []byte(key)}
+	case "PUT", "POST":
+		args = [][]byte{[]byte("SET"), []byte(key), value}
+	case "DELETE":
+		args = [][]byte{[]byte("DEL"), []byte(key)}
+	default:
+		return 0, nil, fmt.Errorf("kv does not support method %v", method)
+	}
+	fmt.Fprintf(cn.w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(cn.w, "$%d\r\n", len(arg))
+		cn.w.Write(arg)
+		cn.w.WriteString("\r\n")
+	}
+	if err := cn.w.Flush(); err != nil {
+		return 0, nil, err
+	}
+
+	line, err := cn.line()
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(line) == 0 {
+		return 0, nil, fmt.Errorf("empty redis reply")
+	}
+	switch line[0] {
+	case '+':
+		return fasthttp.StatusOK, nil, nil
+	case '-':
+		return fasthttp.StatusInternalServerError, line[1:], nil
+	case ':':
+		// Deleting a missing key removes none.
+		if string(line[1:]) == "0" {
+			return fasthttp.StatusNotFound, nil, nil
+		}
+		return fasthttp.StatusOK, nil, nil
+	case '$':
+		size, err := strconv.Atoi(string(line[1:]))
+		if err != nil {
+			return 0, nil, fmt.Errorf("invalid redis reply %q", line)
+		}
+		if size < 0 {
+			return fasthttp.StatusNotFound, nil, nil
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(cn.r, data); err != nil {
+			return 0, nil, err
+		}
+		return fasthttp.StatusOK, data[:size], nil
+	}
+	return 0, nil, fmt.Errorf("unexpected redis reply %q", line)
+}
+
+// memcached sends a command with the memcached text protocol and reads its
+// reply.
+func (cn *conn) memcached(method, key string, value []byte) (int, []byte, error) {
+	switch method {
+	case "GET":
+		fmt.Fprintf(cn.w, "get %s\r\n", key)
+	case "PUT", "POST":
+		fmt.Fprintf(cn.w, "set %s 0 0 %d\r\n", key, len(value))
+		cn.w.Write(value)
+		cn.w.WriteString("\r\n")
+	case "DELETE":
+		fmt.Fprintf(cn.w, "delete %s\r\n", key)
+	default:
+		return 0, nil, fmt.Errorf("kv does not support method %v", method)
+	}
+	if err := cn.w.Flush(); err != nil {
+		return 0, nil, err
+	}
+
+	line, err := cn.line()
+	if err != nil {
+		return 0, nil, err
+	}
+	reply := string(line)
+	switch {
+	case reply == "STORED", reply == "DELETED":
+		return fasthttp.StatusOK, nil, nil
+	case reply == "END", reply == "NOT_FOUND":
+		return fasthttp.StatusNotFound, nil, nil
+	case bytes.HasPrefix(line, []byte("VALUE ")):
+		fields := bytes.Fields(line)
+		if len(fields) < 4 {
+			return 0, nil, fmt.Errorf("invalid memcached reply %q", line)
+		}
+		size, err := strconv.Atoi(string(fields[3]))
+		if err != nil {
+			return 0, nil, fmt.Errorf("invalid memcached reply %q", line)
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(cn.r, data); err != nil {
+			return 0, nil, err
+		}
+		if end, err := cn.line(); err != nil {
+			return 0, nil, err
+		} else if string(end) != "END" {
+			return 0, nil, fmt.Errorf("unexpected memcached reply %q", end)
+		}
+		return fasthttp.StatusOK, data[:size], nil
+	case reply == "ERROR", bytes.HasPrefix(line, []byte("CLIENT_ERROR")), bytes.HasPrefix(line, []byte("SERVER_ERROR")),
+		reply == "NOT_STORED", reply == "EXISTS":
+		return fasthttp.StatusInternalServerError, line, nil
+	}
+	return 0, nil, fmt.Errorf("unexpected memcached reply %q", line)
+}
+
+// line reads a reply line, without its CRLF.
+func (cn *conn) line() ([]byte, error) {
+	line, err := cn.r.ReadSlice('\n')
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(line, "\r\n"), nil
+}
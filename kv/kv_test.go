@@ -0,0 +1,102 @@
+package kv
+
+import (
+	"bufio"
+	"net"
+	"reflect"
+	"testing"
+)
+
+// serve answers every line read from the server side of a pipe with the
+// next of replies.
+func serve(replies ...string) *conn {
+	client, server := net.Pipe()
+	go func() {
+		r := bufio.NewReader(server)
+		for _, reply := range replies {
+			if _, err := r.ReadString('\n'); err != nil {
+				return
+			}
+			server.Write([]byte(reply))
+		}
+	}()
+	return &conn{Conn: client, r: bufio.NewReader(client), w: bufio.NewWriter(client)}
+}
+
+func TestRedis(t *testing.T) {
+	tests := []struct {
+		method, reply string
+		status        int
+		value         string
+	}{
+		{"GET", "$5\r\nhello\r\n", 200, "hello"},
+		{"GET", "$-1\r\n", 404, ""},
+		{"DELETE", ":0\r\n", 404, ""},
+		{"GET", "-ERR wrong type\r\n", 500, "ERR wrong type"},
+	}
+	for _, test := range tests {
+		cn := serve(test.reply)
+		status, value, err := cn.redis(test.method, "key", nil)
+		if err != nil || status != test.status || string(value) != test.value {
+			t.Errorf("Expected %d %q for %q, found %d %q %v", test.status, test.value, test.reply, status, value, err)
+		}
+		cn.Close()
+	}
+}
+
+func TestMemcached(t *testing.T) {
+	tests := []struct {
+		method, reply string
+		status        int
+		value         string
+	}{
+		{"GET", "VALUE key 0 5\r\nhello\r\nEND\r\n", 200, "hello"},
+		{"GET", "END\r\n", 404, ""},
+		{"DELETE", "NOT_FOUND\r\n", 404, ""},
+		{"GET", "SERVER_ERROR out of memory\r\n", 500, "SERVER_ERROR out of memory"},
+	}
+	for _, test := range tests {
+		cn := serve(test.reply)
+		status, value, err := cn.memcached(test.method, "key", nil)
+		if err != nil || status != test.status || string(value) != test.value {
+			t.Errorf("Expected %d %q for %q, found %d %q %v", test.status, test.value, test.reply, status, value, err)
+		}
+		cn.Close()
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	for s, expected := range map[string][2]int{"100": {100, 100}, "10-1000": {10, 1000}} {
+		min, max, err := ParseSize(s)
+		if err != nil || min != expected[0] || max != expected[1] {
+			t.Errorf("Expected %v to parse as %v, found %v %v %v", s, expected, min, max, err)
+		}
+	}
+	for _, s := range []string{"", "big", "100-10", "-5"} {
+		if _, _, err := ParseSize(s); err == nil {
+			t.Errorf("Expected %q to be an invalid size", s)
+		}
+	}
+}
+
+func TestKeys(t *testing.T) {
+	w := Workload{Gets: 50, Keys: 1000, Distribution: Zipf, MinSize: 10, MaxSize: 100, Seed: 1}
+	picks := func(vu int) [][2]int {
+		k := &keys{w: w, seed: w.Seed}
+		var picks [][2]int
+		for i := 0; i < 100; i++ {
+			key, size := k.next(vu, true)
+			if key < 0 || key >= w.Keys || size < w.MinSize || size > w.MaxSize {
+				t.Fatalf("Picked key %d of size %d out of %+v", key, size, w)
+			}
+			picks = append(picks, [2]int{key, size})
+		}
+		return picks
+	}
+	if !reflect.DeepEqual(picks(3), picks(3)) {
+		t.Errorf("Expected a worker to pick the same keys with the same seed")
+	}
+	if reflect.DeepEqual(picks(3), picks(4)) {
+		t.Errorf("Expected workers to pick keys of their own")
+	}
+}
@@ -0,0 +1,164 @@
+package kv
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mercadolibre/pla/boomer"
+	"github.com/valyala/fasthttp"
+)
+
+// Key distributions.
+const (
+	Uniform = "uniform"
+	// Zipf makes a few keys hot, as in most caches.
+	Zipf = "zipf"
+)
+
+// KeyPrefix prefixes the keys of the key space.
+const KeyPrefix = "pla:"
+
+// pickerShards is how many locks the pickers of workers are spread over,
+// so workers don't contend on a single one on every request.
+const pickerShards = 64
+
+// Workload describes a mix of reads and writes over a key space.
+type Workload struct {
+	// Gets is the percentage of reads, the rest are writes.
+	Gets uint
+
+	// Keys is the size of the key space, Distribution how keys are picked
+	// from it.
+	Keys         int
+	Distribution string
+
+	// MinSize and MaxSize bound the size of written values, picked
+	// uniformly between them.
+	MinSize int
+	MaxSize int
+
+	// Seed seeds the picks of every worker, so runs with the same seed and
+	// concurrency pick the same keys and sizes. 0 seeds them with the time.
+	Seed int64
+}
+
+// ParseSize parses a value size, in bytes, or a range of them, ex: 100-1000.
+func ParseSize(s string) (min, max int, err error) {
+	parts := strings.SplitN(s, "-", 2)
+	min, err = strconv.Atoi(parts[0])
+	max = min
+	if err == nil && len(parts) == 2 {
+		max, err = strconv.Atoi(parts[1])
+	}
+	if err != nil || min < 0 || max < min {
+		return 0, 0, fmt.Errorf("value size must be a size in bytes or a range of them, ex: 100-1000")
+	}
+	return min, max, nil
+}
+
+// Mix returns the requests of w for Boomer, to be sent with a Client, and
+// the filter picking their keys and values, which Boomer must run.
+func (w Workload) Mix() ([]*boomer.WeightedRequest, boomer.RequestFilter) {
+	k := &keys{w: w, seed: w.Seed}
+	if k.seed == 0 {
+		k.seed = time.Now().UnixNano()
+	}
+	// Values are slices of a single random one.
+	k.value = make([]byte, w.MaxSize)
+	rand.New(rand.NewSource(k.seed)).Read(k.value)
+	var mix []*boomer.WeightedRequest
+	if w.Gets > 0 {
+		mix = append(mix, request("GET", w.Gets, false))
+	}
+	if w.Gets < 100 {
+		mix = append(mix, request("SET", 100-w.Gets, true))
+	}
+	return mix, k.filter
+}
+
+func request(label string, weight uint, set bool) *boomer.WeightedRequest {
+	req := fasthttp.AcquireRequest()
+	req.Header.SetMethod("GET")
+	if set {
+		req.Header.SetMethod("PUT")
+	}
+	return &boomer.WeightedRequest{
+		Request: req,
+		Weight:  weight,
+		Label:   label,
+	}
+}
+
+// keys picks the keys and values of requests, with a picker per worker.
+type keys struct {
+	w      Workload
+	seed   int64
+	value  []byte
+	shards [pickerShards]pickerShard
+}
+
+// pickerShard holds the pickers of some workers, padded to a cache line so
+// shards don't share one.
+type pickerShard struct {
+	lock    sync.Mutex
+	pickers map[int]*picker
+	_       [48]byte
+}
+
+// picker is the random state of a worker, only used from its goroutine.
+type picker struct {
+	rand *rand.Rand
+	zipf *rand.Zipf
+}
+
+func (k *keys) picker(vu int) *picker {
+	shard := &k.shards[vu%pickerShards]
+	shard.lock.Lock()
+	defer shard.lock.Unlock()
+	if shard.pickers == nil {
+		shard.pickers = make(map[int]*picker)
+	}
+	p, ok := shard.pickers[vu]
+	if !ok {
+		p = &picker{rand: rand.New(rand.NewSource(k.seed + int64(vu)))}
+		if k.w.Distribution == Zipf && k.w.Keys > 1 {
+			p.zipf = rand.NewZipf(p.rand, 1.1, 1, uint64(k.w.Keys-1))
+		}
+		shard.pickers[vu] = p
+	}
+	return p
+}
+
+// filter sets the key of the request of worker vu, and its value when it
+// sets one.
+func (k *keys) filter(vu int, req *fasthttp.Request) error {
+	set := string(req.Header.Method()) == "PUT"
+	key, size := k.next(vu, set)
+	req.URI().SetPath("/" + KeyPrefix + strconv.Itoa(key))
+	if set {
+		req.SetBody(k.value[:size])
+	}
+	return nil
+}
+
+// next returns the key of the next request of worker vu, and the size of
+// its value when it sets one.
+func (k *keys) next(vu int, set bool) (int, int) {
+	p := k.picker(vu)
+	var key int
+	switch {
+	case p.zipf != nil:
+		key = int(p.zipf.Uint64())
+	case k.w.Keys > 1:
+		key = p.rand.Intn(k.w.Keys)
+	}
+	var size int
+	if set {
+		size = k.w.MinSize + p.rand.Intn(k.w.MaxSize-k.w.MinSize+1)
+	}
+	return key, size
+}
@@ -0,0 +1,172 @@
+// Package mail load tests SMTP servers with Boomer, so mail submission gets
+// the same reports and thresholds as HTTP requests.
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// dialTimeout bounds connecting and reading the greeting of the server, as
+// requests without a timeout would otherwise wait forever.
+const dialTimeout = 10 * time.Second
+
+// Client is a boomer.Doer submitting requests as messages to an SMTP server,
+// from and to the addresses in their From and To headers, which are added
+// to their body as message. Responses get the reply code of the server as
+// status code, so accepted messages are 250 and permanent failures 5xx.
+type Client struct {
+	addr string
+	host string
+	idle chan *session
+}
+
+type session struct {
+	conn net.Conn
+	smtp *smtp.Client
+}
+
+// NewClient returns a client of the SMTP server at addr, keeping up to conns
+// idle sessions, which should be the concurrency.
+func NewClient(addr string, conns int) *Client {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	return &Client{addr: addr, host: host, idle: make(chan *session, conns)}
+}
+
+// Do submits req as a message and sets the reply code on resp.
+func (c *Client) Do(req *fasthttp.Request, resp *fasthttp.Response) error {
+	return c.DoTimeout(req, resp, 0)
+}
+
+// DoTimeout submits req as a message and sets the reply code on resp,
+// failing with fasthttp.ErrTimeout when it isn't accepted within timeout.
+// An idle session failing before the server accepted MAIL FROM, as when
+// the server closed it meanwhile, is replaced by a new one once.
+func (c *Client) DoTimeout(req *fasthttp.Request, resp *fasthttp.Response, timeout time.Duration) error {
+	from, to := string(req.Header.Peek("From")), string(req.Header.Peek("To"))
+	msg := append([]byte("From: "+from+"\r\nTo: "+to+"\r\n"), req.Body()...)
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	for retried := false; ; retried = true {
+		s, idle, code, err := c.session(retried)
+		if err != nil {
+			return timeoutErr(err)
+		}
+		if s == nil {
+			// The server rejected the session.
+			resp.SetStatusCode(code)
+			return nil
+		}
+		s.conn.SetDeadline(deadline)
+		code, mailed, err := s.send(from, to, msg)
+		if err != nil {
+			s.conn.Close()
+			if idle && !mailed && timeoutErr(err) == err {
+				continue
+			}
+			return timeoutErr(err)
+		}
+		select {
+		case c.idle <- s:
+		default:
+			s.smtp.Quit()
+		}
+		resp.SetStatusCode(code)
+		return nil
+	}
+}
+
+func timeoutErr(err error) error {
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		return fasthttp.ErrTimeout
+	}
+	return err
+}
+
+// session returns an idle session, unless fresh, or opens a new one, or the
+// reply code of the server when it rejected it. idle tells whether the
+// session was idle.
+func (c *Client) session(fresh bool) (s *session, idle bool, code int, err error) {
+	if !fresh {
+		select {
+		case s := <-c.idle:
+			return s, true, 0, nil
+		default:
+		}
+	}
+	conn, err := net.DialTimeout("tcp", c.addr, dialTimeout)
+	if err != nil {
+		return nil, false, 0, err
+	}
+	conn.SetDeadline(time.Now().Add(dialTimeout))
+	client, err := smtp.NewClient(conn, c.host)
+	if err != nil {
+		conn.Close()
+		if e, ok := err.(*textproto.Error); ok {
+			return nil, false, e.Code, nil
+		}
+		return nil, false, 0, err
+	}
+	return &session{conn: conn, smtp: client}, false, 0, nil
+}
+
+// send submits a message, returning the reply code of the server and
+// whether it accepted MAIL FROM. Rejected messages are reset, so the
+// session can be used again.
+func (s *session) send(from, to string, msg []byte) (code int, mailed bool, err error) {
+	err = s.smtp.Mail(from)
+	if err == nil {
+		mailed = true
+		err = s.smtp.Rcpt(to)
+	}
+	if err == nil {
+		var w io.WriteCloser
+		if w, err = s.smtp.Data(); err == nil {
+			if _, err = w.Write(msg); err == nil {
+				err = w.Close()
+			}
+		}
+	}
+	if e, ok := err.(*textproto.Error); ok {
+		if s.smtp.Reset() != nil {
+			return 0, mailed, err
+		}
+		return e.Code, mailed, nil
+	}
+	if err != nil {
+		return 0, mailed, err
+	}
+	return 250, mailed, nil
+}
+
+// Message returns a message of about size bytes, without the From and To
+// headers the client adds, with a body of random text.
+func Message(size int) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "Subject: pla load test\r\nDate: %s\r\n\r\n", time.Now().Format(time.RFC1123Z))
+	const letters = "abcdefghijklmnopqrstuvwxyz"
+	for b.Len() < size {
+		n := 76
+		if left := size - b.Len() - 2; left < n {
+			n = left
+		}
+		for i := 0; i < n; i++ {
+			b.WriteByte(letters[rand.Intn(len(letters))])
+		}
+		b.WriteString("\r\n")
+	}
+	return b.Bytes()
+}
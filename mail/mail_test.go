@@ -0,0 +1,114 @@
+package mail
+
+import (
+	"bufio"
+	"net"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// serve answers SMTP commands, rejecting recipients starting with nobody.
+func serve(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	conn.Write([]byte("220 ready\r\n"))
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		switch cmd := strings.ToUpper(line); {
+		case strings.HasPrefix(cmd, "RCPT TO:<NOBODY"):
+			conn.Write([]byte("550 no such user\r\n"))
+		case strings.HasPrefix(cmd, "DATA"):
+			conn.Write([]byte("354 go ahead\r\n"))
+			for line != ".\r\n" {
+				if line, err = r.ReadString('\n'); err != nil {
+					return
+				}
+			}
+			conn.Write([]byte("250 queued\r\n"))
+		case strings.HasPrefix(cmd, "QUIT"):
+			conn.Write([]byte("221 bye\r\n"))
+			return
+		default:
+			conn.Write([]byte("250 ok\r\n"))
+		}
+	}
+}
+
+func TestSend(t *testing.T) {
+	client, server := net.Pipe()
+	go serve(server)
+	c, err := smtp.NewClient(client, "localhost")
+	if err != nil {
+		t.Fatalf("Could not start session: %v", err)
+	}
+	s := &session{conn: client, smtp: c}
+	for _, test := range []struct {
+		to   string
+		code int
+	}{
+		{"user@example.com", 250},
+		{"nobody@example.com", 550},
+		// Sessions are reset after rejections.
+		{"user@example.com", 250},
+	} {
+		code, _, err := s.send("pla@example.com", test.to, Message(100))
+		if err != nil || code != test.code {
+			t.Errorf("Expected reply %d sending to %v, found %d %v", test.code, test.to, code, err)
+		}
+	}
+	s.smtp.Quit()
+}
+
+func TestDoTimeoutStale(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn)
+		}
+	}()
+
+	// An idle session the server closed meanwhile.
+	client, server := net.Pipe()
+	go serve(server)
+	smtpClient, err := smtp.NewClient(client, "localhost")
+	if err != nil {
+		t.Fatalf("Could not start session: %v", err)
+	}
+	server.Close()
+	c := NewClient(ln.Addr().String(), 1)
+	c.idle <- &session{conn: client, smtp: smtpClient}
+
+	req, resp := fasthttp.AcquireRequest(), fasthttp.AcquireResponse()
+	req.Header.Set("From", "pla@example.com")
+	req.Header.Set("To", "user@example.com")
+	req.SetBody(Message(100))
+	if err := c.DoTimeout(req, resp, time.Second); err != nil || resp.StatusCode() != 250 {
+		t.Errorf("Expected reply 250 on a new session, found %d %v", resp.StatusCode(), err)
+	}
+}
+
+func TestMessage(t *testing.T) {
+	msg := string(Message(500))
+	if len(msg) < 500 || len(msg) > 510 || !strings.HasPrefix(msg, "Subject: ") {
+		t.Errorf("Expected a message of about 500 bytes, found %d: %q", len(msg), msg)
+	}
+	for _, line := range strings.Split(msg, "\r\n") {
+		if len(line) > 78 {
+			t.Errorf("Expected lines up to 78 characters, found %d", len(line))
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+)
+
+// mesh runs identical load against a service directly and through its
+// service mesh sidecar at the same time, connections opened ahead on both,
+// and reports the latency the mesh adds.
+func mesh(direct, viaMesh string) {
+	targets := []*target{{url: direct}, {url: viaMesh}}
+	fmt.Printf("Comparing %s directly and through the mesh at %s...\n", direct, viaMesh)
+	runTogether(targets)
+	printComparison(targets[0], targets[1])
+	printMeshOverhead(targets[0], targets[1])
+}
+
+func printMeshOverhead(direct, viaMesh *target) {
+	if direct.latencies.Len() == 0 || viaMesh.latencies.Len() == 0 {
+		return
+	}
+	fmt.Printf("\nMesh overhead:\n")
+	fmt.Printf("  Average:\t%+4.4f secs.\n", viaMesh.latencies.Mean()-direct.latencies.Mean())
+	for _, q := range []float64{0.5, 0.9, 0.99} {
+		fmt.Printf("  %v%%:\t%+4.4f secs.\n", q*100, viaMesh.latencies.Quantile(q)-direct.latencies.Quantile(q))
+	}
+	fmt.Printf("  Added by the mesh to every request, negative when it was faster.\n")
+}
@@ -0,0 +1,110 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Docker returns a sampler reading the usage of container from docker
+// stats, along with its network traffic.
+func Docker(container string) Sampler {
+	return &docker{container: container}
+}
+
+type docker struct {
+	container string
+}
+
+func (d *docker) Sample() (Usage, error) {
+	out, err := dockerOutput("stats", "--no-stream", "--format", "{{.CPUPerc}}|{{.MemUsage}}|{{.NetIO}}", d.container)
+	if err != nil {
+		return Usage{}, err
+	}
+	return parseDockerStats(out)
+}
+
+// parseDockerStats parses a line of docker stats of the form
+// 12.34%|45.6MiB / 1.944GiB|1.2kB / 3.4kB.
+func parseDockerStats(line string) (Usage, error) {
+	fields := strings.Split(line, "|")
+	if len(fields) != 3 {
+		return Usage{}, fmt.Errorf("unexpected docker stats %q", line)
+	}
+	var u Usage
+	var err error
+	if u.CPU, err = strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(fields[0]), "%"), 64); err != nil {
+		return Usage{}, fmt.Errorf("unexpected docker CPU usage %q", fields[0])
+	}
+	if u.Memory, _, err = parsePair(fields[1]); err != nil {
+		return Usage{}, err
+	}
+	if u.NetworkIn, u.NetworkOut, err = parsePair(fields[2]); err != nil {
+		return Usage{}, err
+	}
+	return u, nil
+}
+
+// parsePair parses two sizes separated by a slash.
+func parsePair(text string) (float64, float64, error) {
+	sizes := strings.Split(text, "/")
+	if len(sizes) != 2 {
+		return 0, 0, fmt.Errorf("unexpected docker sizes %q", text)
+	}
+	a, err := parseSize(sizes[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	b, err := parseSize(sizes[1])
+	return a, b, err
+}
+
+var sizeUnits = []struct {
+	suffix string
+	bytes  float64
+}{
+	// Longer suffixes first, as B ends them all.
+	{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+	{"kB", 1e3}, {"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12},
+	{"B", 1},
+}
+
+// parseSize parses a size as docker prints them, ex: 45.6MiB or 1.2kB.
+func parseSize(text string) (float64, error) {
+	text = strings.TrimSpace(text)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(text, u.suffix) {
+			v, err := strconv.ParseFloat(strings.TrimSuffix(text, u.suffix), 64)
+			if err != nil {
+				break
+			}
+			return v * u.bytes, nil
+		}
+	}
+	return 0, fmt.Errorf("unexpected docker size %q", text)
+}
+
+// ContainerAddr returns the IP address of container, in its first network.
+func ContainerAddr(container string) (string, error) {
+	out, err := dockerOutput("inspect", "--format", "{{range .NetworkSettings.Networks}}{{.IPAddress}} {{end}}", container)
+	if err != nil {
+		return "", err
+	}
+	addrs := strings.Fields(out)
+	if len(addrs) == 0 {
+		return "", fmt.Errorf("container %v has no IP address", container)
+	}
+	return addrs[0], nil
+}
+
+func dockerOutput(args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), sampleTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "docker", args...).Output()
+	if err != nil {
+		return "", fmt.Errorf("docker %v failed: %v", args[0], err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,165 @@
+// Package monitor samples the CPU and memory usage of the target of a test,
+// so it can be told apart from the latency pla sees.
+package monitor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sampleTimeout is how long a sample may take.
+const sampleTimeout = 5 * time.Second
+
+// Usage is the resources used by the target at some point.
+type Usage struct {
+	// CPU is the percentage of CPU used, of a single CPU for processes, so
+	// it may exceed 100, of the whole machine for hosts.
+	CPU float64
+	// Memory is the amount of bytes used.
+	Memory float64
+	// NetworkIn and NetworkOut are the bytes received and sent so far, when
+	// known.
+	NetworkIn  float64
+	NetworkOut float64
+}
+
+// Sampler samples the usage of the target, it is called from a single
+// goroutine.
+type Sampler interface {
+	Sample() (Usage, error)
+}
+
+// Prometheus returns a sampler scraping a Prometheus exporter at url. The
+// standard process metrics, process_cpu_seconds_total and
+// process_resident_memory_bytes, are used when found, otherwise the node
+// exporter ones of the host. CPU usage is measured since the previous
+// sample, the first one reports none.
+func Prometheus(url string) Sampler {
+	return &prometheus{url: url, client: &http.Client{Timeout: sampleTimeout}}
+}
+
+type prometheus struct {
+	url    string
+	client *http.Client
+
+	// cpu and idle are the CPU seconds used and idle at the previous
+	// sample, at when it was taken.
+	at        time.Time
+	cpu, idle float64
+}
+
+func (p *prometheus) Sample() (Usage, error) {
+	resp, err := p.client.Get(p.url)
+	if err != nil {
+		return Usage{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Usage{}, fmt.Errorf("metrics responded with status %d", resp.StatusCode)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return Usage{}, err
+	}
+	m := parseMetrics(body)
+	now := time.Now()
+	var u Usage
+	if cpu, ok := m["process_cpu_seconds_total"]; ok {
+		u.Memory = m["process_resident_memory_bytes"]
+		if !p.at.IsZero() {
+			u.CPU = (cpu - p.cpu) / now.Sub(p.at).Seconds() * 100
+		}
+		p.at, p.cpu = now, cpu
+		return u, nil
+	}
+	total, ok := m["node_cpu_seconds_total"]
+	if !ok {
+		return Usage{}, fmt.Errorf("metrics have neither process nor node exporter CPU usage")
+	}
+	idle := m[`node_cpu_seconds_total{mode="idle"}`]
+	u.Memory = m["node_memory_MemTotal_bytes"] - m["node_memory_MemAvailable_bytes"]
+	if !p.at.IsZero() && total > p.cpu {
+		u.CPU = (1 - (idle-p.idle)/(total-p.cpu)) * 100
+	}
+	p.at, p.cpu, p.idle = now, total, idle
+	return u, nil
+}
+
+// parseMetrics sums the values of every metric in the Prometheus text
+// format across its labels, and of node_cpu_seconds_total those with mode
+// idle apart.
+func parseMetrics(text []byte) map[string]float64 {
+	m := make(map[string]float64)
+	scanner := bufio.NewScanner(bytes.NewReader(text))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, labels := line, ""
+		if i := strings.IndexByte(line, '{'); i >= 0 {
+			end := strings.LastIndexByte(line, '}')
+			if end < i {
+				continue
+			}
+			name, labels, line = line[:i], line[i+1:end], line[end+1:]
+		} else if i := strings.IndexAny(line, " \t"); i >= 0 {
+			name, line = line[:i], line[i:]
+		} else {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		v, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			continue
+		}
+		m[name] += v
+		if name == "node_cpu_seconds_total" && strings.Contains(labels, `mode="idle"`) {
+			m[`node_cpu_seconds_total{mode="idle"}`] += v
+		}
+	}
+	return m
+}
+
+// Command returns a sampler running command with sh, ex: over ssh, which
+// prints the CPU usage in percent and the memory used in bytes, separated
+// by spaces.
+func Command(command string) Sampler {
+	return &shell{command: command}
+}
+
+type shell struct {
+	command string
+}
+
+func (s *shell) Sample() (Usage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), sampleTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "sh", "-c", s.command).Output()
+	if err != nil {
+		return Usage{}, fmt.Errorf("target command failed: %v", err)
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) != 2 {
+		return Usage{}, fmt.Errorf("target command must print CPU percentage and memory bytes, printed %q", out)
+	}
+	var u Usage
+	if u.CPU, err = strconv.ParseFloat(fields[0], 64); err != nil {
+		return Usage{}, fmt.Errorf("target command printed an invalid CPU percentage %q", fields[0])
+	}
+	if u.Memory, err = strconv.ParseFloat(fields[1], 64); err != nil {
+		return Usage{}, fmt.Errorf("target command printed invalid memory bytes %q", fields[1])
+	}
+	return u, nil
+}
@@ -0,0 +1,64 @@
+package monitor
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPrometheusProcess(t *testing.T) {
+	var cpu float64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cpu += 1000
+		fmt.Fprintf(w, "# TYPE process_cpu_seconds_total counter\nprocess_cpu_seconds_total %v\nprocess_resident_memory_bytes 1.048576e+06\n", cpu)
+	}))
+	defer server.Close()
+
+	s := Prometheus(server.URL)
+	u, err := s.Sample()
+	if err != nil || u.CPU != 0 || u.Memory != 1048576 {
+		t.Fatalf("Expected the first sample to only have memory, found %v, %v", u, err)
+	}
+	if u, err = s.Sample(); err != nil || u.CPU <= 0 {
+		t.Errorf("Expected CPU usage since the first sample, found %v, %v", u, err)
+	}
+}
+
+func TestParseMetrics(t *testing.T) {
+	m := parseMetrics([]byte(`
+# HELP node_cpu_seconds_total Seconds the CPUs spent in each mode.
+node_cpu_seconds_total{cpu="0",mode="idle"} 90
+node_cpu_seconds_total{cpu="0",mode="user"} 10
+node_cpu_seconds_total{cpu="1",mode="idle"} 80 1700000000000
+node_memory_MemTotal_bytes 4e+09
+invalid
+`))
+	if m["node_cpu_seconds_total"] != 180 || m[`node_cpu_seconds_total{mode="idle"}`] != 170 || m["node_memory_MemTotal_bytes"] != 4e9 {
+		t.Errorf("Metrics were not parsed correctly: %v", m)
+	}
+}
+
+func TestCommand(t *testing.T) {
+	u, err := Command("echo 12.5 1048576").Sample()
+	if err != nil || u.CPU != 12.5 || u.Memory != 1048576 {
+		t.Errorf("Expected the usage printed by the command, found %v, %v", u, err)
+	}
+	for _, c := range []string{"echo 12.5", "echo x 1", "exit 1"} {
+		if _, err := Command(c).Sample(); err == nil {
+			t.Errorf("An invalid command output was accepted: %q", c)
+		}
+	}
+}
+
+func TestParseDockerStats(t *testing.T) {
+	u, err := parseDockerStats("12.5%|1.5MiB / 1.944GiB|1.2kB / 3MB")
+	if err != nil || u.CPU != 12.5 || u.Memory != 1.5*(1<<20) || u.NetworkIn != 1200 || u.NetworkOut != 3e6 {
+		t.Errorf("Docker stats were not parsed correctly: %v, %v", u, err)
+	}
+	for _, s := range []string{"", "x%|1B / 1B|1B / 1B", "1%|1 / 1B|1B / 1B", "1%|1B|1B / 1B"} {
+		if _, err := parseDockerStats(s); err == nil {
+			t.Errorf("Invalid docker stats passed parsing: %q", s)
+		}
+	}
+}
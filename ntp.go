@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"time"
+)
+
+// ntpEpoch is the Unix time of the NTP epoch, 1900-01-01.
+const ntpEpoch = -2208988800
+
+// ntpTimeout bounds the clock offset check.
+const ntpTimeout = 5 * time.Second
+
+// clockOffset asks server, host[:port], for the time with SNTP, returning
+// how far ahead of it the local clock is.
+func clockOffset(server string) (time.Duration, error) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "123")
+	}
+	conn, err := net.DialTimeout("udp", server, ntpTimeout)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(ntpTimeout))
+
+	req := make([]byte, 48)
+	// No leap indicator, version 4, client mode.
+	req[0] = 0x23
+	sent := time.Now()
+	if _, err := conn.Write(req); err != nil {
+		return 0, err
+	}
+	resp := make([]byte, 48)
+	if n, err := conn.Read(resp); err != nil {
+		return 0, err
+	} else if n < 48 {
+		return 0, fmt.Errorf("short NTP response from %v", server)
+	}
+	received := time.Now()
+	if resp[1] == 0 {
+		return 0, fmt.Errorf("NTP server %v is not synchronized", server)
+	}
+	return ntpOffset(sent, ntpTime(resp[32:40]), ntpTime(resp[40:48]), received), nil
+}
+
+// ntpTime decodes a 64 bits NTP timestamp.
+func ntpTime(b []byte) time.Time {
+	secs := int64(binary.BigEndian.Uint32(b[:4]))
+	frac := int64(binary.BigEndian.Uint32(b[4:]))
+	return time.Unix(secs+ntpEpoch, frac*int64(time.Second)>>32)
+}
+
+// ntpOffset is how far ahead of the server the local clock is, given when
+// the request was sent and the response received locally, and when the
+// server received and answered it, assuming symmetric network delays.
+func ntpOffset(sent, serverReceived, serverSent, received time.Time) time.Duration {
+	return -(serverReceived.Sub(sent) + serverSent.Sub(received)) / 2
+}
+
+// runMetadata describes a run, written along with its results so they can
+// be correlated with logs of other machines.
+type runMetadata struct {
+	RunID string    `json:"run_id"`
+	Host  string    `json:"host"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+
+	// ClockOffset is how far ahead of NTPServer the local clock was, in
+	// seconds, ClockErr why it could not be checked.
+	NTPServer   string  `json:"ntp_server,omitempty"`
+	ClockOffset float64 `json:"clock_offset,omitempty"`
+	ClockErr    string  `json:"clock_error,omitempty"`
+}
+
+// checkClock fills the clock offset of m against server, printing it.
+func (m *runMetadata) checkClock(server string) {
+	m.NTPServer = server
+	offset, err := clockOffset(server)
+	if err != nil {
+		m.ClockErr = err.Error()
+		fmt.Printf("Could not check the clock against %v: %v\n", server, err)
+		return
+	}
+	m.ClockOffset = offset.Seconds()
+	fmt.Printf("Clock offset against %v: %+4.4f secs.\n", server, offset.Seconds())
+}
+
+// write writes m as JSON to path.
+func (m *runMetadata) write(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
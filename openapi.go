@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/mercadolibre/pla/boomer"
+	"github.com/mercadolibre/pla/workload"
+)
+
+// fromOpenAPI runs a load test against the operation of the OpenAPI
+// document at path.
+func fromOpenAPI(path, operation string) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		usageAndExit(err.Error())
+	}
+	spec, err := workload.ParseOpenAPI(data)
+	if err != nil {
+		usageAndExit(err.Error())
+	}
+	op, err := spec.Operation(operation)
+	if err != nil {
+		usageAndExit(err.Error())
+	}
+	server := *openapiServer
+	if server == "" {
+		server = spec.Server()
+	}
+	if server == "" {
+		usageAndExit("the document has no servers, set one with --server")
+	}
+	_, req := newRequest(server)
+	w := op.Request(req, *openapiToken, *openapiFuzz)
+	requestMix = []*boomer.WeightedRequest{w}
+	fmt.Printf("Testing %s %s\n", op.Method, w.Request.URI())
+	run(server)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"io/ioutil"
+	"runtime"
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+// maxCPUs is the amount of CPUs the affinity mask covers.
+const maxCPUs = 1024
+
+// pinCPUs restricts every thread of the process to run on cpus, threads
+// started later inherit it from the one starting them, and runs as many Go
+// threads at once as there are cpus, so they don't contend for them.
+func pinCPUs(cpus []int) error {
+	var mask [maxCPUs / 64]uint64
+	for _, cpu := range cpus {
+		mask[cpu/64] |= 1 << uint(cpu%64)
+	}
+	tasks, err := ioutil.ReadDir("/proc/self/task")
+	if err != nil {
+		return err
+	}
+	for _, task := range tasks {
+		tid, err := strconv.Atoi(task.Name())
+		if err != nil {
+			continue
+		}
+		_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, uintptr(tid), unsafe.Sizeof(mask), uintptr(unsafe.Pointer(&mask)))
+		// The thread may have exited meanwhile.
+		if errno != 0 && errno != syscall.ESRCH {
+			return errno
+		}
+	}
+	runtime.GOMAXPROCS(len(cpus))
+	return nil
+}
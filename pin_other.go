@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+import "errors"
+
+// maxCPUs is the amount of CPUs that can be given to pin to.
+const maxCPUs = 1024
+
+func pinCPUs(cpus []int) error {
+	return errors.New("pin-cpus is only supported on Linux")
+}
@@ -16,23 +16,51 @@ package main
 
 import (
 	"fmt"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"net"
 	"os"
 	"os/signal"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"encoding/base64"
 
 	"github.com/mercadolibre/pla/boomer"
 	"github.com/mercadolibre/pla/interfaces"
+	"github.com/mercadolibre/pla/monitor"
+	"github.com/mercadolibre/pla/plugins"
+	"github.com/mercadolibre/pla/rpc"
+	"github.com/mercadolibre/pla/script"
+	"github.com/mercadolibre/pla/soap"
+	"github.com/mercadolibre/pla/templates"
+	"github.com/mercadolibre/pla/verify"
+	"github.com/mercadolibre/pla/workload"
 	"github.com/valyala/fasthttp"
 	"gopkg.in/alecthomas/kingpin.v2"
 )
 
 const (
-	headerRegexp = `^([\w-]+):\s*(.+)`
-	authRegexp   = `^(.+):([^\s].+)`
+	headerRegexp   = `^([\w-]+):\s*(.+)`
+	authRegexp     = `^(.+):([^\s].+)`
+	vuHeaderRegexp = `^([\w-]+)=(.+)`
+	breakerRegexp  = `^(\d+(?:\.\d+)?)%/(.+)$`
+	outliersRegexp = `^(\d+(?:\.\d+)?)(sd|xp99)$`
+	sloRegexp      = `^(.+)@(\d+(?:\.\d+)?)%$`
+
+	vuPlaceholder = "{{vu}}"
+
+	// syntheticHeader tags requests as load test traffic.
+	syntheticHeader = "X-Synthetic-Load"
+
+	// maxUringWorkers keeps the threads uring connections block, up to two
+	// each, below the 10000 threads a Go program can have.
+	maxUringWorkers = 4000
 )
 
 var (
@@ -40,13 +68,47 @@ var (
 	n        = app.Flag("amount", "Number of requests to run.").Short('n').Default("0").Uint()
 	duration = app.Flag("length", "Length or duration of test, ex: 10s, 1m, 1h, etc. Invalidates n.").Short('l').Default("0s").Duration()
 	c        = app.Flag("concurrency", "Concurrency, number of requests to run concurrently. If concurrency is set as 0 pla will run with the same amount of cores that the processor has. Cannot be larger than n.").Short('c').Default("0").Uint()
-	q        = app.Flag("qps", "Rate Limit, in seconds (QPS).").Short('q').Default("0").Uint()
+	q        = app.Flag("qps", "Rate Limit, in seconds (QPS), can be fractional, ex: 0.5.").Short('q').Default("0").Float64()
+	rate     = app.Flag("rate", "Rate Limit per unit of time, ex: 300/m, 10/s, 2/h or 5/10s.").Default("").String()
+	pacing   = app.Flag("pacing", "How rate limited requests are spread: uniform sends each one at evenly spaced times, burst sends every second's requests at once.").Default("uniform").Enum("uniform", "burst")
 	f        = app.Flag("fail", "Abort on request failure.").Short('f').Default("false").Bool()
+	retries  = app.Flag("retries", "Send failed requests again up to this amount of times, latencies then span every attempt.").Default("0").Uint()
+	idemKey  = app.Flag("idempotency-key", "Set a unique key in this header for every request, kept across its retries, ex: Idempotency-Key.").Default("").String()
+	breaker  = app.Flag("breaker", "Emulate a client side circuit breaker, stop sending requests for the window once the error rate over it reaches the threshold, ex: 50%/10s.").Default("").String()
+	profile  = app.Flag("profile", "Preset of client buffers and concurrency: high-throughput uses large buffers and several workers per core, low-latency a worker per core, many-connections small buffers and 1000 workers. Explicit concurrency still wins.").Default("default").Enum("default", "high-throughput", "low-latency", "many-connections")
+
+	loadModel   = app.Flag("load-model", "How the offered rate evolves: fixed keeps the rate limit, aimd starts at it and searches the target's capacity, adding requests while there are no breaches and cutting them after any.").Default("fixed").Enum("fixed", "aimd")
+	aimdStep    = app.Flag("aimd-step", "Requests per rate unit the aimd model adds every rate unit without breaches.").Default("1").Float64()
+	aimdFactor  = app.Flag("aimd-factor", "Factor the aimd model multiplies the rate by after a rate unit with breaches.").Default("0.5").Float64()
+	aimdLatency = app.Flag("aimd-latency", "Responses slower than this are breaches for the aimd model too, ex: 200ms. 0 only counts failures and 429 responses.").Default("0s").Duration()
+	rpsTrace    = app.Flag("rps-trace", "Follow the rate of a CSV trace of timestamp,rps lines, interpolating between them, ex: recorded production traffic. Sets the length when not given.").Default("").String()
+
+	rateBurst    = app.Flag("rate-burst", "How many rate limited requests may be sent at once, at the start or to catch up when behind, before uniform pacing kicks in. 1 keeps traffic strictly smooth, 0 catches up without limit.").Default("0").Uint()
+	qpsPerWorker = app.Flag("qps-per-worker", "Apply qps or rate to each worker instead of the aggregate, every worker then paces its own requests evenly, ex: -c 10 -q 5 sends 50 QPS.").Default("false").Bool()
+
+	retryAfter = app.Flag("retry-after", "Honor 429 Too Many Requests responses: halve the offered rate and pause for their Retry-After, doubling it back once they stop. Needs a qps, rate or rps-trace to halve.").Default("false").Bool()
 
 	m          = app.Flag("method", "HTTP method.").Short('m').Default("GET").String()
-	headerList = app.Flag("header", "Add custom HTTP header, name1:value1. Can be repeated for more headers.").Short('H').Strings()
+	headerList = app.Flag("header", "Add custom HTTP header, name1:value1, or name1:@file to rotate its value across the lines of file. Can be repeated for more headers.").Short('H').Strings()
 	body       = app.Flag("body", "Request Body.").Short('d').Default("").String()
 	authHeader = app.Flag("auth", "Basic Authentication, username:password.").Short('a').Default("").String()
+	vuHeaders  = app.Flag("vu-header", "Add a per virtual user HTTP header, name=value, {{vu}} is replaced by the number of the worker sending it. Can be repeated.").Strings()
+
+	rotationMode = app.Flag("header-rotation", "How header values from files are rotated, round-robin or random, picked as seeded by seed.").Default("round-robin").Enum("round-robin", "random")
+	tenantHeader = app.Flag("tenant-header", "Report requests count, error rate and latencies per tenant, identified by the value of this request header, which may rotate or use templates.").Default("").String()
+
+	soapAction   = app.Flag("soap-action", "Send a SOAP request with the given SOAPAction header.").Default("").String()
+	soapEnvelope = app.Flag("soap-envelope", "Wrap the request body in a SOAP 1.1 envelope.").Default("false").Bool()
+	xpaths       = app.Flag("xpath", "Fail requests whose XML response does not match the path, ex: //Status=OK. Can be repeated.").Strings()
+
+	rpcProtocol = app.Flag("rpc", "Call a gRPC-Web or unary Connect method, the URL path being /package.Service/Method, sending the body as its message with the protocol framing, content type and headers, and failing calls whose gRPC status, from trailers, or Connect error isn't OK.").Default("").Enum("", "grpc-web", "grpc-web-text", "connect")
+	rpcCodec    = app.Flag("rpc-codec", "Encoding of the rpc message: proto or json.").Default("proto").Enum("proto", "json")
+	rpcMessage  = app.Flag("rpc-message-file", "Send the message in this file instead of the body, ex: a binary protobuf message.").Default("").String()
+
+	verifyHash = app.Flag("verify-body-hash", "Fail requests whose response body does not have this hex encoded SHA-256.").Default("").String()
+	verifyFile = app.Flag("verify-body-file", "Fail requests whose response body differs from this file's content, compared as JSON, ignoring field order, when the file is JSON.").Default("").String()
+
+	scriptFile = app.Flag("script", "Run the request and response functions of this Lua script on every request and response, see the script package.").Default("").String()
 
 	timeout            = app.Flag("timeout", "Timeout for the hole request connect+write+read, ex: 10s, 1m, 1h, etc.").Short('t').Default("30s").Duration()
 	connectTimeout     = app.Flag("connect-timeout", "Connect timeout, ex: 10s, 1m, 1h, etc.").Default("5s").Duration()
@@ -54,10 +116,172 @@ var (
 	writeTimeout       = app.Flag("write-timeout", "Request write timeout, ex: 10s, 1m, 1h, etc.").Default("0s").Duration()
 	disableCompression = app.Flag("disable-compression", "Disable compression.").Default("false").Bool()
 	disableKeepAlives  = app.Flag("disable-keepalive", "Disable keep-alive.").Default("false").Bool()
+	affinityCookie     = app.Flag("affinity-cookie", "Keep a session per worker sticking to the backend set in this cookie, and report how often affinity was broken.").Default("").String()
+	affinityHeader     = app.Flag("affinity-header", "Track the backend serving each worker through this response header, and report how often affinity was broken.").Default("").String()
+	dnsFanout          = app.Flag("dns-fanout", "Spread requests evenly across every address the host resolves to, reporting each of them separately.").Default("false").Bool()
+	dnsRefresh         = app.Flag("dns-refresh", "Resolve the host again every this often in dns-fanout mode, ex: 10s, 1m. 0 resolves it once.").Default("0s").Duration()
+	happyEyeballs      = app.Flag("happy-eyeballs", "Race IPv6 and IPv4 connection attempts and report which family and address each connection used.").Default("false").Bool()
+	connLifetime       = app.Flag("conn-lifetime", "Close connections after they have been open this long, ex: 30s, 1m. 0 keeps them open.").Default("0s").Duration()
+	requestsPerConn    = app.Flag("requests-per-conn", "Close connections after they served about this amount of requests, counted per worker, so connections the server closes earlier serve fewer. Cannot be used with pipelining. 0 keeps them open.").Default("0").Uint()
+	backoffMin         = app.Flag("backoff", "Wait this long before connecting again to an address after a connect failure, doubling on consecutive failures, ex: 100ms. 0 disables it.").Default("0s").Duration()
+	backoffMax         = app.Flag("backoff-max", "Maximum wait between connect attempts when backing off.").Default("10s").Duration()
+	simulateLatency    = app.Flag("simulate-latency", "Delay every write to the target to emulate WAN conditions, ex: 50ms.").Default("0s").Duration()
+	simulateJitter     = app.Flag("simulate-jitter", "Random variation, plus or minus, of the simulated latency, ex: 10ms.").Default("0s").Duration()
+	proxyProtocol      = app.Flag("proxy-protocol", "Send a PROXY protocol header of this version (1 or 2) on every new connection. 0 disables it.").Default("0").Int()
+	proxySources       = app.Flag("proxy-source", "Source address announced in PROXY protocol headers, ip:port. Can be repeated to rotate them per connection.").Strings()
+	curves             = app.Flag("curves", "TLS curve preferences, comma separated, ex: X25519,P-256. Available: X25519, P-256, P-384, P-521, X25519MLKEM768.").Default("").String()
+	pipeline           = app.Flag("pipeline", "Enable HTTP/1.1 pipelining with up to N requests in flight per connection.").Default("0").Uint()
+	stream             = app.Flag("stream", "Consume responses as they arrive, latencies measure time to first byte and stream duration is reported separately.").Default("false").Bool()
+	sse                = app.Flag("sse", "Open Server-Sent Events streams instead of one-shot requests, concurrency is the amount of open streams.").Default("false").Bool()
+
+	mixFile = app.Flag("mix", "Workload spec file, each line has the form: weight [rate] METHOD path [body], rate caps the entry, ex: 100/s, or setup|teardown METHOD path [body] for requests sent once around the test. Paths are relative to the URL.").Default("").String()
+	mixIDs  = app.Flag("mix-ids", "Replace {id} in mix paths by a random id between 1 and this value.").Default("1000").Uint()
+	crud    = app.Flag("crud", "Run the mix as a CRUD workflow, {id} is replaced by ids of resources created by its POST requests.").Default("false").Bool()
+	crudID  = app.Flag("crud-id-field", "JSON field of POST responses holding the created id, falls back to the Location header.").Default("id").String()
+
+	targetsFormat = app.Flag("targets-format", "Format of the mix file: pla, or vegeta for its http targets format, METHOD url lines followed by headers and @body files. Targets must be on the host of the URL.").Default("pla").Enum("pla", "vegeta")
+	scenarioFile  = app.Flag("scenario", "Scenario file, every iteration sends its steps in order from the same worker, each line has the form: [budget] [onFailure=abort|continue|retry[:N]] [save=pool[:field]] METHOD path [body], budget is the latency the step should stay within, ex: 200ms, and onFailure whether a failed step ends the iteration or is sent again, up to N times. save adds the ids the step creates, from the JSON field, id by default, to a pool templates may pop. Steps may be named, name: METHOD path, for lines if status==404 then name [else name], or goto name, to pick which one follows, end ends the iteration. Lines scenario name share, ex: scenario browse 80%, start each of several mixed scenarios. Amount and rate count iterations.").Default("").String()
+
+	iterationPacing = app.Flag("iteration-pacing", "Make every worker start a scenario iteration at this fixed cadence, however long the previous one took, ex: 5s.").Default("0s").Duration()
+
+	vusPerSecond = app.Flag("vus-per-second", "Make virtual users, workers, join the test gradually at this rate, each of them then runs the scenario loop or sends requests, ex: 10.").Default("0").Float64()
+	maxVUs       = app.Flag("max-vus", "Maximum amount of virtual users joining the test, the same as concurrency.").Default("0").Uint()
+
+	iterations = app.Flag("iterations", "Repeat the test this amount of times and report mean, standard deviation and 95% confidence intervals of key metrics.").Default("1").Uint()
+	warmup     = app.Flag("warmup-iterations", "Discard this amount of initial iterations as warm-up.").Default("0").Uint()
+
+	otelPropagate = app.Flag("otel-propagate", "Start a trace with every request, sending a W3C traceparent header, and include its trace id in the results file and outlier dumps, to look slow requests up in Jaeger, Tempo or other tracing backends.").Default("false").Bool()
+	markSynthetic = app.Flag("mark-synthetic", "Tag every request with an X-Synthetic-Load: pla/<run id> header, so the target can tell load test traffic apart, ex: to leave it out of analytics.").Default("false").Bool()
+
+	cacheBust   = app.Flag("cache-bust", "Make every request miss caches, with a query parameter unique to it or a Cache-Control: no-cache header.").Default("off").Enum("off", "query", "header")
+	conditional = app.Flag("conditional", "Keep the ETag and Last-Modified of responses to GET and HEAD requests, and send them back as If-None-Match and If-Modified-Since when a worker requests the same URL again, reporting how many were 304 Not Modified and their latency against full responses.").Default("false").Bool()
+	cacheStatus = app.Flag("cache-status", "Report requests and latency of cache hits and misses apart, as told by the X-Cache, CF-Cache-Status, X-Cache-Status or X-Proxy-Cache response headers or a positive Age, ex: repeating the same URL to measure cache hits.").Default("false").Bool()
+
+	preflightShare   = app.Flag("preflight", "Send a CORS preflight, an OPTIONS request, before this percentage of the requests, as browsers do for cross-origin APIs, and report their latency apart, ex: 30.").Default("0").Float64()
+	preflightOrigin  = app.Flag("preflight-origin", "Origin header of requests and their preflights, ex: https://www.example.com.").Default("").String()
+	preflightHeaders = app.Flag("preflight-headers", "Headers preflights ask to be allowed in Access-Control-Request-Headers, ex: Content-Type,Authorization.").Default("").String()
+
+	encodings = app.Flag("encoding-matrix", "Run the test once with each of these Accept-Encoding values, one after the other, and compare their latency and transfer size, ex: identity,gzip,br.").Default("").String()
+
+	calibrateFlag = app.Flag("calibrate", "Measure the latency pla itself adds against an embedded no-op server first, with a fixed minimal configuration, and report it along with the results.").Default("false").Bool()
+	startAt       = app.Flag("start-at", "Start the load at this exact time, so independent pla processes can start together, ex: 2024-05-01T14:00:00Z.").Default("").String()
+	pluginPaths   = app.Flag("plugin", "Load a Go plugin adding a reporter, a request hook or a request factory, see the plugins package. Can be repeated.").Strings()
+	shard         = app.Flag("shard", "Run this share of the test, so several pla processes split the amount, qps, rate or trace evenly, ex: 2/8 for the second of eight. Combine with start-at and results-file to start together and merge results.").Default("").String()
+	pinCPUList    = app.Flag("pin-cpus", "Run pla only on these CPUs, ex: 0-7 or 0,2,4-6, with as many threads at once as CPUs, so it doesn't contend with the target or other processes on the same machine. Linux only.").Default("").String()
+	engine        = app.Flag("engine", "Network engine connections read and write through: the Go net poller, or io_uring, experimental and Linux 5.7 or later only, with a ring for reads and another for writes per connection. Every waiting read or write blocks a thread instead of waiting in the poller, so it needs up to two threads per connection and runs at most 4000 workers: it suits a few busy connections, not many connections.").Default("netpoll").Enum("netpoll", "uring")
+	prepareFlag   = app.Flag("prepare", "Resolve the host and open every connection, with its TLS handshake, before the test starts, so one-time costs don't skew it.").Default("false").Bool()
+	resultsPolicy = app.Flag("results-policy", "What to do with results the reporter cannot keep up with: block workers, drop them, spill them to disk or drop the oldest buffered ones.").Default("block").Enum("block", "drop", "spill", "drop-oldest")
+	resultsBuffer = app.Flag("results-buffer", "How many results can wait for the reporter before the results policy applies, 0 means one per worker, ex: 100000 to absorb bursts.").Default("0").Uint()
+
+	signHeader   = app.Flag("sign-header", "Sign every request with HMAC-SHA256 in this header.").Default("").String()
+	signSecret   = app.Flag("sign-secret", "Secret key of request signatures.").Default("").String()
+	signPayload  = app.Flag("sign-payload", "Template of the signed payload, {{method}}, {{host}}, {{path}}, {{query}}, {{uri}}, {{body}} and {{header \"Name\"}} are replaced by those of the request.").Default("{{method}}{{path}}{{body}}").String()
+	signEncoding = app.Flag("sign-encoding", "Encoding of request signatures.").Default("hex").Enum("hex", "base64")
+
+	seed = app.Flag("seed", "Seed the random picks of request mixes, template functions, header rotations and kv keys, so runs with the same seed and concurrency send the same values, though not in the same order. 0 picks a new seed every run.").Default("0").Int64()
+
+	outliers     = app.Flag("outliers", "Flag requests slower than the median plus N standard deviations, ex: 3sd, or than a multiple of the 99th percentile, ex: 2xp99.").Default("").String()
+	outliersDump = app.Flag("outliers-dump", "Write the details and response headers of every outlier to this file.").Default("").String()
+
+	targetMetrics  = app.Flag("target-metrics", "Scrape the CPU and memory used by the target from this Prometheus exporter URL while running, process metrics when found, else node exporter ones, and report them along with latency every target-interval.").Default("").String()
+	targetCommand  = app.Flag("target-command", "Run this command with sh while running to sample the target, ex: over ssh, it must print the CPU usage in percent and the memory used in bytes, and report them along with latency every target-interval.").Default("").String()
+	targetInterval = app.Flag("target-interval", "How often the target is sampled.").Default("5s").Duration()
+
+	dockerContainer = app.Flag("docker-container", "Send requests to the address of this Docker container, keeping the port and Host of the URL, and sample its CPU, memory and network from docker stats every target-interval.").Default("").String()
+
+	liveErrors = app.Flag("live-errors", "Print every distinct error while running, the first time it happens and then every N occurrences of it. 0 prints them only at the end.").Default("0").Int()
+
+	slo    = app.Flag("slo", "Show how many requests violate this latency objective, and how fast they burn its error budget, while running and at the end, ex: 200ms@99%. Failed requests violate it too.").Default("").String()
+	apdexT = app.Flag("apdex-t", "Report the Apdex score of latencies against this target, requests within it satisfy users, within 4 times it are tolerated and slower or failed ones frustrate them, ex: 100ms.").Default("0s").Duration()
+
+	exactPercentiles = app.Flag("exact-percentiles", "Keep every latency, spilling them to disk on long runs, and report exact percentiles instead of approximations, ex: to publish benchmark numbers.").Default("false").Bool()
+
+	maxMemory = app.Flag("max-memory", "Heap size in megabytes past which exact percentiles spill to disk in much smaller runs. It is no hard cap: nothing else shrinks, ex: results-buffer or per label and tenant reports, and without exact-percentiles it only reports the peak heap. 0 means no limit.").Default("0").Int64()
+
+	resultsFile     = app.Flag("results-file", "Write every result to this file, as a JSON line or a CSV row, for analysis elsewhere.").Default("").String()
+	resultsFormat   = app.Flag("results-format", "Format of the results file: ndjson, a JSON object per line, or csv, with a header row.").Default(interfaces.ExportJSON).Enum(interfaces.ExportJSON, interfaces.ExportCSV)
+	resultsCompress = app.Flag("results-compress", "Compress the results file: none, gzip or zstd, faster and smaller on multi-hour runs.").Default(interfaces.CompressNone).Enum(interfaces.CompressNone, interfaces.CompressGzip, interfaces.CompressZstd)
+	lossyReports    = app.Flag("lossy-reports", "Let the results file and plugin reporters drop the results they cannot keep up with, instead of slowing down the test. The summary still gets every result.").Default("false").Bool()
+
+	ntpServer = app.Flag("ntp-server", "Check the local clock against this NTP server before running, print how far off it is and record it in the metadata written along with the results file, ex: pool.ntp.org.").Default("").String()
+
+	captureFirst = app.Flag("capture-first", "Save the first N complete requests, as sent, and their responses to the capture file.").Default("0").Int()
+	captureFile  = app.Flag("capture-file", "File where the capture-first requests and responses are saved.").Default("pla-capture.txt").String()
+
+	failuresDir   = app.Flag("save-failures", "Save the status, headers and body of responses of failed requests to files in this directory.").Default("").String()
+	failuresMax   = app.Flag("save-failures-max", "Maximum amount of failed responses to save.").Default("100").Int()
+	failuresBytes = app.Flag("save-failures-mb", "Maximum size in megabytes of all saved failed responses.").Default("10").Int64()
+
+	runCmd = app.Command("run", "Run a load test against a URL.").Default()
+	url    = runCmd.Arg("url", "Request URL").Required().String()
+
+	compareCmd = app.Command("compare", "Run identical load against two URLs simultaneously and compare their latencies.")
+	compareA   = compareCmd.Arg("url-a", "First request URL").Required().String()
+	compareB   = compareCmd.Arg("url-b", "Second request URL").Required().String()
+
+	meshCmd    = app.Command("mesh", "Run identical load against a service directly and through its service mesh sidecar simultaneously, connections opened ahead on both, and report the latency the mesh adds.")
+	meshDirect = meshCmd.Arg("direct", "Request URL of the service itself").Required().String()
+	meshURL    = meshCmd.Arg("via-mesh", "Request URL of the service through the mesh, ex: its Envoy listener").Required().String()
+
+	shadowCmd       = app.Command("shadow", "Send every request to both a primary and a candidate URL and report where their responses diverge.")
+	shadowPrimary   = shadowCmd.Arg("primary", "Primary request URL").Required().String()
+	shadowCandidate = shadowCmd.Arg("candidate", "Candidate URL, requests keep the primary's path").Required().String()
+	shadowBodies    = shadowCmd.Flag("bodies", "Also compare SHA-256 hashes of response bodies.").Default("false").Bool()
+
+	openapiCmd       = app.Command("from-openapi", "Run a load test against an operation of an OpenAPI 3 document, building its requests from the document.")
+	openapiSpec      = openapiCmd.Arg("spec", "OpenAPI document, JSON or YAML").Required().String()
+	openapiOperation = openapiCmd.Flag("operation", "operationId of the operation to test.").Required().String()
+	openapiServer    = openapiCmd.Flag("server", "Base URL of the API, defaults to the first server of the document.").Default("").String()
+	openapiToken     = openapiCmd.Flag("token", "Credential for operations secured with bearer, OAuth or API key schemes.").Default("").String()
+	openapiFuzz      = openapiCmd.Flag("fuzz", "Send random parameter and body values within their schemas instead of the examples.").Default("false").Bool()
+
+	curveCmd  = app.Command("curve", "Hold increasing load levels for the length of the test each, and report the 99% latency against the throughput achieved by each of them.")
+	curveURL  = curveCmd.Arg("url", "Request URL").Required().String()
+	curveFrom = curveCmd.Flag("from", "Load of the first level, in requests per second.").Required().Float64()
+	curveTo   = curveCmd.Flag("to", "Load of the last level, in requests per second.").Required().Float64()
+	curveStep = curveCmd.Flag("step", "Increase of the load from one level to the next, in requests per second.").Required().Float64()
+	curveHold = curveCmd.Flag("hold", "How long every level is held, overrides length.").Default("30s").Duration()
+	curveCSV  = curveCmd.Flag("csv", "Save the curve to this file as CSV, ex: pla-curve.csv.").Default("").String()
+
+	kvCmd          = app.Command("kv", "Run a load test of GET and SET commands against a Redis or Memcached server, reported like requests: missing keys respond 404 and server errors 500.")
+	kvAddr         = kvCmd.Arg("addr", "Server address, host:port").Required().String()
+	kvProtocol     = kvCmd.Flag("protocol", "Protocol of the server: redis or memcached.").Default("redis").Enum("redis", "memcached")
+	kvGets         = kvCmd.Flag("gets", "Percentage of GET commands, the rest are SET.").Default("90").Uint()
+	kvKeys         = kvCmd.Flag("keys", "Size of the key space.").Default("10000").Int()
+	kvDistribution = kvCmd.Flag("key-distribution", "How keys are picked: uniform, or zipf so a few keys are hot.").Default("uniform").Enum("uniform", "zipf")
+	kvValueSize    = kvCmd.Flag("value-size", "Size of SET values in bytes, or a range they are picked uniformly from, ex: 100-1000.").Default("100").String()
+
+	dnsCmd      = app.Command("dns", "Run a load test of queries against a DNS resolver, reporting the responses of every rcode along with the latency.")
+	dnsServer   = dnsCmd.Flag("server", "Resolver address, host[:port].").Required().String()
+	dnsType     = dnsCmd.Flag("qtype", "Type of the queries.").Default("A").Enum("A", "AAAA", "CNAME", "MX", "NS", "PTR", "SOA", "SRV", "TXT", "ANY")
+	dnsTemplate = dnsCmd.Flag("name-template", "Name queried, which may use templates, ex: {{randword}}.example.com to miss caches.").Required().String()
+
+	smtpCmd     = app.Command("smtp", "Run a load test submitting messages to an SMTP server, reporting accept latency and the reply codes of the server as status codes: 250 accepted, 4xx deferred and 5xx rejected.")
+	smtpServer  = smtpCmd.Arg("server", "SMTP server address, host[:port]").Required().String()
+	smtpFrom    = smtpCmd.Flag("from", "Sender address, which may use templates.").Default("pla@example.com").String()
+	smtpTo      = smtpCmd.Flag("to", "Recipient address, which may use templates, ex: user{{counter}}@example.com.").Required().String()
+	smtpMsgSize = smtpCmd.Flag("message-size", "Size of messages in bytes.").Default("1024").Int()
+
+	selftestCmd = app.Command("selftest", "Run against an embedded echo server to find the maximum requests per second this machine can generate.")
 
-	url            = app.Arg("url", "Request URL").Required().String()
 	boomerInstance *boomer.Boomer
 	ui             Interface
+	tracePoints    []boomer.TracePoint
+	startTime      time.Time
+	loadedPlugins  []*plugins.Plugin
+	luaScript      *script.Script
+	requestMix     []*boomer.WeightedRequest
+	requestFilter  boomer.RequestFilter
+	doer           boomer.Doer
+	reporters      []Interface
+	cpus           []int
+	shardIndex     uint
+	shardTotal     uint
+	rotations      []*headerRotation
+	containerAddr  string
+
+	// runID tells the requests of this run apart from other runs'.
+	runID = strconv.FormatInt(time.Now().UnixNano(), 36)
 )
 
 func main() {
@@ -65,11 +289,110 @@ func main() {
 	if len(os.Args) < 2 {
 		usageAndExit("")
 	}
-	_, err := app.Parse(os.Args[1:])
+	cmd, err := app.Parse(os.Args[1:])
 	if err != nil {
 		usageAndExit(err.Error())
 	}
+	if cmd == selftestCmd.FullCommand() && *duration <= 0 && *n <= 0 {
+		*duration = selftestDuration
+	}
+	if cmd == meshCmd.FullCommand() {
+		*prepareFlag = true
+	}
+	if cmd == curveCmd.FullCommand() {
+		if *curveFrom <= 0 || *curveStep <= 0 || *curveTo < *curveFrom {
+			usageAndExit("curve needs positive from and step, and to not smaller than from")
+		}
+		if *q > 0 || *rate != "" || *rpsTrace != "" || *loadModel != "fixed" {
+			usageAndExit("curve sets the load of every level, it cannot be used with qps, rate, rps-trace or another load model")
+		}
+		*duration, *n = *curveHold, 0
+	}
+	if *shard != "" {
+		index, total, err := parseShard(*shard)
+		if err != nil {
+			usageAndExit(err.Error())
+		}
+		shardIndex, shardTotal = index, total
+		if *n > 0 && shardOf(*n) == 0 {
+			usageAndExit("shard gets no requests of the amount, use fewer shards")
+		}
+		*n = shardOf(*n)
+	}
+	if *rpsTrace != "" {
+		tracePoints = loadTrace(*rpsTrace)
+		for i := range tracePoints {
+			tracePoints[i].RPS /= float64(shards())
+		}
+		if *duration <= 0 && *n <= 0 {
+			*duration = tracePoints[len(tracePoints)-1].At
+		}
+	}
+	validateFlags()
+	if *dockerContainer != "" {
+		addr, err := monitor.ContainerAddr(*dockerContainer)
+		if err != nil {
+			usageAndExit(err.Error())
+		}
+		containerAddr = addr
+	}
+	rotations = headerRotations()
+	for _, path := range *pluginPaths {
+		p, err := plugins.Load(path)
+		if err != nil {
+			usageAndExit(err.Error())
+		}
+		loadedPlugins = append(loadedPlugins, p)
+	}
+	if len(cpus) > 0 {
+		if err := pinCPUs(cpus); err != nil {
+			usageAndExit(err.Error())
+		}
+	}
+	if *engine == "uring" {
+		if err := checkUring(); err != nil {
+			usageAndExit(err.Error())
+		}
+	}
+	defer func() {
+		if luaScript != nil {
+			luaScript.Close()
+		}
+	}()
+
+	switch cmd {
+	case compareCmd.FullCommand():
+		compare(*compareA, *compareB)
+	case meshCmd.FullCommand():
+		mesh(*meshDirect, *meshURL)
+	case shadowCmd.FullCommand():
+		shadow(*shadowPrimary, *shadowCandidate)
+	case openapiCmd.FullCommand():
+		fromOpenAPI(*openapiSpec, *openapiOperation)
+	case curveCmd.FullCommand():
+		curve(*curveURL, curveLevels(*curveFrom, *curveTo, *curveStep))
+	case kvCmd.FullCommand():
+		kvTest(*kvAddr)
+	case dnsCmd.FullCommand():
+		dnsTest(*dnsServer, *dnsTemplate)
+	case smtpCmd.FullCommand():
+		smtpTest(*smtpServer, *smtpFrom, *smtpTo, *smtpMsgSize)
+	case selftestCmd.FullCommand():
+		selftest()
+	default:
+		if *iterations > 1 {
+			iterate(*url, *iterations, *warmup)
+			return
+		}
+		if *encodings != "" {
+			encodingMatrix(*url, strings.Split(*encodings, ","))
+			return
+		}
+		run(*url)
+	}
+}
 
+func validateFlags() {
 	if *duration <= 0 && *n <= 0 {
 		usageAndExit("length or amount must be specified")
 	}
@@ -82,6 +405,344 @@ func main() {
 		usageAndExit("concurrency cannot be greater than amount")
 	}
 
+	if *pipeline > 0 && *disableKeepAlives {
+		usageAndExit("pipelining cannot be used with keep-alive disabled")
+	}
+
+	if *engine == "uring" && *happyEyeballs {
+		usageAndExit("happy-eyeballs cannot be used with the uring engine")
+	}
+
+	if *engine == "uring" && (*c > maxUringWorkers || *maxVUs > maxUringWorkers) {
+		usageAndExit(fmt.Sprintf("concurrency and max-vus cannot be above %d with the uring engine", maxUringWorkers))
+	}
+
+	if *requestsPerConn > 0 && *pipeline > 0 {
+		usageAndExit("requests-per-conn cannot be used with pipelining")
+	}
+
+	if (*sse || *stream) && *pipeline > 0 {
+		usageAndExit("sse and stream cannot be used with pipelining")
+	}
+
+	if *scenarioFile != "" && *mixFile != "" {
+		usageAndExit("scenario and mix cannot be used together")
+	}
+
+	if *targetsFormat == "vegeta" && *crud {
+		usageAndExit("crud cannot be used with vegeta targets")
+	}
+
+	if *signHeader != "" && *signSecret == "" {
+		usageAndExit("sign-header needs a sign-secret")
+	}
+
+	if *rpcProtocol != "" && (*soapEnvelope || *soapAction != "") {
+		usageAndExit("rpc cannot be used with soap-envelope or soap-action")
+	}
+
+	if *rpcMessage != "" && *rpcProtocol == "" {
+		usageAndExit("rpc-message-file needs an rpc protocol")
+	}
+
+	if *preflightShare < 0 || *preflightShare > 100 {
+		usageAndExit("preflight must be a percentage between 0 and 100")
+	}
+
+	if *preflightShare > 0 && *preflightOrigin == "" {
+		usageAndExit("preflight needs a preflight-origin")
+	}
+
+	if *affinityCookie != "" && *affinityHeader != "" {
+		usageAndExit("affinity-cookie and affinity-header cannot be used together")
+	}
+
+	if *dnsFanout && (*sse || *stream) {
+		usageAndExit("dns-fanout cannot be used with sse or stream")
+	}
+
+	if *sse && *stream {
+		usageAndExit("sse and stream cannot be used together")
+	}
+
+	if *proxyProtocol < 0 || *proxyProtocol > 2 {
+		usageAndExit("proxy-protocol version must be 1 or 2")
+	}
+
+	if *q < 0 {
+		usageAndExit("qps cannot be smaller than 0")
+	}
+
+	if *encodings != "" && *iterations > 1 {
+		usageAndExit("encoding-matrix cannot be used with iterations")
+	}
+	for _, e := range strings.Split(*encodings, ",") {
+		if *encodings != "" && strings.TrimSpace(e) == "" {
+			usageAndExit("encoding-matrix values cannot be empty")
+		}
+	}
+
+	var samplers int
+	for _, s := range []string{*targetMetrics, *targetCommand, *dockerContainer} {
+		if s != "" {
+			samplers++
+		}
+	}
+	if samplers > 1 {
+		usageAndExit("target-metrics, target-command and docker-container cannot be used together")
+	}
+	if *targetInterval <= 0 {
+		usageAndExit("target-interval must be positive")
+	}
+
+	if *q > 0 && *rate != "" {
+		usageAndExit("qps and rate cannot be used together")
+	}
+
+	if *rpsTrace != "" && (*q > 0 || *rate != "" || *loadModel != "fixed") {
+		usageAndExit("rps-trace cannot be used with qps, rate or another load model")
+	}
+
+	if *loadModel == "aimd" {
+		if *q == 0 && *rate == "" {
+			usageAndExit("aimd load model needs an initial qps or rate")
+		}
+		if *aimdStep <= 0 {
+			usageAndExit("aimd-step must be positive")
+		}
+		if *aimdFactor <= 0 || *aimdFactor >= 1 {
+			usageAndExit("aimd-factor must be between 0 and 1")
+		}
+		if *retryAfter {
+			usageAndExit("aimd load model and retry-after cannot be used together")
+		}
+	}
+
+	// Curves set the rate of every level themselves.
+	if *retryAfter && *q == 0 && *rate == "" && *rpsTrace == "" && *curveURL == "" {
+		usageAndExit("retry-after needs a qps, rate or rps-trace to back off from")
+	}
+
+	if *startAt != "" {
+		t, err := time.Parse(time.RFC3339Nano, *startAt)
+		if err != nil {
+			usageAndExit("start-at must be an RFC 3339 time, ex: 2024-05-01T14:00:00Z")
+		}
+		if t.Before(time.Now()) {
+			usageAndExit("start-at cannot be in the past")
+		}
+		startTime = t
+	}
+
+	if *pinCPUList != "" {
+		list, err := parseCPUs(*pinCPUList)
+		if err != nil {
+			usageAndExit(err.Error())
+		}
+		cpus = list
+	}
+
+	if *rateBurst > 0 && *pacing == "burst" {
+		usageAndExit("rate-burst only applies to uniform pacing")
+	}
+
+	if *vusPerSecond < 0 {
+		usageAndExit("vus-per-second cannot be negative")
+	}
+
+	if *maxVUs > 0 && *c > 0 {
+		usageAndExit("max-vus and concurrency cannot be used together")
+	}
+
+	if *iterationPacing > 0 && (*scenarioFile == "" || *qpsPerWorker) {
+		usageAndExit("iteration-pacing needs a scenario, and cannot be used with qps-per-worker")
+	}
+
+	if *qpsPerWorker {
+		if *q == 0 && *rate == "" {
+			usageAndExit("qps-per-worker needs a qps or rate")
+		}
+		if *pacing == "burst" || *rateBurst > 0 || *loadModel != "fixed" || *retryAfter {
+			usageAndExit("qps-per-worker cannot be used with burst pacing, rate-burst, another load model or retry-after")
+		}
+	}
+
+	if *iterations < 1 {
+		usageAndExit("iterations must be at least 1")
+	}
+
+	if *apdexT < 0 {
+		usageAndExit("apdex-t cannot be negative")
+	}
+
+	if *maxMemory < 0 {
+		usageAndExit("max-memory cannot be negative")
+	}
+
+	if *warmup >= *iterations {
+		usageAndExit("warmup-iterations must be smaller than iterations")
+	}
+}
+
+func run(rawURL string) {
+	var overhead time.Duration
+	if *calibrateFlag {
+		overhead = calibrate()
+	}
+	basic := interfaces.NewBasicInterface().WithOverhead(overhead)
+	boomerInstance = newBoomer(newRequest(rawURL))
+	if *outliers != "" {
+		o, err := parseOutliers(*outliers)
+		if err != nil {
+			usageAndExit(err.Error())
+		}
+		if *outliersDump != "" {
+			file, err := os.Create(*outliersDump)
+			if err != nil {
+				usageAndExit(err.Error())
+			}
+			defer file.Close()
+			o.Dump = file
+			boomerInstance.WithResponseHeaders(true)
+		}
+		basic.WithOutliers(o)
+	}
+	if *targetMetrics != "" {
+		basic.WithTargetResources(monitor.Prometheus(*targetMetrics), *targetInterval)
+	} else if *targetCommand != "" {
+		basic.WithTargetResources(monitor.Command(*targetCommand), *targetInterval)
+	} else if *dockerContainer != "" {
+		basic.WithTargetResources(monitor.Docker(*dockerContainer), *targetInterval)
+	}
+	if *liveErrors > 0 {
+		basic.WithErrorLog(&interfaces.ErrorLog{Every: *liveErrors, Out: os.Stderr})
+	}
+	if *slo != "" {
+		s, err := parseSLO(*slo)
+		if err != nil {
+			usageAndExit(err.Error())
+		}
+		basic.WithSLO(s)
+	}
+	if *apdexT > 0 {
+		basic.WithApdex(*apdexT)
+	}
+	basic.WithExactPercentiles(*exactPercentiles).WithMaxMemory(*maxMemory)
+	uis := &fanout{}
+	uis.add(basic, false)
+	if *resultsFile != "" {
+		file, err := os.Create(*resultsFile)
+		if err != nil {
+			usageAndExit(err.Error())
+		}
+		defer file.Close()
+		uis.add(interfaces.NewExport(file, *resultsFormat, *resultsCompress), *lossyReports)
+	}
+	for _, p := range loadedPlugins {
+		if p.Reporter != nil {
+			uis.add(p.Reporter, *lossyReports)
+		}
+	}
+	for _, r := range reporters {
+		uis.add(r, false)
+	}
+	if *captureFirst > 0 {
+		file, err := os.Create(*captureFile)
+		if err != nil {
+			usageAndExit(err.Error())
+		}
+		defer file.Close()
+		boomerInstance.WithCapture(file, *captureFirst)
+	}
+	ui = uis
+	meta := &runMetadata{RunID: runID}
+	meta.Host, _ = os.Hostname()
+	if *ntpServer != "" {
+		meta.checkClock(*ntpServer)
+	}
+	prepare(boomerInstance)
+	waitForStart()
+
+	interrupted := make(chan struct{})
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	go func() {
+		<-c
+		close(interrupted)
+		// The test winds down as usual, running its teardown, unless
+		// interrupted again.
+		boomerInstance.Stop()
+		<-c
+		os.Exit(1)
+	}()
+
+	meta.Start = time.Now()
+	ui.Start(boomerInstance)
+	boomerInstance.Run()
+	go processResults()
+	boomerInstance.Wait()
+	time.Sleep(1 * time.Millisecond)
+	ui.End()
+	writeMetadata(meta)
+	if err := boomerInstance.SetupErr(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	select {
+	case <-interrupted:
+		os.Exit(1)
+	default:
+	}
+}
+
+// writeMetadata writes the metadata of the run next to the results file,
+// if any, as its name followed by .meta.json.
+func writeMetadata(meta *runMetadata) {
+	if *resultsFile == "" {
+		return
+	}
+	meta.End = time.Now()
+	if err := meta.write(*resultsFile + ".meta.json"); err != nil {
+		fmt.Fprintf(os.Stderr, "Could not write the run metadata: %v\n", err)
+	}
+}
+
+// prepare opens the connections of b ahead of the test when asked to.
+func prepare(b *boomer.Boomer) {
+	if !*prepareFlag {
+		return
+	}
+	start := time.Now()
+	opened, err := b.Prepare()
+	fmt.Printf("Prepared %d connections in %4.4f secs.\n", opened, time.Since(start).Seconds())
+	if err != nil {
+		fmt.Printf("  Some could not be opened: %v\n", err)
+	}
+}
+
+// waitForStart blocks until the start-at time, if any, only the first time
+// it is called.
+func waitForStart() {
+	if startTime.IsZero() {
+		return
+	}
+	fmt.Printf("Waiting to start at %v...\n", startTime.Format(time.RFC3339Nano))
+	// Sleep most of the wait, then spin on the rest for precision.
+	for {
+		d := startTime.Sub(time.Now())
+		if d <= 0 {
+			break
+		}
+		if d > time.Millisecond {
+			time.Sleep(d - time.Millisecond)
+		}
+	}
+	startTime = time.Time{}
+}
+
+// newRequest builds the request described by the flags for the given url,
+// returning it along with the address to connect to.
+func newRequest(rawURL string) (string, *fasthttp.Request) {
 	var (
 		method string
 		// Username and password for basic auth
@@ -100,20 +761,45 @@ func main() {
 	}
 
 	req := fasthttp.AcquireRequest()
-	req.URI().Update(*url)
+	req.URI().Update(rawURL)
 	if len(req.URI().Host()) == 0 {
-		req.URI().Update("http://" + *url)
+		req.URI().Update("http://" + rawURL)
 		if len(req.URI().Host()) == 0 {
 			usageAndExit("invalid url ''" + req.URI().String() + "'', unable to detect host")
 		}
 	}
 	addr := string(req.URI().Host())
 	if !strings.Contains(addr, ":") {
-		addr = addr + ":80"
+		if string(req.URI().Scheme()) == "https" {
+			addr = addr + ":443"
+		} else {
+			addr = addr + ":80"
+		}
+	}
+	if containerAddr != "" {
+		_, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			usageAndExit(err.Error())
+		}
+		addr = net.JoinHostPort(containerAddr, port)
 	}
 	req.Header.SetMethod(method)
-	req.SetBodyString(*body)
+	if *soapEnvelope {
+		req.SetBodyString(soap.Envelope(*body))
+	} else {
+		req.SetBodyString(*body)
+	}
 	req.Header.SetContentLength(len(req.Body()))
+	if *rpcProtocol != "" {
+		msg := []byte(*body)
+		if *rpcMessage != "" {
+			var err error
+			if msg, err = ioutil.ReadFile(*rpcMessage); err != nil {
+				usageAndExit(err.Error())
+			}
+		}
+		rpc.SetRequest(req, *rpcProtocol, *rpcCodec, msg)
+	}
 	if username != "" || password != "" {
 		req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(username+":"+password)))
 	}
@@ -124,6 +810,10 @@ func main() {
 		if err != nil {
 			usageAndExit(err.Error())
 		}
+		if strings.HasPrefix(match[2], "@") {
+			// Rotated by the hooks of headerRotations.
+			continue
+		}
 		if match[1] == "Host" || match[1] == "host" {
 			req.SetHost(match[2])
 		} else {
@@ -131,6 +821,10 @@ func main() {
 		}
 	}
 
+	if *soapAction != "" {
+		soap.SetAction(req, *soapAction)
+	}
+
 	if !*disableCompression {
 		req.Header.Set("Accept-Encoding", "gzip,deflate")
 	}
@@ -138,31 +832,280 @@ func main() {
 	if *disableKeepAlives {
 		req.SetConnectionClose()
 	}
+	return addr, req
+}
 
-	ui = interfaces.NewBasicInterface()
-	boomerInstance = boomer.NewBoomer(addr, req).
+// newBoomer builds a boomer sending req to addr configured by the flags.
+func newBoomer(addr string, req *fasthttp.Request) *boomer.Boomer {
+	pace, err := boomer.ParsePacing(*pacing)
+	if err != nil {
+		usageAndExit(err.Error())
+	}
+	policy, err := boomer.ParseResultsPolicy(*resultsPolicy)
+	if err != nil {
+		usageAndExit(err.Error())
+	}
+	prof, err := boomer.ParseProfile(*profile)
+	if err != nil {
+		usageAndExit(err.Error())
+	}
+	conc := *c
+	if *maxVUs > 0 {
+		conc = *maxVUs
+	}
+	if conc == 0 && prof != boomer.ProfileDefault {
+		conc = prof.Concurrency()
+		if *n > 0 && conc > *n {
+			conc = *n
+		}
+	}
+	limit, per := rateLimit(*q, time.Second)
+	if *rate != "" {
+		limit, per, err = parseRate(*rate)
+		if err != nil {
+			usageAndExit(err.Error())
+		}
+	}
+	// Every shard sends the same requests over a longer window.
+	per *= time.Duration(shards())
+	b := boomer.NewBoomer(addr, req).
 		WithAmount(*n).
-		WithConcurrency(*c).
+		WithConcurrency(conc).
+		WithClientOptions(prof.ClientOptions()).
 		WithDuration(*duration).
 		WithTimeout(*timeout).
-		WithRateLimit(*q, time.Second).
-		WithAbortionOnFailure(*f)
+		WithRateLimit(limit, per).
+		WithPacing(pace).
+		WithRateBurst(*rateBurst).
+		WithRatePerWorker(*qpsPerWorker).
+		WithIterationPacing(*iterationPacing).
+		WithVUArrival(*vusPerSecond).
+		WithResultsPolicy(policy).
+		WithResultsBuffer(*resultsBuffer).
+		WithAbortionOnFailure(*f).
+		WithPipelining(*pipeline).
+		WithSSE(*sse).
+		WithStreaming(*stream).
+		WithAffinityCookie(*affinityCookie).
+		WithAffinityHeader(*affinityHeader).
+		WithDNSFanout(*dnsFanout, *dnsRefresh).
+		WithHappyEyeballs(*happyEyeballs).
+		WithConnectionChurn(*connLifetime, *requestsPerConn).
+		WithConnectBackoff(*backoffMin, *backoffMax).
+		WithSimulatedLatency(*simulateLatency, *simulateJitter).
+		WithRetries(*retries).
+		WithIdempotencyKey(*idemKey).
+		WithQuotaBackoff(*retryAfter)
 
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
-	go func() {
-		<-c
-		boomerInstance.Stop()
-		ui.End()
-		os.Exit(1)
-	}()
+	if *loadModel == "aimd" {
+		b.WithAIMD(*aimdStep, *aimdFactor, *aimdLatency)
+	}
+	if tracePoints != nil {
+		b.WithRPSTrace(tracePoints)
+	}
+	if *failuresDir != "" {
+		b.WithFailureSamples(*failuresDir, *failuresMax, *failuresBytes<<20)
+	}
 
-	ui.Start(boomerInstance)
-	boomerInstance.Run()
-	go processResults()
-	boomerInstance.Wait()
-	time.Sleep(1 * time.Millisecond)
-	ui.End()
+	// The requests sent, to tell whether they have templates to render.
+	reqs := []*fasthttp.Request{req}
+	// Bodies read lazily, vuHeaders and rotations may bring templates too.
+	templated := len(rotations) > 0 || len(*vuHeaders) > 0
+	if *mixFile != "" {
+		file, err := os.Open(*mixFile)
+		if err != nil {
+			usageAndExit(err.Error())
+		}
+		var spec []workload.Entry
+		var targets []workload.Target
+		if *targetsFormat == "vegeta" {
+			targets, err = workload.ParseVegeta(file)
+		} else {
+			spec, err = workload.ParseSpec(file)
+		}
+		file.Close()
+		if err != nil {
+			usageAndExit(err.Error())
+		}
+		b.WithSetup(workload.Stage(spec, req, workload.StageSetup)...).
+			WithTeardown(workload.Stage(spec, req, workload.StageTeardown)...)
+		var mix []*boomer.WeightedRequest
+		if targets != nil {
+			mix, err = workload.Targets(targets, req)
+		} else if *crud {
+			mix, err = workload.Workflow(spec, req, &workload.Pool{}, *crudID)
+		} else {
+			mix, err = workload.Mix(spec, req, *mixIDs)
+		}
+		if err != nil {
+			usageAndExit(err.Error())
+		}
+		for _, w := range mix {
+			reqs = append(reqs, w.Request)
+		}
+		for _, t := range targets {
+			templated = templated || t.BodyFile != ""
+		}
+		b.WithRequestMix(mix)
+	}
+	// Shared by scenario steps and templates.
+	store := &workload.Store{}
+	if *scenarioFile != "" {
+		file, err := os.Open(*scenarioFile)
+		if err != nil {
+			usageAndExit(err.Error())
+		}
+		spec, err := workload.ParseScenario(file)
+		file.Close()
+		if err != nil {
+			usageAndExit(err.Error())
+		}
+		scenarios, err := workload.Scenarios(spec, req, *mixIDs, store)
+		if err != nil {
+			usageAndExit(err.Error())
+		}
+		for _, s := range scenarios {
+			for _, w := range s.Steps {
+				reqs = append(reqs, w.Request)
+			}
+		}
+		b.WithScenarios(scenarios).
+			WithSetup(workload.Stage(spec, req, workload.StageSetup)...).
+			WithTeardown(workload.Stage(spec, req, workload.StageTeardown)...)
+	}
+	if requestMix != nil {
+		for _, w := range requestMix {
+			reqs = append(reqs, w.Request)
+		}
+		b.WithRequestMix(requestMix)
+	}
+	if doer != nil {
+		b.WithDoer(doer)
+	}
+	b.WithSeed(*seed)
+	if *tenantHeader != "" {
+		b.WithTenantHeader(*tenantHeader)
+	}
+
+	var sources []*net.TCPAddr
+	for _, source := range *proxySources {
+		addr, err := net.ResolveTCPAddr("tcp", source)
+		if err != nil {
+			usageAndExit(err.Error())
+		}
+		sources = append(sources, addr)
+	}
+	b.WithProxyProtocol(*proxyProtocol, sources)
+	if *engine == "uring" {
+		b.WithDialer(dialUring(*connectTimeout))
+	}
+
+	if *breaker != "" {
+		threshold, window, err := parseBreaker(*breaker)
+		if err != nil {
+			usageAndExit(err.Error())
+		}
+		b.WithCircuitBreaker(threshold, window)
+	}
+
+	if *curves != "" {
+		ids, err := boomer.ParseCurves(*curves)
+		if err != nil {
+			usageAndExit(err.Error())
+		}
+		b.WithCurvePreferences(ids)
+	}
+
+	for _, h := range *vuHeaders {
+		match, err := parseInputWithRegexp(h, vuHeaderRegexp)
+		if err != nil {
+			usageAndExit(err.Error())
+		}
+		b.WithRequestHook(vuHeaderHook(match[1], match[2]))
+	}
+	for _, r := range rotations {
+		b.WithRequestHook(r.hook)
+	}
+	if requestFilter != nil {
+		b.WithRequestFilter(requestFilter)
+	}
+	tmpl := templates.New(*seed).WithStore(store)
+	if templated || templates.Contains(reqs...) {
+		b.WithRequestFilter(tmpl.Filter())
+	}
+
+	for _, p := range loadedPlugins {
+		if p.Hook != nil {
+			b.WithRequestHook(p.Hook)
+		}
+		if p.Factory != nil {
+			b.WithRequestFactory(p.Factory)
+		}
+	}
+
+	if *scriptFile != "" {
+		// Loaded once, the states are shared by every test of the run.
+		if luaScript == nil {
+			s, err := script.Load(*scriptFile, int(b.C))
+			if err != nil {
+				usageAndExit(err.Error())
+			}
+			luaScript = s
+		}
+		if f := luaScript.Filter(); f != nil {
+			b.WithRequestFilter(f)
+		}
+		if a := luaScript.Assertion(); a != nil {
+			b.WithAssertion(a)
+		}
+	}
+
+	if *markSynthetic {
+		b.WithRequestHook(markSyntheticHook)
+	}
+	if *cacheBust != "off" {
+		b.WithRequestHook(cacheBustHook(*cacheBust))
+	}
+	b.WithCacheStatus(*cacheStatus).WithConditionalRequests(*conditional)
+	b.WithPreflight(*preflightShare/100, *preflightOrigin, *preflightHeaders)
+	b.WithTracePropagation(*otelPropagate)
+
+	// Signed last, so the signature covers every change of other hooks.
+	if *signHeader != "" {
+		sign, err := tmpl.SignFilter(*signHeader, *signSecret, *signPayload, *signEncoding)
+		if err != nil {
+			usageAndExit(err.Error())
+		}
+		b.WithRequestFilter(sign)
+	}
+
+	if *rpcProtocol != "" {
+		b.WithAssertion(rpc.Assertion(*rpcProtocol))
+	}
+
+	for _, x := range *xpaths {
+		path, err := soap.ParsePath(x)
+		if err != nil {
+			usageAndExit(err.Error())
+		}
+		b.WithAssertion(path.Assertion())
+	}
+
+	if *verifyHash != "" {
+		m, err := verify.Hash(*verifyHash)
+		if err != nil {
+			usageAndExit(err.Error())
+		}
+		b.WithAssertion(m.Assertion("response body hash mismatch"))
+	}
+	if *verifyFile != "" {
+		expected, err := ioutil.ReadFile(*verifyFile)
+		if err != nil {
+			usageAndExit(err.Error())
+		}
+		b.WithAssertion(verify.Content(expected).Assertion("response body does not match " + *verifyFile))
+	}
+	return b
 }
 
 func usageAndExit(msg string) {
@@ -184,6 +1127,251 @@ func parseInputWithRegexp(input, regx string) ([]string, error) {
 	return matches, nil
 }
 
+// loadTrace reads the rate trace at path.
+func loadTrace(path string) []boomer.TracePoint {
+	file, err := os.Open(path)
+	if err != nil {
+		usageAndExit(err.Error())
+	}
+	defer file.Close()
+	points, err := workload.ParseTrace(file)
+	if err != nil {
+		usageAndExit(err.Error())
+	}
+	return points
+}
+
+// parseBreaker parses a threshold/window pair like 50%/10s.
+func parseBreaker(input string) (float64, time.Duration, error) {
+	match, err := parseInputWithRegexp(input, breakerRegexp)
+	if err != nil {
+		return 0, 0, err
+	}
+	pct, err := strconv.ParseFloat(match[1], 64)
+	if err != nil || pct <= 0 || pct > 100 {
+		return 0, 0, fmt.Errorf("breaker threshold must be between 0%% and 100%%; input = %v", input)
+	}
+	window, err := time.ParseDuration(match[2])
+	if err != nil || window < boomer.MinBreakerWindow {
+		return 0, 0, fmt.Errorf("breaker window must be at least %v; input = %v", boomer.MinBreakerWindow, input)
+	}
+	return pct / 100, window, nil
+}
+
+// parseRate parses a rate like 300/m as a whole amount of requests per
+// window.
+func parseRate(input string) (uint, time.Duration, error) {
+	count, per, err := boomer.ParseRate(input)
+	if err != nil {
+		return 0, 0, err
+	}
+	limit, per := rateLimit(count, per)
+	return limit, per, nil
+}
+
+// rateLimit expresses count requests per period as a whole amount of
+// requests per window, fractional counts become one request every so often.
+func rateLimit(count float64, per time.Duration) (uint, time.Duration) {
+	if count <= 0 {
+		return 0, per
+	}
+	if count == math.Trunc(count) {
+		return uint(count), per
+	}
+	return 1, time.Duration(float64(per) / count)
+}
+
+// parseOutliers parses an outlier threshold like 3sd or 2xp99.
+func parseOutliers(input string) (*interfaces.Outliers, error) {
+	match, err := parseInputWithRegexp(input, outliersRegexp)
+	if err != nil {
+		return nil, err
+	}
+	v, err := strconv.ParseFloat(match[1], 64)
+	if err != nil || v <= 0 {
+		return nil, fmt.Errorf("outliers threshold must be positive; input = %v", input)
+	}
+	if match[2] == "xp99" {
+		return &interfaces.Outliers{P99: v}, nil
+	}
+	return &interfaces.Outliers{StdDevs: v}, nil
+}
+
+// parseShard parses a shard like 2/8, the second of eight.
+func parseShard(input string) (uint, uint, error) {
+	parts := strings.SplitN(input, "/", 2)
+	if len(parts) == 2 {
+		index, err := strconv.ParseUint(parts[0], 10, 32)
+		total, err2 := strconv.ParseUint(parts[1], 10, 32)
+		if err == nil && err2 == nil && index >= 1 && index <= total {
+			return uint(index), uint(total), nil
+		}
+	}
+	return 0, 0, fmt.Errorf("shard must be like 2/8, from 1 to the amount of shards; input = %v", input)
+}
+
+// shards returns the amount of processes the test is split in.
+func shards() uint {
+	if shardTotal == 0 {
+		return 1
+	}
+	return shardTotal
+}
+
+// shardOf returns the share of amount this shard runs, the remainder goes
+// to the first shards.
+func shardOf(amount uint) uint {
+	if shardTotal == 0 {
+		return amount
+	}
+	share := amount / shardTotal
+	if shardIndex <= amount%shardTotal {
+		share++
+	}
+	return share
+}
+
+// parseCPUs parses a list of CPUs and ranges of them like 0-3,8.
+func parseCPUs(input string) ([]int, error) {
+	var list []int
+	for _, part := range strings.Split(input, ",") {
+		bounds := strings.SplitN(part, "-", 2)
+		from, err := strconv.Atoi(bounds[0])
+		to := from
+		if err == nil && len(bounds) == 2 {
+			to, err = strconv.Atoi(bounds[1])
+		}
+		if err != nil || from < 0 || to < from || to >= maxCPUs {
+			return nil, fmt.Errorf("could not parse the provided cpus; input = %v", input)
+		}
+		for cpu := from; cpu <= to; cpu++ {
+			list = append(list, cpu)
+		}
+	}
+	return list, nil
+}
+
+// parseSLO parses a latency objective like 200ms@99.9%.
+func parseSLO(input string) (*interfaces.SLO, error) {
+	match, err := parseInputWithRegexp(input, sloRegexp)
+	if err != nil {
+		return nil, err
+	}
+	latency, err := time.ParseDuration(match[1])
+	if err != nil || latency <= 0 {
+		return nil, fmt.Errorf("slo latency must be a positive duration; input = %v", input)
+	}
+	target, err := strconv.ParseFloat(match[2], 64)
+	if err != nil || target <= 0 || target >= 100 {
+		return nil, fmt.Errorf("slo target must be between 0 and 100%%; input = %v", input)
+	}
+	return &interfaces.SLO{Latency: latency, Target: target / 100}, nil
+}
+
+func vuHeaderHook(name, value string) boomer.RequestHook {
+	return func(vu int, req *fasthttp.Request) {
+		req.Header.Set(name, strings.Replace(value, vuPlaceholder, strconv.Itoa(vu), -1))
+	}
+}
+
+// markSyntheticHook tags req as load test traffic of this run.
+func markSyntheticHook(vu int, req *fasthttp.Request) {
+	req.Header.Set(syntheticHeader, "pla/"+runID)
+}
+
+// cacheBustHook makes every request miss caches, with the strategy query
+// adding a parameter unique to the request and run, or header asking for
+// no-cache.
+func cacheBustHook(strategy string) boomer.RequestHook {
+	var n uint64
+	return func(vu int, req *fasthttp.Request) {
+		if strategy == "header" {
+			req.Header.Set("Cache-Control", "no-cache")
+			req.Header.Set("Pragma", "no-cache")
+			return
+		}
+		uri := string(req.Header.RequestURI())
+		sep := "?"
+		if strings.Contains(uri, "?") {
+			sep = "&"
+		}
+		req.SetRequestURI(uri + sep + "_pla=" + runID + "-" + strconv.FormatUint(atomic.AddUint64(&n, 1), 36))
+	}
+}
+
+// headerRotation sets a header to one of values on every request, in
+// order or picked with rand when random.
+type headerRotation struct {
+	name   string
+	values []string
+	next   uint64
+
+	// lock guards rand, shared by workers.
+	lock sync.Mutex
+	rand *rand.Rand
+}
+
+func (r *headerRotation) value() string {
+	if r.rand != nil {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+		return r.values[r.rand.Intn(len(r.values))]
+	}
+	return r.values[(atomic.AddUint64(&r.next, 1)-1)%uint64(len(r.values))]
+}
+
+func (r *headerRotation) hook(vu int, req *fasthttp.Request) {
+	req.Header.Set(r.name, r.value())
+}
+
+// headerRotations returns the headers whose value rotates through the lines
+// of a file, given as Name: @path. Random picks are seeded from seed, a
+// different one for each header.
+func headerRotations() []*headerRotation {
+	seed := *seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	var rotations []*headerRotation
+	for _, h := range *headerList {
+		match, err := parseInputWithRegexp(h, headerRegexp)
+		if err != nil {
+			usageAndExit(err.Error())
+		}
+		if !strings.HasPrefix(match[2], "@") {
+			continue
+		}
+		values, err := readLines(match[2][1:])
+		if err != nil {
+			usageAndExit(err.Error())
+		}
+		r := &headerRotation{name: match[1], values: values}
+		if *rotationMode == "random" {
+			r.rand = rand.New(rand.NewSource(seed + int64(len(rotations))))
+		}
+		rotations = append(rotations, r)
+	}
+	return rotations
+}
+
+// readLines returns the non blank lines of the file at path.
+func readLines(path string) ([]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("%v has no values", path)
+	}
+	return lines, nil
+}
+
 func processResults() {
 	for res := range boomerInstance.Results() {
 		ui.ProcessResult(res)
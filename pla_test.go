@@ -15,7 +15,15 @@
 package main
 
 import (
+	"bytes"
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
 )
 
 func TestParseValidHeaderFlag(t *testing.T) {
@@ -58,3 +66,232 @@ func TestParseAuthMetaCharacters(t *testing.T) {
 		t.Errorf("Could not parse an auth header with a plus sign in the user name")
 	}
 }
+
+func TestParseVUHeaderFlag(t *testing.T) {
+	match, err := parseInputWithRegexp("X-User-Id=user-{{vu}}", vuHeaderRegexp)
+	if err != nil {
+		t.Errorf("A valid vu header was not parsed correctly: %v", err.Error())
+	}
+	if match[1] != "X-User-Id" || match[2] != "user-{{vu}}" {
+		t.Errorf("A valid vu header was not parsed correctly, parsed values: %v %v", match[1], match[2])
+	}
+}
+
+func TestVUHeaderHook(t *testing.T) {
+	req := fasthttp.AcquireRequest()
+	vuHeaderHook("X-User-Id", "user-{{vu}}")(7, req)
+	if v := string(req.Header.Peek("X-User-Id")); v != "user-7" {
+		t.Errorf("Expected vu header to be user-7, %v is found", v)
+	}
+}
+
+func TestParseBreaker(t *testing.T) {
+	threshold, window, err := parseBreaker("50%/10s")
+	if err != nil {
+		t.Errorf("A valid breaker was not parsed correctly: %v", err.Error())
+	}
+	if threshold != 0.5 || window != 10*time.Second {
+		t.Errorf("A valid breaker was not parsed correctly, parsed values: %v %v", threshold, window)
+	}
+	for _, input := range []string{"50/10s", "150%/10s", "50%/10", "0%/1s", "50%/-1s", "50%/5ns"} {
+		if _, _, err := parseBreaker(input); err == nil {
+			t.Errorf("An invalid breaker passed parsing: %v", input)
+		}
+	}
+}
+
+func TestParseOutliers(t *testing.T) {
+	o, err := parseOutliers("3sd")
+	if err != nil || o.StdDevs != 3 || o.P99 != 0 {
+		t.Errorf("A valid standard deviation threshold was not parsed correctly: %v %v", o, err)
+	}
+	o, err = parseOutliers("1.5xp99")
+	if err != nil || o.P99 != 1.5 || o.StdDevs != 0 {
+		t.Errorf("A valid p99 threshold was not parsed correctly: %v %v", o, err)
+	}
+	for _, input := range []string{"3", "sd", "0sd", "2xp90", "-1sd"} {
+		if _, err := parseOutliers(input); err == nil {
+			t.Errorf("An invalid outliers threshold passed parsing: %v", input)
+		}
+	}
+}
+
+func TestParseSLO(t *testing.T) {
+	s, err := parseSLO("200ms@99.9%")
+	if err != nil || s.Latency != 200*time.Millisecond || math.Abs(s.Target-0.999) > 1e-9 {
+		t.Errorf("A valid slo was not parsed correctly: %v %v", s, err)
+	}
+	for _, input := range []string{"200ms", "200ms@99", "200@99%", "0s@99%", "200ms@100%", "200ms@0%"} {
+		if _, err := parseSLO(input); err == nil {
+			t.Errorf("An invalid slo passed parsing: %v", input)
+		}
+	}
+}
+
+func TestParseCPUs(t *testing.T) {
+	list, err := parseCPUs("0,2,4-6")
+	if err != nil || len(list) != 5 || list[0] != 0 || list[1] != 2 || list[4] != 6 {
+		t.Errorf("A valid cpu list was not parsed correctly: %v %v", list, err)
+	}
+	for _, input := range []string{"", "a", "3-1", "-1", "0-", "1,,2", "4096"} {
+		if _, err := parseCPUs(input); err == nil {
+			t.Errorf("An invalid cpu list passed parsing: %v", input)
+		}
+	}
+}
+
+func TestShard(t *testing.T) {
+	defer func() { shardIndex, shardTotal = 0, 0 }()
+	index, total, err := parseShard("2/8")
+	if err != nil || index != 2 || total != 8 {
+		t.Errorf("A valid shard was not parsed correctly: %v %v %v", index, total, err)
+	}
+	for _, input := range []string{"2", "0/8", "9/8", "a/8", "2/"} {
+		if _, _, err := parseShard(input); err == nil {
+			t.Errorf("An invalid shard passed parsing: %v", input)
+		}
+	}
+	var sum uint
+	for shardIndex, shardTotal = 1, 3; shardIndex <= 3; shardIndex++ {
+		sum += shardOf(100)
+	}
+	if sum != 100 {
+		t.Errorf("Expected shards to split 100 requests exactly, found %d", sum)
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		input string
+		limit uint
+		per   time.Duration
+	}{
+		{"300/m", 300, time.Minute},
+		{"10/s", 10, time.Second},
+		{"2/h", 2, time.Hour},
+		{"5/10s", 5, 10 * time.Second},
+		{"0.5/s", 1, 2 * time.Second},
+	}
+	for _, test := range tests {
+		limit, per, err := parseRate(test.input)
+		if err != nil || limit != test.limit || per != test.per {
+			t.Errorf("Expected %v to be %d per %v, found %d per %v, %v", test.input, test.limit, test.per, limit, per, err)
+		}
+	}
+	for _, input := range []string{"300", "0/s", "10/x", "/m", "10/-1s"} {
+		if _, _, err := parseRate(input); err == nil {
+			t.Errorf("An invalid rate passed parsing: %v", input)
+		}
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	if limit, per := rateLimit(0.5, time.Second); limit != 1 || per != 2*time.Second {
+		t.Errorf("Expected 0.5 qps to be 1 request every 2s, found %d per %v", limit, per)
+	}
+	if limit, per := rateLimit(20, time.Second); limit != 20 || per != time.Second {
+		t.Errorf("Expected 20 qps to be kept, found %d per %v", limit, per)
+	}
+	if limit, _ := rateLimit(0, time.Second); limit != 0 {
+		t.Errorf("Expected no rate limit, found %d", limit)
+	}
+}
+
+func TestHeaderRotation(t *testing.T) {
+	r := &headerRotation{name: "X-API-Key", values: []string{"a", "b", "c"}}
+	var got []string
+	for i := 0; i < 4; i++ {
+		got = append(got, r.value())
+	}
+	if strings.Join(got, ",") != "a,b,c,a" {
+		t.Errorf("Expected values to be rotated round-robin, found %v", got)
+	}
+	picks := func(seed int64) []string {
+		r.rand = rand.New(rand.NewSource(seed))
+		var picks []string
+		for i := 0; i < 10; i++ {
+			v := r.value()
+			if v != "a" && v != "b" && v != "c" {
+				t.Errorf("Expected a random value of the list, found %v", v)
+			}
+			picks = append(picks, v)
+		}
+		return picks
+	}
+	if !reflect.DeepEqual(picks(1), picks(1)) {
+		t.Errorf("Expected random values to be picked the same with the same seed")
+	}
+}
+
+func TestCurveLevels(t *testing.T) {
+	for _, c := range []struct {
+		from, to, step float64
+		expected       []float64
+	}{
+		{100, 500, 100, []float64{100, 200, 300, 400, 500}},
+		{100, 450, 100, []float64{100, 200, 300, 400}},
+		{0.1, 0.3, 0.1, []float64{0.1, 0.2, 0.3}},
+		{100, 100, 10, []float64{100}},
+	} {
+		levels := curveLevels(c.from, c.to, c.step)
+		if len(levels) != len(c.expected) {
+			t.Errorf("Expected levels %v from %v to %v, found %v", c.expected, c.from, c.to, levels)
+			continue
+		}
+		for i := range levels {
+			if math.Abs(levels[i]-c.expected[i]) > 1e-9 {
+				t.Errorf("Expected levels %v from %v to %v, found %v", c.expected, c.from, c.to, levels)
+				break
+			}
+		}
+	}
+
+	var csv bytes.Buffer
+	writeCurve(&csv, []curvePoint{{offered: 100, achieved: 99.5, p99: 0.0123, errors: 2}})
+	if csv.String() != "offered_rps,achieved_rps,p99_secs,errors\n100.0000,99.5000,0.012300,2\n" {
+		t.Errorf("Curve was not written as CSV correctly: %q", csv.String())
+	}
+}
+
+func TestCacheBustHook(t *testing.T) {
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI("http://example.org/items?page=1")
+	hook := cacheBustHook("query")
+	hook(1, req)
+	first := string(req.Header.RequestURI())
+	req.SetRequestURI("http://example.org/items?page=1")
+	hook(1, req)
+	if !strings.HasPrefix(first, "/items?page=1&_pla=") || first == string(req.Header.RequestURI()) {
+		t.Errorf("Expected a query parameter unique to every request, found %v and %v", first, req.Header.RequestURI())
+	}
+
+	req = fasthttp.AcquireRequest()
+	cacheBustHook("header")(1, req)
+	if v := string(req.Header.Peek("Cache-Control")); v != "no-cache" {
+		t.Errorf("Expected Cache-Control to be no-cache, %v is found", v)
+	}
+}
+
+func TestMarkSyntheticHook(t *testing.T) {
+	req := fasthttp.AcquireRequest()
+	markSyntheticHook(1, req)
+	if v := string(req.Header.Peek(syntheticHeader)); v != "pla/"+runID {
+		t.Errorf("Expected requests to be tagged with pla/%v, %v is found", runID, v)
+	}
+}
+
+func TestNTPOffset(t *testing.T) {
+	ts := []byte{0xe8, 0xfe, 0x6f, 0x80, 0x80, 0, 0, 0}
+	if tm := ntpTime(ts); !tm.Equal(time.Unix(1700000000, int64(time.Second/2))) {
+		t.Errorf("Expected NTP timestamp to decode to 1700000000.5, found %v", tm.UnixNano())
+	}
+
+	sent := time.Unix(1000, 0)
+	// The local clock is 50ms ahead, with 10ms network delays each way.
+	serverReceived := sent.Add(10*time.Millisecond - 50*time.Millisecond)
+	serverSent := serverReceived.Add(time.Millisecond)
+	received := sent.Add(21 * time.Millisecond)
+	if offset := ntpOffset(sent, serverReceived, serverSent, received); offset != 50*time.Millisecond {
+		t.Errorf("Expected a clock offset of 50ms, found %v", offset)
+	}
+}
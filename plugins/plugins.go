@@ -0,0 +1,66 @@
+// Package plugins is the stable interface between pla and plugins, which let
+// teams report to their own systems or build requests their own way, ex:
+// signing them, without forking pla.
+//
+// A plugin is a main package built with go build -buildmode=plugin against
+// the same pla version, exporting a Plugin variable:
+//
+//	package main
+//
+//	import "github.com/mercadolibre/pla/plugins"
+//
+//	var Plugin = plugins.Plugin{
+//		Hook: func(vu int, req *fasthttp.Request) {
+//			req.Header.Set("Authorization", sign(req))
+//		},
+//	}
+//
+// It is then loaded with pla --plugin path/to/plugin.so.
+package plugins
+
+import (
+	"fmt"
+	"plugin"
+
+	"github.com/mercadolibre/pla/boomer"
+)
+
+// Symbol is the name of the variable plugins export.
+const Symbol = "Plugin"
+
+// Reporter receives the results of a test, like pla's own interfaces.
+// ProcessResult is called from a single goroutine.
+type Reporter interface {
+	Start(b *boomer.Boomer)
+	ProcessResult(res boomer.Result)
+	End()
+}
+
+// Plugin is what a plugin adds to pla, any of its fields can be nil.
+type Plugin struct {
+	// Reporter gets every result next to the terminal report.
+	Reporter Reporter
+
+	// Hook is called with every request before it is sent.
+	Hook boomer.RequestHook
+
+	// Factory builds the requests instead of copying the configured one.
+	Factory boomer.RequestFactory
+}
+
+// Load opens the plugin at path.
+func Load(path string) (*Plugin, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	sym, err := p.Lookup(Symbol)
+	if err != nil {
+		return nil, err
+	}
+	loaded, ok := sym.(*Plugin)
+	if !ok {
+		return nil, fmt.Errorf("%s: %s is a %T, must be a plugins.Plugin", path, Symbol, sym)
+	}
+	return loaded, nil
+}
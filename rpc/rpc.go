@@ -0,0 +1,201 @@
+// Package rpc provides helpers to load test gRPC-Web and Connect services,
+// as browser facing RPC gateways expose them over HTTP/1.1.
+package rpc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/mercadolibre/pla/boomer"
+	"github.com/valyala/fasthttp"
+)
+
+// Protocols requests may be sent with.
+const (
+	// GRPCWeb frames messages like gRPC, with trailers in the body.
+	GRPCWeb = "grpc-web"
+	// GRPCWebText is GRPCWeb encoded in base64, as browsers without binary
+	// streaming support use.
+	GRPCWebText = "grpc-web-text"
+	// Connect sends unary Connect requests, whose messages aren't framed.
+	Connect = "connect"
+)
+
+// Codecs messages may be encoded with.
+const (
+	Proto = "proto"
+	JSON  = "json"
+)
+
+// frameTrailers flags the gRPC-Web frame holding the trailers, frameHeader
+// is the size of the header of frames.
+const (
+	frameTrailers = 0x80
+	frameHeader   = 5
+)
+
+// codes are the names of gRPC status codes.
+var codes = []string{
+	"OK", "CANCELLED", "UNKNOWN", "INVALID_ARGUMENT", "DEADLINE_EXCEEDED",
+	"NOT_FOUND", "ALREADY_EXISTS", "PERMISSION_DENIED", "RESOURCE_EXHAUSTED",
+	"FAILED_PRECONDITION", "ABORTED", "OUT_OF_RANGE", "UNIMPLEMENTED",
+	"INTERNAL", "UNAVAILABLE", "DATA_LOSS", "UNAUTHENTICATED",
+}
+
+// SetRequest makes req a call sending msg, encoded with codec, using
+// protocol, with its method, content type, protocol headers and body.
+func SetRequest(req *fasthttp.Request, protocol, codec string, msg []byte) {
+	req.Header.SetMethod("POST")
+	switch protocol {
+	case Connect:
+		req.Header.SetContentType("application/" + codec)
+		req.Header.Set("Connect-Protocol-Version", "1")
+		req.SetBody(msg)
+	case GRPCWebText:
+		req.Header.SetContentType("application/grpc-web-text")
+		req.Header.Set("Accept", "application/grpc-web-text")
+		req.Header.Set("X-Grpc-Web", "1")
+		req.SetBodyString(base64.StdEncoding.EncodeToString(Frame(msg)))
+	default:
+		req.Header.SetContentType("application/grpc-web+" + codec)
+		req.Header.Set("X-Grpc-Web", "1")
+		req.SetBody(Frame(msg))
+	}
+	req.Header.SetContentLength(len(req.Body()))
+}
+
+// Frame prefixes msg with the gRPC frame header: an uncompressed flag and
+// its length.
+func Frame(msg []byte) []byte {
+	framed := make([]byte, frameHeader+len(msg))
+	binary.BigEndian.PutUint32(framed[1:frameHeader], uint32(len(msg)))
+	copy(framed[frameHeader:], msg)
+	return framed
+}
+
+// Assertion returns a boomer.Assertion failing responses whose call failed
+// according to protocol, as told by their gRPC status or Connect error.
+func Assertion(protocol string) boomer.Assertion {
+	if protocol == Connect {
+		return connectStatus
+	}
+	return func(resp *fasthttp.Response) error {
+		return grpcStatus(resp, protocol == GRPCWebText)
+	}
+}
+
+// grpcStatus reads the status of a gRPC-Web call from the headers of
+// trailers-only responses, or from the trailers frame ending the body.
+func grpcStatus(resp *fasthttp.Response, text bool) error {
+	if code := resp.Header.Peek("Grpc-Status"); len(code) > 0 {
+		return statusError(string(code), string(resp.Header.Peek("Grpc-Message")))
+	}
+	body, err := boomer.ResponseBody(resp)
+	if err != nil {
+		return err
+	}
+	if text {
+		if body, err = decodeText(body); err != nil {
+			return fmt.Errorf("invalid grpc-web-text response: %v", err)
+		}
+	}
+	for len(body) >= frameHeader {
+		flags, size := body[0], binary.BigEndian.Uint32(body[1:frameHeader])
+		if uint64(len(body)-frameHeader) < uint64(size) {
+			break
+		}
+		frame := body[frameHeader : frameHeader+int(size)]
+		body = body[frameHeader+int(size):]
+		if flags&frameTrailers == 0 {
+			continue
+		}
+		var code, msg string
+		for _, line := range strings.Split(string(frame), "\r\n") {
+			kv := strings.SplitN(line, ":", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			switch strings.ToLower(strings.TrimSpace(kv[0])) {
+			case "grpc-status":
+				code = strings.TrimSpace(kv[1])
+			case "grpc-message":
+				msg = strings.TrimSpace(kv[1])
+			}
+		}
+		return statusError(code, msg)
+	}
+	return fmt.Errorf("grpc-web response without grpc-status")
+}
+
+// decodeText decodes a grpc-web-text body, whose frames may have been
+// encoded separately, each with its own padding.
+func decodeText(body []byte) ([]byte, error) {
+	var decoded []byte
+	for len(body) > 0 {
+		end := bytes.IndexByte(body, '=')
+		if end < 0 {
+			end = len(body)
+		}
+		for end < len(body) && body[end] == '=' {
+			end++
+		}
+		chunk := make([]byte, base64.StdEncoding.DecodedLen(end))
+		n, err := base64.StdEncoding.Decode(chunk, body[:end])
+		if err != nil {
+			return nil, err
+		}
+		decoded = append(decoded, chunk[:n]...)
+		body = body[end:]
+	}
+	return decoded, nil
+}
+
+func statusError(code, msg string) error {
+	if code == "0" {
+		return nil
+	}
+	if code == "" {
+		return fmt.Errorf("grpc-web response without grpc-status")
+	}
+	name := code
+	if i, err := strconv.Atoi(code); err == nil && i >= 0 && i < len(codes) {
+		name = codes[i]
+	}
+	// Messages are percent encoded.
+	if unescaped, err := url.PathUnescape(msg); err == nil {
+		msg = unescaped
+	}
+	if msg == "" {
+		return fmt.Errorf("grpc-status %v", name)
+	}
+	return fmt.Errorf("grpc-status %v: %v", name, msg)
+}
+
+// connectStatus fails unary Connect responses other than 200, with the code
+// and message of their JSON error.
+func connectStatus(resp *fasthttp.Response) error {
+	if resp.StatusCode() == fasthttp.StatusOK {
+		return nil
+	}
+	var e struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	body, err := boomer.ResponseBody(resp)
+	if err != nil {
+		return err
+	}
+	if json.Unmarshal(body, &e) != nil || e.Code == "" {
+		return fmt.Errorf("connect error with status %d", resp.StatusCode())
+	}
+	if e.Message == "" {
+		return fmt.Errorf("connect error %v", e.Code)
+	}
+	return fmt.Errorf("connect error %v: %v", e.Code, e.Message)
+}
@@ -0,0 +1,47 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+)
+
+func TestFrame(t *testing.T) {
+	framed := Frame([]byte("hello"))
+	if !bytes.Equal(framed, []byte{0, 0, 0, 0, 5, 'h', 'e', 'l', 'l', 'o'}) {
+		t.Errorf("Message was not framed correctly: %v", framed)
+	}
+}
+
+func TestDecodeText(t *testing.T) {
+	data := Frame([]byte("a"))
+	trailers := append([]byte{frameTrailers, 0, 0, 0, 13}, "grpc-status:0"...)
+	// Frames may be encoded separately, each padded.
+	body := base64.StdEncoding.EncodeToString(data) + base64.StdEncoding.EncodeToString(trailers)
+	decoded, err := decodeText([]byte(body))
+	if err != nil {
+		t.Fatalf("Could not decode a valid body: %v", err)
+	}
+	if !bytes.Equal(decoded, append(data, trailers...)) {
+		t.Errorf("Body was not decoded correctly: %v", decoded)
+	}
+}
+
+func TestStatusError(t *testing.T) {
+	tests := []struct {
+		code, msg string
+		expected  string
+	}{
+		{"0", "", ""},
+		{"14", "upstream%20down", "grpc-status UNAVAILABLE: upstream down"},
+		{"5", "", "grpc-status NOT_FOUND"},
+		{"99", "", "grpc-status 99"},
+		{"", "", "grpc-web response without grpc-status"},
+	}
+	for _, test := range tests {
+		err := statusError(test.code, test.msg)
+		if test.expected == "" && err != nil || test.expected != "" && (err == nil || err.Error() != test.expected) {
+			t.Errorf("Expected %q for grpc-status %v, found %v", test.expected, test.code, err)
+		}
+	}
+}
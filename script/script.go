@@ -0,0 +1,235 @@
+// Package script runs Lua callbacks on requests and responses, for logic
+// too specific for flags, like custom signing or conditional checks.
+//
+// A script may define any of these global functions:
+//
+//	function setup()
+//		-- Called once before the test, the table it returns, of strings,
+//		-- numbers, booleans and tables of them, is the global data of the
+//		-- other callbacks.
+//		return {token = "..."}
+//	end
+//
+//	function request(vu, req)
+//		-- req has method, uri, body and headers, changes to them are sent,
+//		-- headers set to nil are removed. Errors fail the request.
+//		req.headers["Authorization"] = data.token
+//	end
+//
+//	function response(res)
+//		-- res has status, body and headers, returning false, and an
+//		-- optional message, fails the request.
+//		return res.status == 200, "unexpected status"
+//	end
+//
+// Callbacks run on one of several Lua states, so they should not keep state
+// across calls besides data.
+package script
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/mercadolibre/pla/boomer"
+	"github.com/valyala/fasthttp"
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+// ErrResponse is the error of responses a script fails without a message.
+var ErrResponse = errors.New("script failed response")
+
+// Script is a loaded Lua script.
+type Script struct {
+	states chan *lua.LState
+}
+
+// Load runs the script at path in n Lua states, so up to n requests can run
+// callbacks at once, and calls its setup function on the first of them.
+// The states must be released with Close.
+func Load(path string, n int) (*Script, error) {
+	if n < 1 {
+		n = 1
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	chunk, err := parse.Parse(file, path)
+	if err != nil {
+		return nil, err
+	}
+	// Compiled once, but still run on every state to define its functions.
+	proto, err := lua.Compile(chunk, path)
+	if err != nil {
+		return nil, err
+	}
+	s := &Script{states: make(chan *lua.LState, n)}
+	var data *lua.LTable
+	for i := 0; i < n; i++ {
+		L := lua.NewState()
+		L.Push(L.NewFunctionFromProto(proto))
+		if err := L.PCall(0, lua.MultRet, nil); err != nil {
+			L.Close()
+			s.Close()
+			return nil, err
+		}
+		if i == 0 {
+			if data, err = setup(L); err != nil {
+				L.Close()
+				return nil, err
+			}
+			L.SetGlobal("data", data)
+		} else {
+			L.SetGlobal("data", copyTable(L, data, 0))
+		}
+		s.states <- L
+	}
+	return s, nil
+}
+
+// setup calls the setup function of the script on L, returning the table
+// it returns, an empty one without setup.
+func setup(L *lua.LState) (*lua.LTable, error) {
+	fn := L.GetGlobal("setup")
+	if fn.Type() != lua.LTFunction {
+		return L.NewTable(), nil
+	}
+	if err := L.CallByParam(lua.P{Fn: fn, NRet: 1, Protect: true}); err != nil {
+		return nil, fmt.Errorf("script setup: %v", err)
+	}
+	ret := L.Get(-1)
+	L.Pop(1)
+	data, ok := ret.(*lua.LTable)
+	if !ok {
+		return L.NewTable(), nil
+	}
+	return data, nil
+}
+
+// maxDataDepth is how deep tables of setup data are copied, deeper ones,
+// like those referencing themselves, are dropped.
+const maxDataDepth = 16
+
+// copyTable copies the strings, numbers, booleans and tables of them of
+// data, which belongs to another state, into a new table of L.
+func copyTable(L *lua.LState, data *lua.LTable, depth int) *lua.LTable {
+	t := L.NewTable()
+	data.ForEach(func(k, v lua.LValue) {
+		switch k.Type() {
+		case lua.LTString, lua.LTNumber, lua.LTBool:
+		default:
+			return
+		}
+		switch v := v.(type) {
+		case lua.LString, lua.LNumber, lua.LBool:
+			t.RawSet(k, v)
+		case *lua.LTable:
+			if depth < maxDataDepth {
+				t.RawSet(k, copyTable(L, v, depth+1))
+			}
+		}
+	})
+	return t
+}
+
+// Close releases the Lua states.
+func (s *Script) Close() {
+	for {
+		select {
+		case L := <-s.states:
+			L.Close()
+		default:
+			return
+		}
+	}
+}
+
+// Filter returns a request filter calling the request function of the
+// script, nil if it has none. Errors of the function fail the request.
+func (s *Script) Filter() boomer.RequestFilter {
+	if !s.defines("request") {
+		return nil
+	}
+	return func(vu int, req *fasthttp.Request) error {
+		L := <-s.states
+		defer func() { s.states <- L }()
+		t := L.NewTable()
+		t.RawSetString("method", lua.LString(req.Header.Method()))
+		t.RawSetString("uri", lua.LString(req.Header.RequestURI()))
+		t.RawSetString("body", lua.LString(req.Body()))
+		headers := L.NewTable()
+		var names []string
+		req.Header.VisitAll(func(k, v []byte) {
+			headers.RawSetString(string(k), lua.LString(v))
+			names = append(names, string(k))
+		})
+		t.RawSetString("headers", headers)
+		fn := L.GetGlobal("request")
+		if err := L.CallByParam(lua.P{Fn: fn, NRet: 0, Protect: true}, lua.LNumber(vu), t); err != nil {
+			return fmt.Errorf("script request: %v", err)
+		}
+		if method := lua.LVAsString(t.RawGetString("method")); method != string(req.Header.Method()) {
+			req.Header.SetMethod(method)
+		}
+		if uri := lua.LVAsString(t.RawGetString("uri")); uri != string(req.Header.RequestURI()) {
+			req.SetRequestURI(uri)
+		}
+		if body := lua.LVAsString(t.RawGetString("body")); body != string(req.Body()) {
+			req.SetBody([]byte(body))
+		}
+		if headers, ok := t.RawGetString("headers").(*lua.LTable); ok {
+			for _, name := range names {
+				if headers.RawGetString(name) == lua.LNil {
+					req.Header.Del(name)
+				}
+			}
+			headers.ForEach(func(k, v lua.LValue) {
+				req.Header.Set(k.String(), lua.LVAsString(v))
+			})
+		}
+		return nil
+	}
+}
+
+// Assertion returns an assertion calling the response function of the
+// script, nil if it has none.
+func (s *Script) Assertion() boomer.Assertion {
+	if !s.defines("response") {
+		return nil
+	}
+	return func(resp *fasthttp.Response) error {
+		L := <-s.states
+		defer func() { s.states <- L }()
+		t := L.NewTable()
+		t.RawSetString("status", lua.LNumber(resp.StatusCode()))
+		t.RawSetString("body", lua.LString(resp.Body()))
+		headers := L.NewTable()
+		resp.Header.VisitAll(func(k, v []byte) {
+			headers.RawSetString(string(k), lua.LString(v))
+		})
+		t.RawSetString("headers", headers)
+		fn := L.GetGlobal("response")
+		if err := L.CallByParam(lua.P{Fn: fn, NRet: 2, Protect: true}, t); err != nil {
+			return fmt.Errorf("script response: %v", err)
+		}
+		ok, msg := L.Get(-2), L.Get(-1)
+		L.Pop(2)
+		if ok != lua.LNil && lua.LVIsFalse(ok) {
+			if msg.Type() == lua.LTString {
+				return errors.New(msg.String())
+			}
+			return ErrResponse
+		}
+		return nil
+	}
+}
+
+// defines tells whether the script has a global function named name.
+func (s *Script) defines(name string) bool {
+	L := <-s.states
+	defer func() { s.states <- L }()
+	return L.GetGlobal(name).Type() == lua.LTFunction
+}
@@ -0,0 +1,82 @@
+package script
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+const source = `
+function setup()
+	return {token = "secret", tenant = {id = "acme"}}
+end
+
+function request(vu, req)
+	if req.uri == "/fail" then
+		error("refused")
+	end
+	req.headers["Authorization"] = data.token .. "-" .. vu
+	req.headers["X-Tenant"] = data.tenant.id
+	req.headers["X-Debug"] = nil
+end
+
+function response(res)
+	return res.status == 200, "unexpected status"
+end
+`
+
+func load(t *testing.T) *Script {
+	file, err := ioutil.TempFile("", "script")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString(source)
+	file.Close()
+	s, err := Load(file.Name(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestFilter(t *testing.T) {
+	s := load(t)
+	defer s.Close()
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI("http://example.com/")
+	req.Header.Set("X-Debug", "1")
+	if err := s.Filter()(3, req); err != nil {
+		t.Fatal(err)
+	}
+	if auth := string(req.Header.Peek("Authorization")); auth != "secret-3" {
+		t.Errorf("Expected the script to set the header from setup data, found %q", auth)
+	}
+	if tenant := string(req.Header.Peek("X-Tenant")); tenant != "acme" {
+		t.Errorf("Expected nested setup data to be kept, found %q", tenant)
+	}
+	if debug := req.Header.Peek("X-Debug"); debug != nil {
+		t.Errorf("Expected the script to remove the header, found %q", debug)
+	}
+
+	req.SetRequestURI("http://example.com/fail")
+	if err := s.Filter()(3, req); err == nil {
+		t.Errorf("Expected errors of the script to fail the request")
+	}
+}
+
+func TestAssertion(t *testing.T) {
+	s := load(t)
+	defer s.Close()
+	resp := fasthttp.AcquireResponse()
+	resp.SetStatusCode(200)
+	if err := s.Assertion()(resp); err != nil {
+		t.Errorf("Expected a 200 response to pass, found %v", err)
+	}
+	resp.SetStatusCode(500)
+	if err := s.Assertion()(resp); err == nil || err.Error() != "unexpected status" {
+		t.Errorf("Expected a 500 response to fail with the script's message, found %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"runtime"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// selftestDuration is how long selftest runs when neither length nor amount
+// are given.
+const selftestDuration = 10 * time.Second
+
+// startEchoServer starts a server on a local port answering requests with
+// their own body, it stops when the returned listener is closed.
+func startEchoServer() net.Listener {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		usageAndExit(err.Error())
+	}
+	server := &fasthttp.Server{
+		Handler: func(ctx *fasthttp.RequestCtx) {
+			ctx.SetBody(ctx.PostBody())
+		},
+	}
+	go server.Serve(ln)
+	return ln
+}
+
+// selftest drives load against an embedded echo server, reporting how many
+// requests per second this machine can generate, so it can be told whether
+// pla or the target is the bottleneck of a test.
+func selftest() {
+	ln := startEchoServer()
+	defer ln.Close()
+
+	t := &target{url: "http://" + ln.Addr().String() + "/"}
+	t.boom = newBoomer(newRequest(t.url))
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	go func() {
+		<-c
+		t.boom.Stop()
+	}()
+
+	fmt.Printf("Running against an embedded echo server at %s...\n", ln.Addr())
+	t.boom.Run()
+	t.process(time.Now())
+
+	requests := t.latencies.Len() + t.errors
+	fmt.Printf("\nSelf test:\n")
+	fmt.Printf("  CPUs:\t%d\n", runtime.NumCPU())
+	fmt.Printf("  Concurrency:\t%d\n", t.boom.C)
+	fmt.Printf("  Requests:\t%d\n", requests)
+	fmt.Printf("  Errors:\t%d\n", t.errors)
+	fmt.Printf("  Average:\t%4.4f secs.\n", t.latencies.Mean())
+	fmt.Printf("  99%%:\t%4.4f secs.\n", t.latencies.Quantile(0.99))
+	fmt.Printf("  Requests/sec:\t%4.4f\n", float64(requests)/t.total.Seconds())
+	fmt.Printf("\nThis is about the most this machine can generate with these settings,\n")
+	fmt.Printf("targets reaching close to it may be limited by pla rather than by themselves.\n")
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"time"
+
+	"github.com/mercadolibre/pla/boomer"
+)
+
+// divergence counts where the candidate responded differently than the
+// primary.
+type divergence struct {
+	requests int
+	statuses map[string]int
+	bodies   int
+	compared int
+}
+
+func (d *divergence) add(res boomer.Result) {
+	d.requests++
+	s := res.Shadow
+	if res.StatusCode != s.StatusCode {
+		d.statuses[fmt.Sprintf("%s vs %s", statusName(res.StatusCode, res.Err), statusName(s.StatusCode, s.Err))]++
+	}
+	if *shadowBodies && res.Err == nil && s.Err == nil {
+		d.compared++
+		if !s.BodyMatch {
+			d.bodies++
+		}
+	}
+}
+
+func statusName(code int, err error) string {
+	if code == 0 && err != nil {
+		return "error"
+	}
+	return fmt.Sprint(code)
+}
+
+// shadow sends every request to both primaryURL and candidateURL and prints
+// how their responses diverged.
+func shadow(primaryURL, candidateURL string) {
+	addr, req := newRequest(primaryURL)
+	candidateAddr, candidateReq := newRequest(candidateURL)
+	if string(req.URI().Scheme()) != string(candidateReq.URI().Scheme()) {
+		usageAndExit("primary and candidate must use the same scheme")
+	}
+	boom := newBoomer(addr, req).WithShadow(candidateAddr, string(candidateReq.URI().Host()), *shadowBodies)
+	prepare(boom)
+	waitForStart()
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	go func() {
+		<-c
+		boom.Stop()
+	}()
+
+	fmt.Printf("Shadowing %s with %s...\n", primaryURL, candidateURL)
+	primary := &target{url: primaryURL, boom: boom}
+	candidate := &target{url: candidateURL}
+	d := &divergence{statuses: make(map[string]int)}
+	start := time.Now()
+	boom.Run()
+	for res := range boom.Results() {
+		if res.Shadow == nil {
+			continue
+		}
+		d.add(res)
+		if res.Err != nil || res.StatusCode >= 500 {
+			primary.errors++
+		} else {
+			primary.latencies.Add(res.Duration.Seconds())
+		}
+		if res.Shadow.Err != nil || res.Shadow.StatusCode >= 500 {
+			candidate.errors++
+		} else {
+			candidate.latencies.Add(res.Shadow.Duration.Seconds())
+		}
+	}
+	primary.total = time.Since(start)
+	candidate.total = primary.total
+	printComparison(primary, candidate)
+	printDivergence(d)
+}
+
+func printDivergence(d *divergence) {
+	if d.requests == 0 {
+		return
+	}
+	var mismatches int
+	pairs := make([]string, 0, len(d.statuses))
+	for pair, count := range d.statuses {
+		mismatches += count
+		pairs = append(pairs, pair)
+	}
+	sort.Strings(pairs)
+
+	fmt.Printf("\nDivergence:\n")
+	fmt.Printf("  Status codes:\t%d of %d requests (%4.2f%%)\n", mismatches, d.requests, float64(mismatches)/float64(d.requests)*100)
+	for _, pair := range pairs {
+		fmt.Printf("    [%s]\t%d responses\n", pair, d.statuses[pair])
+	}
+	if d.compared > 0 {
+		fmt.Printf("  Bodies:\t%d of %d compared responses (%4.2f%%)\n", d.bodies, d.compared, float64(d.bodies)/float64(d.compared)*100)
+	}
+}
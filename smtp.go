@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"runtime"
+
+	"github.com/mercadolibre/pla/boomer"
+	"github.com/mercadolibre/pla/mail"
+	"github.com/valyala/fasthttp"
+)
+
+// smtpTest runs a load test submitting messages of size bytes, from and to
+// addresses rendered from templates, to the SMTP server at server.
+func smtpTest(server, from, to string, size int) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "25")
+	}
+	if size < 0 {
+		usageAndExit("message-size cannot be negative")
+	}
+	conns := int(*c)
+	if conns == 0 {
+		conns = runtime.NumCPU()
+	}
+	req := fasthttp.AcquireRequest()
+	req.Header.Set("From", from)
+	req.Header.Set("To", to)
+	req.SetBody(mail.Message(size))
+	requestMix = []*boomer.WeightedRequest{{Request: req, Weight: 1, Label: "SMTP " + to}}
+	doer = mail.NewClient(server, conns)
+	fmt.Printf("Submitting %d bytes messages to %s\n", size, server)
+	run("http://" + server + "/")
+}
@@ -0,0 +1,158 @@
+// Package soap provides helpers to load test SOAP/XML services.
+package soap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mercadolibre/pla/boomer"
+	"github.com/valyala/fasthttp"
+)
+
+const envelope = `<?xml version="1.0" encoding="utf-8"?>` +
+	`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">` +
+	`<soap:Body>%s</soap:Body>` +
+	`</soap:Envelope>`
+
+// Envelope wraps body in a SOAP 1.1 envelope.
+func Envelope(body string) string {
+	return fmt.Sprintf(envelope, body)
+}
+
+// SetAction sets the SOAPAction header on req, along with an XML content
+// type when none was provided.
+func SetAction(req *fasthttp.Request, action string) {
+	req.Header.Set("SOAPAction", `"`+action+`"`)
+	if len(req.Header.ContentType()) == 0 {
+		req.Header.SetContentType("text/xml; charset=utf-8")
+	}
+}
+
+type step struct {
+	name       string
+	descendant bool
+}
+
+// Path is a small subset of XPath: absolute (/a/b) and descendant (//b)
+// location steps over element local names, '*' matching any element. An
+// optional trailing =value compares the text of the matched element.
+type Path struct {
+	expr     string
+	steps    []step
+	value    string
+	hasValue bool
+}
+
+// ParsePath parses an expression like //Response/Status=OK.
+func ParsePath(expr string) (*Path, error) {
+	p := &Path{expr: expr}
+	path := expr
+	if i := strings.Index(expr, "="); i >= 0 {
+		path, p.value, p.hasValue = expr[:i], expr[i+1:], true
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("xpath must be absolute; expr = %v", expr)
+	}
+	if strings.ContainsAny(path, "[]@()") {
+		return nil, fmt.Errorf("xpath predicates, attributes and functions are not supported; expr = %v", expr)
+	}
+	var descendant bool
+	for _, name := range strings.Split(path[1:], "/") {
+		if name == "" {
+			if descendant {
+				return nil, fmt.Errorf("invalid xpath; expr = %v", expr)
+			}
+			descendant = true
+			continue
+		}
+		p.steps = append(p.steps, step{name: name, descendant: descendant})
+		descendant = false
+	}
+	if len(p.steps) == 0 || descendant {
+		return nil, fmt.Errorf("invalid xpath; expr = %v", expr)
+	}
+	return p, nil
+}
+
+// Match reports whether the XML document contains an element matching p.
+func (p *Path) Match(doc []byte) (bool, error) {
+	dec := xml.NewDecoder(bytes.NewReader(doc))
+	var stack []string
+	// depth of the matched element whose text is being collected
+	matched := -1
+	var text bytes.Buffer
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			stack = append(stack, t.Name.Local)
+			if matched < 0 && matches(p.steps, stack) {
+				if !p.hasValue {
+					return true, nil
+				}
+				matched = len(stack)
+				text.Reset()
+			}
+		case xml.CharData:
+			if matched >= 0 {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			if matched == len(stack) {
+				if strings.TrimSpace(text.String()) == p.value {
+					return true, nil
+				}
+				matched = -1
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+}
+
+func matches(steps []step, stack []string) bool {
+	if len(steps) == 0 {
+		return len(stack) == 0
+	}
+	s := steps[0]
+	if !s.descendant {
+		return len(stack) > 0 && s.matchName(stack[0]) && matches(steps[1:], stack[1:])
+	}
+	for i := range stack {
+		if s.matchName(stack[i]) && matches(steps[1:], stack[i+1:]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s step) matchName(name string) bool {
+	return s.name == "*" || s.name == name
+}
+
+// Assertion returns a boomer.Assertion failing responses which don't match p.
+func (p *Path) Assertion() boomer.Assertion {
+	err := fmt.Errorf("response does not match xpath %v", p.expr)
+	return func(resp *fasthttp.Response) error {
+		body, bodyErr := boomer.ResponseBody(resp)
+		if bodyErr != nil {
+			return bodyErr
+		}
+		ok, xmlErr := p.Match(body)
+		if xmlErr != nil {
+			return fmt.Errorf("invalid xml response: %v", xmlErr)
+		}
+		if !ok {
+			return err
+		}
+		return nil
+	}
+}
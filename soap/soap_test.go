@@ -0,0 +1,60 @@
+package soap
+
+import (
+	"testing"
+)
+
+const response = `<?xml version="1.0"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+  <soap:Body>
+    <m:GetPriceResponse xmlns:m="https://www.example.org/stock">
+      <m:Price>34.5</m:Price>
+      <m:Status> OK </m:Status>
+    </m:GetPriceResponse>
+  </soap:Body>
+</soap:Envelope>`
+
+func TestPathMatch(t *testing.T) {
+	tests := []struct {
+		expr  string
+		match bool
+	}{
+		{"/Envelope/Body/GetPriceResponse/Price", true},
+		{"/Envelope/Body/*/Status=OK", true},
+		{"//Price=34.5", true},
+		{"//Body//Status", true},
+		{"//Price=35", false},
+		{"/Body/GetPriceResponse", false},
+		{"//Fault", false},
+	}
+	for _, test := range tests {
+		p, err := ParsePath(test.expr)
+		if err != nil {
+			t.Errorf("Could not parse a valid xpath %v: %v", test.expr, err)
+			continue
+		}
+		match, err := p.Match([]byte(response))
+		if err != nil {
+			t.Errorf("Unexpected error matching %v: %v", test.expr, err)
+		}
+		if match != test.match {
+			t.Errorf("Expected %v to match %v, found %v", test.expr, test.match, match)
+		}
+	}
+}
+
+func TestParseInvalidPath(t *testing.T) {
+	for _, expr := range []string{"Envelope", "/", "//", "/a///b", "//a[1]", "//@id"} {
+		if _, err := ParsePath(expr); err == nil {
+			t.Errorf("An invalid xpath passed parsing: %v", expr)
+		}
+	}
+}
+
+func TestEnvelope(t *testing.T) {
+	p, _ := ParsePath("/Envelope/Body/Ping=pong")
+	match, err := p.Match([]byte(Envelope("<Ping>pong</Ping>")))
+	if err != nil || !match {
+		t.Errorf("Envelope did not wrap the body, match: %v, err: %v", match, err)
+	}
+}
@@ -0,0 +1,208 @@
+// Package stats provides statistics over samples of measurements.
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// Sample keeps every value added to it, so exact statistics can be computed.
+type Sample struct {
+	values []float64
+	sorted bool
+	sum    float64
+}
+
+// Add adds v to the sample.
+func (s *Sample) Add(v float64) {
+	s.values = append(s.values, v)
+	s.sorted = false
+	s.sum += v
+}
+
+// Len returns the amount of values in the sample.
+func (s *Sample) Len() int {
+	return len(s.values)
+}
+
+// Mean returns the arithmetic mean of the sample.
+func (s *Sample) Mean() float64 {
+	if len(s.values) == 0 {
+		return 0
+	}
+	return s.sum / float64(len(s.values))
+}
+
+// Variance returns the unbiased variance of the sample.
+func (s *Sample) Variance() float64 {
+	if len(s.values) < 2 {
+		return 0
+	}
+	mean := s.Mean()
+	var sq float64
+	for _, v := range s.values {
+		sq += (v - mean) * (v - mean)
+	}
+	return sq / float64(len(s.values)-1)
+}
+
+// StdDev returns the standard deviation of the sample.
+func (s *Sample) StdDev() float64 {
+	return math.Sqrt(s.Variance())
+}
+
+// Min returns the smallest value of the sample.
+func (s *Sample) Min() float64 {
+	return s.Quantile(0)
+}
+
+// Max returns the largest value of the sample.
+func (s *Sample) Max() float64 {
+	return s.Quantile(1)
+}
+
+// Quantile returns the q quantile (0 to 1) of the sample, linearly
+// interpolating between the closest ranks.
+func (s *Sample) Quantile(q float64) float64 {
+	if len(s.values) == 0 {
+		return 0
+	}
+	if !s.sorted {
+		sort.Float64s(s.values)
+		s.sorted = true
+	}
+	pos := q * float64(len(s.values)-1)
+	i := int(pos)
+	if i >= len(s.values)-1 {
+		return s.values[len(s.values)-1]
+	}
+	frac := pos - float64(i)
+	return s.values[i] + frac*(s.values[i+1]-s.values[i])
+}
+
+// WelchTTest tests whether a and b have different means without assuming
+// equal variances. It returns the t statistic and the two-tailed p-value,
+// the probability of observing such a difference if the means were equal.
+func WelchTTest(a, b *Sample) (t, p float64) {
+	na, nb := float64(a.Len()), float64(b.Len())
+	if na < 2 || nb < 2 {
+		return 0, 1
+	}
+	va, vb := a.Variance()/na, b.Variance()/nb
+	if va+vb == 0 {
+		if a.Mean() == b.Mean() {
+			return 0, 1
+		}
+		return math.Inf(1), 0
+	}
+	t = (a.Mean() - b.Mean()) / math.Sqrt(va+vb)
+	df := (va + vb) * (va + vb) / (va*va/(na-1) + vb*vb/(nb-1))
+	p = 2 * (1 - StudentTCDF(math.Abs(t), df))
+	return t, p
+}
+
+// StudentTCDF returns the cumulative distribution function of the Student's
+// t distribution with df degrees of freedom.
+func StudentTCDF(t, df float64) float64 {
+	x := df / (df + t*t)
+	tail := 0.5 * incompleteBeta(df/2, 0.5, x)
+	if t > 0 {
+		return 1 - tail
+	}
+	return tail
+}
+
+// StudentTQuantile returns the value below which the p fraction of the
+// Student's t distribution with df degrees of freedom lies.
+func StudentTQuantile(p, df float64) float64 {
+	if p == 0.5 {
+		return 0
+	}
+	lo, hi := -1e3, 1e3
+	for i := 0; i < 200; i++ {
+		mid := (lo + hi) / 2
+		if StudentTCDF(mid, df) < p {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// incompleteBeta returns the regularized incomplete beta function I_x(a, b).
+func incompleteBeta(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	la, _ := math.Lgamma(a + b)
+	lb, _ := math.Lgamma(a)
+	lc, _ := math.Lgamma(b)
+	front := math.Exp(la - lb - lc + a*math.Log(x) + b*math.Log(1-x))
+	// The continued fraction converges quickly only on this side.
+	if x < (a+1)/(a+b+2) {
+		return front * betaFraction(a, b, x) / a
+	}
+	return 1 - front*betaFraction(b, a, 1-x)/b
+}
+
+// betaFraction evaluates the continued fraction of the incomplete beta
+// function with the modified Lentz's method.
+func betaFraction(a, b, x float64) float64 {
+	const (
+		epsilon = 1e-14
+		tiny    = 1e-300
+	)
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+	for m := 1.0; m <= 300; m++ {
+		m2 := 2 * m
+		aa := m * (b - m) * x / ((a + m2 - 1) * (a + m2))
+		d = 1 + aa*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		h *= d * c
+		aa = -(a + m) * (a + b + m) * x / ((a + m2) * (a + m2 + 1))
+		d = 1 + aa*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		del := d * c
+		h *= del
+		if math.Abs(del-1) < epsilon {
+			break
+		}
+	}
+	return h
+}
+
+// ConfidenceInterval returns the bounds of the confidence interval of the
+// sample mean at the given level, ex: 0.95, using the Student's t
+// distribution so it holds for small samples.
+func (s *Sample) ConfidenceInterval(level float64) (lo, hi float64) {
+	mean := s.Mean()
+	if len(s.values) < 2 {
+		return mean, mean
+	}
+	n := float64(len(s.values))
+	margin := StudentTQuantile(1-(1-level)/2, n-1) * s.StdDev() / math.Sqrt(n)
+	return mean - margin, mean + margin
+}
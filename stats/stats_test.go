@@ -0,0 +1,82 @@
+package stats
+
+import (
+	"math"
+	"testing"
+)
+
+func near(a, b, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance
+}
+
+func TestSample(t *testing.T) {
+	s := &Sample{}
+	for _, v := range []float64{4, 1, 3, 2, 5} {
+		s.Add(v)
+	}
+	if s.Len() != 5 || s.Mean() != 3 {
+		t.Errorf("Expected 5 values with mean 3, found %d with mean %v", s.Len(), s.Mean())
+	}
+	if !near(s.Variance(), 2.5, 1e-9) {
+		t.Errorf("Expected variance 2.5, found %v", s.Variance())
+	}
+	if s.Min() != 1 || s.Max() != 5 || s.Quantile(0.5) != 3 || s.Quantile(0.25) != 2 {
+		t.Errorf("Unexpected quantiles: min %v, max %v, median %v", s.Min(), s.Max(), s.Quantile(0.5))
+	}
+	if q := s.Quantile(0.9); !near(q, 4.6, 1e-9) {
+		t.Errorf("Expected interpolated p90 of 4.6, found %v", q)
+	}
+}
+
+func TestStudentT(t *testing.T) {
+	// Critical values of two-tailed 95% intervals.
+	tests := []struct {
+		df, t float64
+	}{
+		{1, 12.706},
+		{4, 2.776},
+		{10, 2.228},
+		{1000, 1.962},
+	}
+	for _, test := range tests {
+		if q := StudentTQuantile(0.975, test.df); !near(q, test.t, 1e-3) {
+			t.Errorf("Expected t quantile %v for %v degrees of freedom, found %v", test.t, test.df, q)
+		}
+	}
+	if p := StudentTCDF(0, 5); !near(p, 0.5, 1e-9) {
+		t.Errorf("Expected CDF at 0 to be 0.5, found %v", p)
+	}
+}
+
+func TestWelchTTest(t *testing.T) {
+	a, b, c := &Sample{}, &Sample{}, &Sample{}
+	for i := 0; i < 100; i++ {
+		v := float64(i % 10)
+		a.Add(v)
+		b.Add(v + 0.1)
+		c.Add(v + 5)
+	}
+	if _, p := WelchTTest(a, b); p < 0.5 {
+		t.Errorf("Expected close means not to be significantly different, p = %v", p)
+	}
+	if _, p := WelchTTest(a, c); p > 0.001 {
+		t.Errorf("Expected distant means to be significantly different, p = %v", p)
+	}
+}
+
+func TestConfidenceInterval(t *testing.T) {
+	s := &Sample{}
+	for _, v := range []float64{10, 12, 14, 16, 18} {
+		s.Add(v)
+	}
+	// mean 14, stddev 3.1623, t(0.975, 4) 2.7764
+	lo, hi := s.ConfidenceInterval(0.95)
+	if !near(lo, 10.0736, 1e-3) || !near(hi, 17.9264, 1e-3) {
+		t.Errorf("Expected interval [10.0736, 17.9264], found [%v, %v]", lo, hi)
+	}
+	single := &Sample{}
+	single.Add(3)
+	if lo, hi := single.ConfidenceInterval(0.95); lo != 3 || hi != 3 {
+		t.Errorf("Expected a single value interval to collapse, found [%v, %v]", lo, hi)
+	}
+}
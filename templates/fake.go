@@ -0,0 +1,118 @@
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+var (
+	firstNames = []string{
+		"James", "Mary", "John", "Patricia", "Robert", "Jennifer", "Michael", "Linda",
+		"William", "Elizabeth", "David", "Barbara", "Richard", "Susan", "Joseph", "Jessica",
+		"Thomas", "Sarah", "Carlos", "Lucia", "Juan", "Sofia", "Mateo", "Valentina",
+		"Pedro", "Camila", "Diego", "Martina", "Jorge", "Isabella", "Luis", "Mariana",
+	}
+	lastNames = []string{
+		"Smith", "Johnson", "Williams", "Brown", "Jones", "Garcia", "Miller", "Davis",
+		"Rodriguez", "Martinez", "Hernandez", "Lopez", "Gonzalez", "Wilson", "Anderson", "Thomas",
+		"Taylor", "Moore", "Jackson", "Martin", "Lee", "Perez", "Thompson", "White",
+		"Harris", "Sanchez", "Clark", "Ramirez", "Lewis", "Robinson", "Walker", "Silva",
+	}
+	domains = []string{"example.com", "example.org", "example.net", "mail.example.com"}
+	streets = []string{
+		"Main", "Oak", "Pine", "Maple", "Cedar", "Elm", "Washington", "Lake",
+		"Hill", "Park", "Sunset", "Rivadavia", "Corrientes", "Libertad", "San Martin", "Belgrano",
+	}
+	streetKinds = []string{"St", "Ave", "Blvd", "Rd", "Ln", "Way"}
+	cities      = []string{
+		"Springfield", "Riverside", "Franklin", "Greenville", "Bristol", "Clinton", "Fairview", "Salem",
+		"Buenos Aires", "Cordoba", "Rosario", "Montevideo", "Santiago", "Bogota", "Lima", "Sao Paulo",
+	}
+	words = strings.Fields(`lorem ipsum dolor sit amet consectetur adipiscing elit sed do
+		eiusmod tempor incididunt ut labore et dolore magna aliqua enim ad minim veniam
+		quis nostrud exercitation ullamco laboris nisi aliquip ex ea commodo consequat
+		duis aute irure in reprehenderit voluptate velit esse cillum fugiat nulla pariatur
+		excepteur sint occaecat cupidatat non proident sunt culpa qui officia deserunt
+		mollit anim id est laborum`)
+)
+
+// fakeFuncs returns the functions generating fake data with the random
+// numbers of s:
+//
+//	{{firstName}} {{lastName}} {{name}} {{email}} {{phone}}
+//	{{street}} {{city}} {{zip}} {{address}}
+//	{{lorem N}}  N characters of lorem ipsum text
+//	{{randword}} a random word of 8 to 12 lowercase letters, seldom repeated
+func (s *state) fakeFuncs() map[string]interface{} {
+	return map[string]interface{}{
+		"firstName": s.firstName,
+		"lastName":  s.lastName,
+		"name":      s.name,
+		"email":     s.email,
+		"phone":     s.phone,
+		"street":    s.street,
+		"city":      s.city,
+		"zip":       s.zip,
+		"address":   s.address,
+		"lorem":     s.lorem,
+		"randword":  s.randword,
+	}
+}
+
+func (s *state) pick(values []string) string {
+	return values[s.rand.Intn(len(values))]
+}
+
+func (s *state) firstName() string { return s.pick(firstNames) }
+
+func (s *state) lastName() string { return s.pick(lastNames) }
+
+func (s *state) name() string { return s.firstName() + " " + s.lastName() }
+
+func (s *state) email() string {
+	user := strings.ToLower(s.firstName() + "." + strings.Replace(s.lastName(), " ", "", -1))
+	return fmt.Sprintf("%s%d@%s", user, s.rand.Intn(1000), s.pick(domains))
+}
+
+func (s *state) phone() string {
+	return fmt.Sprintf("+1-%03d-%03d-%04d", 200+s.rand.Intn(800), s.rand.Intn(1000), s.rand.Intn(10000))
+}
+
+func (s *state) street() string {
+	return fmt.Sprintf("%d %s %s", 1+s.rand.Intn(9999), s.pick(streets), s.pick(streetKinds))
+}
+
+func (s *state) city() string { return s.pick(cities) }
+
+func (s *state) zip() string { return fmt.Sprintf("%05d", s.rand.Intn(100000)) }
+
+func (s *state) address() string {
+	return s.street() + ", " + s.city() + " " + s.zip()
+}
+
+// randword returns a random word, unlike the ones of lorem, so names built
+// with it miss caches.
+func (s *state) randword() string {
+	b := make([]byte, 8+s.rand.Intn(5))
+	for i := range b {
+		b[i] = byte('a' + s.rand.Intn(26))
+	}
+	return string(b)
+}
+
+// lorem returns n characters of lorem ipsum words.
+func (s *state) lorem(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	var b bytes.Buffer
+	b.Grow(n + 16)
+	for b.Len() < n {
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(s.pick(words))
+	}
+	return b.String()[:n]
+}
@@ -0,0 +1,72 @@
+package templates
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"text/template"
+
+	"github.com/mercadolibre/pla/boomer"
+	"github.com/valyala/fasthttp"
+)
+
+// requestFuncs returns the functions reading the request being signed:
+//
+//	{{method}} {{host}} {{path}} {{query}} {{uri}} {{body}} {{header "Name"}}
+//
+// They are empty when rendering the request itself.
+func (s *state) requestFuncs() map[string]interface{} {
+	return map[string]interface{}{
+		"method": func() string { return s.part(func(req *fasthttp.Request) []byte { return req.Header.Method() }) },
+		"host":   func() string { return s.part(func(req *fasthttp.Request) []byte { return req.Host() }) },
+		"path":   func() string { return s.part(func(req *fasthttp.Request) []byte { return req.URI().Path() }) },
+		"query":  func() string { return s.part(func(req *fasthttp.Request) []byte { return req.URI().QueryString() }) },
+		"uri":    func() string { return s.part(func(req *fasthttp.Request) []byte { return req.Header.RequestURI() }) },
+		"body":   func() string { return s.part(func(req *fasthttp.Request) []byte { return req.Body() }) },
+		"header": func(name string) string {
+			return s.part(func(req *fasthttp.Request) []byte { return req.Header.Peek(name) })
+		},
+	}
+}
+
+func (s *state) part(get func(req *fasthttp.Request) []byte) string {
+	if s.req == nil {
+		return ""
+	}
+	return string(get(s.req))
+}
+
+// SignFilter returns a request filter setting header to the HMAC-SHA256,
+// keyed by secret, of payload rendered for every request, encoded in hex or
+// base64. It must be added after the filter rendering requests, so the
+// signature covers what is sent, and their counters are the same.
+func (t *Templates) SignFilter(header, secret, payload, encoding string) (boomer.RequestFilter, error) {
+	if _, err := template.New("payload").Funcs(t.state(0).funcs).Parse(payload); err != nil {
+		return nil, fmt.Errorf("invalid signature payload: %v", err)
+	}
+	encode := hex.EncodeToString
+	switch encoding {
+	case "hex":
+	case "base64":
+		encode = base64.StdEncoding.EncodeToString
+	default:
+		return nil, fmt.Errorf("unknown signature encoding %q", encoding)
+	}
+	key := []byte(secret)
+	text := []byte(payload)
+	return func(vu int, req *fasthttp.Request) error {
+		s := t.state(vu)
+		s.req = req
+		rendered, err := s.render(text)
+		s.req = nil
+		if err != nil {
+			return err
+		}
+		mac := hmac.New(sha256.New, key)
+		mac.Write(rendered)
+		req.Header.Set(header, encode(mac.Sum(nil)))
+		return nil
+	}, nil
+}
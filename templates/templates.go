@@ -0,0 +1,277 @@
+// Package templates renders the {{...}} functions found in the URL, headers
+// and body of requests, so every request can carry its own values:
+//
+//	{{vu}}         the number of the worker sending the request
+//	{{counter}}    a counter shared by every worker
+//	{{vuCounter}}  a counter of the worker sending the request
+//	{{choose "A:70" "B:20" "C:10"}}
+//	               one of the choices, randomly according to their weights
+//	{{push "ids" counter}}
+//	               adds a value to a pool of the store shared by every worker
+//	{{pop "ids"}}  removes a random value from the pool and returns it
+//	{{peek "ids"}} returns a random value of the pool, leaving it there
+//
+// along with functions generating fake data, like {{name}}, {{email}} or
+// {{lorem 100}}, listed in fakeFuncs.
+//
+// Counters start at 1 and advance once per request using them, so every use
+// within a request gets the same value. Text which is not a valid template
+// is sent as is, while templates failing to render, like those popping or
+// peeking an empty pool, fail the request.
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"github.com/mercadolibre/pla/boomer"
+	"github.com/mercadolibre/pla/workload"
+	"github.com/valyala/fasthttp"
+)
+
+// maxCached is how many templates a worker keeps parsed, requests built
+// with ever changing text would otherwise grow the cache without bound.
+const maxCached = 1024
+
+// stateShards is how many locks the states of workers are spread over, so
+// workers don't contend on a single one on every request.
+const stateShards = 64
+
+var open = []byte("{{")
+
+// Templates renders the templates of requests.
+type Templates struct {
+	counter uint64
+	seed    int64
+	store   *workload.Store
+	shards  [stateShards]stateShard
+}
+
+// stateShard holds the states of some workers, padded to a cache line so
+// shards don't share one.
+type stateShard struct {
+	lock   sync.Mutex
+	states map[int]*state
+	_      [48]byte
+}
+
+// New returns templates with their counters at zero. Random functions of
+// each worker are seeded from seed, so runs with the same seed and
+// concurrency render the same values per worker, though workers interleave
+// differently on every run. 0 seeds them with the time.
+func New(seed int64) *Templates {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &Templates{seed: seed, store: &workload.Store{}}
+}
+
+// Contains tells whether the URI, body or headers of any of reqs have
+// templates to render.
+func Contains(reqs ...*fasthttp.Request) bool {
+	for _, req := range reqs {
+		if bytes.Contains(req.Header.RequestURI(), open) || bytes.Contains(req.Body(), open) {
+			return true
+		}
+		var found bool
+		req.Header.VisitAll(func(k, v []byte) {
+			found = found || bytes.Contains(v, open)
+		})
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+// WithStore makes the templates push, pop and peek the pools of store, ex:
+// to share them with scenario steps, instead of their own.
+func (t *Templates) WithStore(store *workload.Store) *Templates {
+	t.store = store
+	return t
+}
+
+// state is what the templates of a worker need, only used from its
+// goroutine.
+type state struct {
+	vu    int
+	req   *fasthttp.Request
+	rand  *rand.Rand
+	funcs template.FuncMap
+	cache map[string]*template.Template
+	buf   bytes.Buffer
+
+	// vuCount is the last value of the worker counter, counter and
+	// vuCounter are the values of the current request, 0 until used.
+	vuCount   uint64
+	counter   uint64
+	vuCounter uint64
+}
+
+func (t *Templates) state(vu int) *state {
+	shard := &t.shards[vu%stateShards]
+	shard.lock.Lock()
+	defer shard.lock.Unlock()
+	if shard.states == nil {
+		shard.states = make(map[int]*state)
+	}
+	s, ok := shard.states[vu]
+	if !ok {
+		s = &state{
+			vu:    vu,
+			rand:  rand.New(rand.NewSource(t.seed + int64(vu))),
+			cache: make(map[string]*template.Template),
+		}
+		s.funcs = template.FuncMap{
+			"vu": func() int { return s.vu },
+			"counter": func() uint64 {
+				if s.counter == 0 {
+					s.counter = atomic.AddUint64(&t.counter, 1)
+				}
+				return s.counter
+			},
+			"vuCounter": func() uint64 {
+				if s.vuCounter == 0 {
+					s.vuCount++
+					s.vuCounter = s.vuCount
+				}
+				return s.vuCounter
+			},
+			"choose": s.choose,
+			"push": func(pool string, value interface{}) string {
+				t.store.Pool(pool).Add(fmt.Sprint(value))
+				return ""
+			},
+			"pop":  t.pooled((*workload.Pool).Take),
+			"peek": t.pooled((*workload.Pool).Get),
+		}
+		for name, fn := range s.fakeFuncs() {
+			s.funcs[name] = fn
+		}
+		for name, fn := range s.requestFuncs() {
+			s.funcs[name] = fn
+		}
+		shard.states[vu] = s
+	}
+	return s
+}
+
+// pooled returns a template function getting a value from a pool of the
+// store with get, failing when it is empty.
+func (t *Templates) pooled(get func(*workload.Pool) (string, bool)) func(string) (string, error) {
+	return func(pool string) (string, error) {
+		v, ok := get(t.store.Pool(pool))
+		if !ok {
+			return "", fmt.Errorf("pool %v is empty", pool)
+		}
+		return v, nil
+	}
+}
+
+// Filter returns a request filter rendering the templates of every request,
+// failing those whose templates fail to render.
+func (t *Templates) Filter() boomer.RequestFilter {
+	return func(vu int, req *fasthttp.Request) error {
+		s := t.state(vu)
+		s.req, s.counter, s.vuCounter = nil, 0, 0
+		if uri := req.Header.RequestURI(); bytes.Contains(uri, open) {
+			rendered, err := s.render(uri)
+			if err != nil {
+				return err
+			}
+			req.SetRequestURI(string(rendered))
+		}
+		if body := req.Body(); bytes.Contains(body, open) {
+			rendered, err := s.render(body)
+			if err != nil {
+				return err
+			}
+			req.SetBody(rendered)
+		}
+		var headers [][2]string
+		req.Header.VisitAll(func(k, v []byte) {
+			if bytes.Contains(v, open) {
+				headers = append(headers, [2]string{string(k), string(v)})
+			}
+		})
+		for _, h := range headers {
+			rendered, err := s.render([]byte(h[1]))
+			if err != nil {
+				return err
+			}
+			req.Header.Set(h[0], string(rendered))
+		}
+		return nil
+	}
+}
+
+// Render renders text as the next request of worker vu would.
+func (t *Templates) Render(vu int, text string) (string, error) {
+	s := t.state(vu)
+	s.req, s.counter, s.vuCounter = nil, 0, 0
+	rendered, err := s.render([]byte(text))
+	return string(rendered), err
+}
+
+// render returns the rendered text, which is only valid until the next
+// call, text itself when it is not a valid template.
+func (s *state) render(text []byte) ([]byte, error) {
+	tmpl, ok := s.cache[string(text)]
+	if !ok {
+		var err error
+		tmpl, err = template.New("request").Funcs(s.funcs).Parse(string(text))
+		if err != nil {
+			tmpl = nil
+		}
+		if len(s.cache) >= maxCached {
+			s.cache = make(map[string]*template.Template)
+		}
+		s.cache[string(text)] = tmpl
+	}
+	if tmpl == nil {
+		return text, nil
+	}
+	s.buf.Reset()
+	if err := tmpl.Execute(&s.buf, nil); err != nil {
+		return nil, err
+	}
+	return s.buf.Bytes(), nil
+}
+
+// choose picks one of choices, of the form value:weight, randomly according
+// to their weights.
+func (s *state) choose(choices ...string) (string, error) {
+	values := make([]string, len(choices))
+	weights := make([]int, len(choices))
+	var total int
+	for i, c := range choices {
+		sep := strings.LastIndex(c, ":")
+		if sep < 0 {
+			return "", fmt.Errorf("choice %q must have the form value:weight", c)
+		}
+		weight, err := strconv.Atoi(c[sep+1:])
+		if err != nil || weight < 0 {
+			return "", fmt.Errorf("weight of choice %q must be a non negative integer", c)
+		}
+		values[i], weights[i] = c[:sep], weight
+		total += weight
+	}
+	if total == 0 {
+		return "", fmt.Errorf("choose needs a positive weight")
+	}
+	n := s.rand.Intn(total)
+	for i, w := range weights {
+		if n < w {
+			return values[i], nil
+		}
+		n -= w
+	}
+	return values[len(values)-1], nil
+}
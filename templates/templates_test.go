@@ -0,0 +1,155 @@
+package templates
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"testing"
+
+	"github.com/mercadolibre/pla/workload"
+	"github.com/valyala/fasthttp"
+)
+
+// render renders text as worker vu, failing the test if it fails.
+func render(t *testing.T, tmpl *Templates, vu int, text string) string {
+	got, err := tmpl.Render(vu, text)
+	if err != nil {
+		t.Fatalf("Could not render %q: %v", text, err)
+	}
+	return got
+}
+
+func TestCounters(t *testing.T) {
+	tmpl := New(0)
+	for _, c := range []struct {
+		vu   int
+		text string
+		want string
+	}{
+		{1, "/items/{{counter}}?again={{counter}}", "/items/1?again=1"},
+		{2, "/items/{{counter}}", "/items/2"},
+		{1, "{{vu}}-{{vuCounter}}", "1-1"},
+		{1, "{{vu}}-{{vuCounter}}", "1-2"},
+		{2, "{{vu}}-{{vuCounter}}-{{counter}}", "2-1-3"},
+		{3, `{"title": "{{"}`, `{"title": "{{"}`},
+		{3, "{{unknown}}", "{{unknown}}"},
+	} {
+		if got := render(t, tmpl, c.vu, c.text); got != c.want {
+			t.Errorf("Expected %q to render as %q, found %q", c.text, c.want, got)
+		}
+	}
+}
+
+func TestContains(t *testing.T) {
+	plain := fasthttp.AcquireRequest()
+	plain.SetRequestURI("http://example.com/items")
+	plain.SetBody([]byte(`{"name": "item"}`))
+	header := fasthttp.AcquireRequest()
+	header.SetRequestURI("http://example.com/items")
+	header.Header.Set("X-Request-ID", "{{counter}}")
+	if Contains(plain) {
+		t.Errorf("Expected a request without templates not to contain them")
+	}
+	if !Contains(plain, header) {
+		t.Errorf("Expected templates in headers to be found")
+	}
+}
+
+func TestChoose(t *testing.T) {
+	tmpl := New(1)
+	counts := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		counts[render(t, tmpl, 1, `{{choose "A:70" "B:20" "C:10" "D:0"}}`)]++
+	}
+	if len(counts) != 3 || counts["A"] < 600 || counts["B"] < 120 || counts["C"] < 50 || counts["A"] < counts["B"] || counts["B"] < counts["C"] {
+		t.Errorf("Choices were not picked according to their weights: %v", counts)
+	}
+
+	a, b := New(7), New(7)
+	for i := 0; i < 10; i++ {
+		if x, y := render(t, a, 2, `{{choose "A:1" "B:1"}}`), render(t, b, 2, `{{choose "A:1" "B:1"}}`); x != y {
+			t.Errorf("Choices with the same seed differ: %v and %v", x, y)
+		}
+	}
+
+	for _, text := range []string{`{{choose "A"}}`, `{{choose "A:x"}}`, `{{choose "A:0"}}`} {
+		if got, err := tmpl.Render(1, text); err == nil {
+			t.Errorf("Invalid choices %q rendered as %q", text, got)
+		}
+	}
+}
+
+func TestFake(t *testing.T) {
+	tmpl := New(1)
+	for _, c := range []struct {
+		text    string
+		pattern string
+	}{
+		{"{{name}}", `^[A-Z][a-z]+ [A-Z][a-z]+$`},
+		{"{{email}}", `^[a-z]+\.[a-z]+[0-9]+@[a-z.]+$`},
+		{"{{phone}}", `^\+1-[0-9]{3}-[0-9]{3}-[0-9]{4}$`},
+		{"{{address}}", `^[0-9]+ [A-Za-z ]+ [A-Za-z]+, [A-Za-z ]+ [0-9]{5}$`},
+		{"{{lorem 50}}", `^[a-z ]{50}$`},
+		{"{{lorem 0}}", `^$`},
+		{"{{randword}}.example.com", `^[a-z]{8,12}\.example\.com$`},
+	} {
+		got := render(t, tmpl, 1, c.text)
+		if !regexp.MustCompile(c.pattern).MatchString(got) {
+			t.Errorf("Expected %v to match %v, found %q", c.text, c.pattern, got)
+		}
+	}
+}
+
+func TestSignFilter(t *testing.T) {
+	tmpl := New(0)
+	for _, encoding := range []string{"hex", "base64"} {
+		if _, err := tmpl.SignFilter("X-Signature", "secret", "{{method}}{{path}}{{body}}", encoding); err != nil {
+			t.Errorf("A valid signature was not accepted: %v", err)
+		}
+	}
+	if _, err := tmpl.SignFilter("X-Signature", "secret", "{{method", "hex"); err == nil {
+		t.Errorf("An invalid payload was accepted")
+	}
+	if _, err := tmpl.SignFilter("X-Signature", "secret", "{{method}}", "base32"); err == nil {
+		t.Errorf("An unknown encoding was accepted")
+	}
+
+	sign, _ := tmpl.SignFilter("X-Signature", "secret", "{{method}}{{body}}", "hex")
+	req := fasthttp.AcquireRequest()
+	req.Header.SetMethod("POST")
+	req.SetBodyString(`{"id": 1}`)
+	if err := sign(1, req); err != nil {
+		t.Fatal(err)
+	}
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(`POST{"id": 1}`))
+	if got, want := string(req.Header.Peek("X-Signature")), hex.EncodeToString(mac.Sum(nil)); got != want {
+		t.Errorf("Expected signature %v, found %v", want, got)
+	}
+}
+
+func TestStore(t *testing.T) {
+	store := &workload.Store{}
+	tmpl := New(1).WithStore(store)
+	if got := render(t, tmpl, 1, `{{push "ids" counter}}{{push "ids" "b"}}created`); got != "created" {
+		t.Errorf("Pushing values rendered %q", got)
+	}
+	if got := render(t, tmpl, 2, `{{peek "ids"}}`); got != "1" && got != "b" {
+		t.Errorf("Peeked an unknown value %q", got)
+	}
+	popped := map[string]bool{render(t, tmpl, 2, `{{pop "ids"}}`): true, render(t, tmpl, 3, `{{pop "ids"}}`): true}
+	if !popped["1"] || !popped["b"] || store.Pool("ids").Len() != 0 {
+		t.Errorf("Expected to pop every pushed value, found %v", popped)
+	}
+	for _, text := range []string{`/items/{{pop "ids"}}`, `/items/{{peek "ids"}}`} {
+		if got, err := tmpl.Render(1, text); err == nil {
+			t.Errorf("Expected %v to fail on an empty pool, rendered %q", text, got)
+		}
+	}
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(`/items/{{pop "ids"}}`)
+	if err := tmpl.Filter()(1, req); err == nil {
+		t.Errorf("Expected a request popping an empty pool to fail")
+	}
+}
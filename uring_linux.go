@@ -0,0 +1,386 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// io_uring system calls, offsets and flags from linux/io_uring.h, which the
+// syscall package doesn't have.
+const (
+	sysIOUringSetup = 425
+	sysIOUringEnter = 426
+
+	uringOffSQRing = 0
+	uringOffCQRing = 0x8000000
+	uringOffSQEs   = 0x10000000
+
+	uringOpLinkTimeout = 15
+	uringOpSend        = 26
+	uringOpRecv        = 27
+
+	uringSQELink        = 1 << 2
+	uringEnterGetEvents = 1
+	uringFeatFastPoll   = 1 << 5
+
+	// uringEntries is the size of the rings of every connection, enough for
+	// a read or write and the timeout linked to it.
+	uringEntries = 2
+
+	// Tags of the completions of an operation and of its timeout.
+	uringOpTag      = 1
+	uringTimeoutTag = 2
+)
+
+var errUringClosed = errors.New("use of closed uring connection")
+
+type uringParams struct {
+	sqEntries    uint32
+	cqEntries    uint32
+	flags        uint32
+	sqThreadCPU  uint32
+	sqThreadIdle uint32
+	features     uint32
+	wqFD         uint32
+	resv         [3]uint32
+	sqOff        uringSQOffsets
+	cqOff        uringCQOffsets
+}
+
+type uringSQOffsets struct {
+	head, tail, ringMask, ringEntries, flags, dropped, array, resv1 uint32
+	userAddr                                                        uint64
+}
+
+type uringCQOffsets struct {
+	head, tail, ringMask, ringEntries, overflow, cqes, flags, resv1 uint32
+	userAddr                                                        uint64
+}
+
+type uringSQE struct {
+	opcode      uint8
+	flags       uint8
+	ioprio      uint16
+	fd          int32
+	off         uint64
+	addr        uint64
+	len         uint32
+	opFlags     uint32
+	userData    uint64
+	bufIndex    uint16
+	personality uint16
+	spliceFDIn  int32
+	pad         [2]uint64
+}
+
+type uringCQE struct {
+	userData uint64
+	res      int32
+	flags    uint32
+}
+
+type kernelTimespec struct {
+	sec, nsec int64
+}
+
+// ring is an io_uring instance with its submission and completion queues
+// mapped.
+type ring struct {
+	fd     int
+	sq     []byte
+	cq     []byte
+	sqes   []byte
+	params uringParams
+
+	// timeout is where the kernel reads the timeout of the operation in
+	// flight from, kept here so it doesn't move while it does.
+	timeout kernelTimespec
+}
+
+func newRing() (*ring, error) {
+	r := &ring{fd: -1}
+	fd, _, errno := syscall.Syscall(sysIOUringSetup, uringEntries, uintptr(unsafe.Pointer(&r.params)), 0)
+	if errno != 0 {
+		return nil, os.NewSyscallError("io_uring_setup", errno)
+	}
+	r.fd = int(fd)
+	p := &r.params
+	if p.features&uringFeatFastPoll == 0 {
+		r.close()
+		return nil, errors.New("io_uring needs Linux 5.7 or later")
+	}
+	mmap := func(offset int64, size uint32) ([]byte, error) {
+		return syscall.Mmap(r.fd, offset, int(size), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED|syscall.MAP_POPULATE)
+	}
+	var err error
+	if r.sq, err = mmap(uringOffSQRing, p.sqOff.array+p.sqEntries*4); err != nil {
+		r.close()
+		return nil, err
+	}
+	if r.cq, err = mmap(uringOffCQRing, p.cqOff.cqes+p.cqEntries*uint32(unsafe.Sizeof(uringCQE{}))); err != nil {
+		r.close()
+		return nil, err
+	}
+	if r.sqes, err = mmap(uringOffSQEs, p.sqEntries*uint32(unsafe.Sizeof(uringSQE{}))); err != nil {
+		r.close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func word(b []byte, offset uint32) *uint32 {
+	return (*uint32)(unsafe.Pointer(&b[offset]))
+}
+
+// push queues sqe. There is always room, as every operation waits for its
+// completions before the next one is queued.
+func (r *ring) push(sqe uringSQE) {
+	p := &r.params
+	tail := atomic.LoadUint32(word(r.sq, p.sqOff.tail))
+	i := tail & *word(r.sq, p.sqOff.ringMask)
+	*(*uringSQE)(unsafe.Pointer(&r.sqes[uintptr(i)*unsafe.Sizeof(sqe)])) = sqe
+	*word(r.sq, p.sqOff.array+i*4) = i
+	atomic.StoreUint32(word(r.sq, p.sqOff.tail), tail+1)
+}
+
+// wait submits the n queued entries and waits for their completions,
+// returning the result of the operation.
+func (r *ring) wait(n int) (int32, error) {
+	p := &r.params
+	head := word(r.cq, p.cqOff.head)
+	tail := word(r.cq, p.cqOff.tail)
+	mask := *word(r.cq, p.cqOff.ringMask)
+	var res int32
+	pending, done := n, 0
+	for {
+		for h := atomic.LoadUint32(head); h != atomic.LoadUint32(tail); h++ {
+			cqe := (*uringCQE)(unsafe.Pointer(&r.cq[p.cqOff.cqes+(h&mask)*uint32(unsafe.Sizeof(uringCQE{}))]))
+			if cqe.userData == uringOpTag {
+				res = cqe.res
+			}
+			done++
+			atomic.StoreUint32(head, h+1)
+		}
+		if done == n {
+			return res, nil
+		}
+		submitted, _, errno := syscall.Syscall6(sysIOUringEnter, uintptr(r.fd), uintptr(pending), uintptr(n-done), uringEnterGetEvents, 0, 0)
+		switch {
+		case errno == syscall.EINTR:
+		case errno != 0:
+			return 0, os.NewSyscallError("io_uring_enter", errno)
+		default:
+			pending -= int(submitted)
+		}
+	}
+}
+
+func (r *ring) close() {
+	for _, m := range [][]byte{r.sq, r.cq, r.sqes} {
+		if m != nil {
+			syscall.Munmap(m)
+		}
+	}
+	if r.fd >= 0 {
+		syscall.Close(r.fd)
+	}
+}
+
+// uringTimeout is the error of operations past their deadline.
+type uringTimeout struct{}
+
+func (uringTimeout) Error() string   { return "i/o timeout" }
+func (uringTimeout) Timeout() bool   { return true }
+func (uringTimeout) Temporary() bool { return true }
+
+// uringHalf reads or writes a connection through a ring of its own, so a
+// read waiting for a response doesn't hold back writing the next request,
+// as pipelined connections do.
+type uringHalf struct {
+	lock sync.Mutex
+	ring *ring
+
+	// deadline in Unix nanoseconds, 0 for none.
+	deadline int64
+}
+
+func (h *uringHalf) close() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.ring != nil {
+		h.ring.close()
+		h.ring = nil
+	}
+}
+
+// uringConn is a TCP connection which reads and writes through rings of its
+// own instead of the Go net poller. Operations block the thread running
+// them, and with a deadline, are linked to a timeout which cancels them.
+type uringConn struct {
+	read, write   uringHalf
+	fd            int
+	closed        int32
+	local, remote net.Addr
+}
+
+func (c *uringConn) do(h *uringHalf, op uint8, p []byte) (int, error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.ring == nil {
+		return 0, errUringClosed
+	}
+	deadline := atomic.LoadInt64(&h.deadline)
+	sqe := uringSQE{
+		opcode:   op,
+		fd:       int32(c.fd),
+		addr:     uint64(uintptr(unsafe.Pointer(&p[0]))),
+		len:      uint32(len(p)),
+		userData: uringOpTag,
+	}
+	if op == uringOpSend {
+		sqe.opFlags = syscall.MSG_NOSIGNAL
+	}
+	n := 1
+	if deadline != 0 {
+		d := time.Until(time.Unix(0, deadline))
+		if d <= 0 {
+			return 0, uringTimeout{}
+		}
+		h.ring.timeout = kernelTimespec{sec: int64(d / time.Second), nsec: int64(d % time.Second)}
+		sqe.flags |= uringSQELink
+		h.ring.push(sqe)
+		sqe = uringSQE{
+			opcode:   uringOpLinkTimeout,
+			addr:     uint64(uintptr(unsafe.Pointer(&h.ring.timeout))),
+			len:      1,
+			userData: uringTimeoutTag,
+		}
+		n = 2
+	}
+	h.ring.push(sqe)
+	res, err := h.ring.wait(n)
+	runtime.KeepAlive(p)
+	switch {
+	case err != nil:
+		return 0, err
+	case res == -int32(syscall.ECANCELED) && n == 2:
+		return 0, uringTimeout{}
+	case res < 0:
+		name := "read"
+		if op == uringOpSend {
+			name = "write"
+		}
+		return 0, &net.OpError{Op: name, Net: "tcp", Source: c.local, Addr: c.remote, Err: os.NewSyscallError(name, syscall.Errno(-res))}
+	}
+	return int(res), nil
+}
+
+func (c *uringConn) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	n, err := c.do(&c.read, uringOpRecv, p)
+	if err == nil && n == 0 {
+		return 0, io.EOF
+	}
+	return n, err
+}
+
+func (c *uringConn) Write(p []byte) (int, error) {
+	var written int
+	for written < len(p) {
+		n, err := c.do(&c.write, uringOpSend, p[written:])
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+func (c *uringConn) Close() error {
+	if !atomic.CompareAndSwapInt32(&c.closed, 0, 1) {
+		return errUringClosed
+	}
+	// Shut down first, so operations waiting on the rings return.
+	syscall.Shutdown(c.fd, syscall.SHUT_RDWR)
+	c.read.close()
+	c.write.close()
+	return syscall.Close(c.fd)
+}
+
+func (c *uringConn) LocalAddr() net.Addr  { return c.local }
+func (c *uringConn) RemoteAddr() net.Addr { return c.remote }
+
+func (c *uringConn) SetDeadline(t time.Time) error {
+	c.SetReadDeadline(t)
+	return c.SetWriteDeadline(t)
+}
+
+func (c *uringConn) SetReadDeadline(t time.Time) error {
+	atomic.StoreInt64(&c.read.deadline, unixNano(t))
+	return nil
+}
+
+func (c *uringConn) SetWriteDeadline(t time.Time) error {
+	atomic.StoreInt64(&c.write.deadline, unixNano(t))
+	return nil
+}
+
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// checkUring tells why the uring engine cannot run here, if it can't.
+func checkUring() error {
+	r, err := newRing()
+	if err != nil {
+		return fmt.Errorf("uring engine unavailable: %v", err)
+	}
+	r.close()
+	return nil
+}
+
+// dialUring returns a dialer which connects as usual, within timeout, and
+// then hands the socket to a ring for reads and another for writes.
+func dialUring(timeout time.Duration) func(addr string) (net.Conn, error) {
+	return func(addr string) (net.Conn, error) {
+		conn, err := net.DialTimeout("tcp", addr, timeout)
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+		// File leaves the socket in blocking mode, the ring polls it.
+		f, err := conn.(*net.TCPConn).File()
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		fd, err := syscall.Dup(int(f.Fd()))
+		if err != nil {
+			return nil, err
+		}
+		syscall.CloseOnExec(fd)
+		c := &uringConn{fd: fd, local: conn.LocalAddr(), remote: conn.RemoteAddr()}
+		if c.read.ring, err = newRing(); err == nil {
+			c.write.ring, err = newRing()
+		}
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+		return c, nil
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestUringConn(t *testing.T) {
+	if err := checkUring(); err != nil {
+		t.Skip(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf := make([]byte, 5)
+		if _, err := io.ReadFull(conn, buf); err == nil {
+			conn.Write(buf)
+		}
+		// Stays silent until closed, so the next read times out.
+		io.Copy(ioutil.Discard, conn)
+	}()
+
+	conn, err := dialUring(time.Second)(ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 5)
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "hello" {
+		t.Fatalf("Expected hello, got %q, %v", buf, err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	_, err = conn.Read(buf)
+	if ne, ok := err.(net.Error); !ok || !ne.Timeout() {
+		t.Errorf("Expected a timeout, got %v", err)
+	}
+
+	if err := conn.Close(); err != nil {
+		t.Error(err)
+	}
+	if _, err := conn.Read(buf); err != errUringClosed {
+		t.Errorf("Expected %v, got %v", errUringClosed, err)
+	}
+}
+
+func TestUringConnPipelining(t *testing.T) {
+	if err := checkUring(); err != nil {
+		t.Skip(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.Copy(conn, conn)
+	}()
+
+	conn, err := dialUring(time.Second)(ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	// The reader waits for responses before the writer sends the requests,
+	// as fasthttp's pipelined connections do.
+	read := make(chan error)
+	go func() {
+		buf := make([]byte, 10)
+		_, err := io.ReadFull(conn, buf)
+		if err == nil && string(buf) != "req1\nreq2\n" {
+			err = fmt.Errorf("read %q", buf)
+		}
+		read <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	for _, req := range []string{"req1\n", "req2\n"} {
+		if _, err := conn.Write([]byte(req)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := <-read; err != nil {
+		t.Errorf("Expected both responses while reading, got %v", err)
+	}
+}
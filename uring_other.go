@@ -0,0 +1,18 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+import (
+	"errors"
+	"net"
+	"time"
+)
+
+func checkUring() error {
+	return errors.New("uring engine is only supported on Linux")
+}
+
+func dialUring(timeout time.Duration) func(addr string) (net.Conn, error) {
+	return nil
+}
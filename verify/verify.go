@@ -0,0 +1,65 @@
+// Package verify checks that response bodies have the expected content.
+package verify
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/mercadolibre/pla/boomer"
+	"github.com/valyala/fasthttp"
+)
+
+// Matcher tells whether a response body has the expected content.
+type Matcher func(body []byte) bool
+
+// Hash returns a Matcher for bodies whose SHA-256 is the hex encoded digest.
+func Hash(digest string) (Matcher, error) {
+	expected, err := hex.DecodeString(strings.ToLower(digest))
+	if err != nil || len(expected) != sha256.Size {
+		return nil, fmt.Errorf("body hash must be a hex encoded SHA-256; input = %v", digest)
+	}
+	return func(body []byte) bool {
+		hash := sha256.Sum256(body)
+		return bytes.Equal(hash[:], expected)
+	}, nil
+}
+
+// Content returns a Matcher for bodies equal to expected. When expected is
+// JSON, bodies are compared as JSON, ignoring field order and formatting.
+func Content(expected []byte) Matcher {
+	var value interface{}
+	if err := json.Unmarshal(expected, &value); err != nil {
+		return func(body []byte) bool {
+			return bytes.Equal(body, expected)
+		}
+	}
+	return func(body []byte) bool {
+		var actual interface{}
+		if err := json.Unmarshal(body, &actual); err != nil {
+			return false
+		}
+		return reflect.DeepEqual(actual, value)
+	}
+}
+
+// Assertion returns a boomer.Assertion failing responses m does not match
+// with msg as their error.
+func (m Matcher) Assertion(msg string) boomer.Assertion {
+	err := errors.New(msg)
+	return func(resp *fasthttp.Response) error {
+		body, bodyErr := boomer.ResponseBody(resp)
+		if bodyErr != nil {
+			return bodyErr
+		}
+		if !m(body) {
+			return err
+		}
+		return nil
+	}
+}
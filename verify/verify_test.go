@@ -0,0 +1,48 @@
+package verify
+
+import (
+	"testing"
+)
+
+func TestHash(t *testing.T) {
+	m, err := Hash("2CF24DBA5FB0A30E26E83B2AC5B9E29E1B161E5C1FA7425E73043362938B9824")
+	if err != nil {
+		t.Fatalf("A valid hash was not parsed: %v", err)
+	}
+	if !m([]byte("hello")) {
+		t.Errorf("Expected the body to match its hash")
+	}
+	if m([]byte("hello!")) {
+		t.Errorf("Expected a different body not to match")
+	}
+	for _, digest := range []string{"", "xyz", "2cf24dba"} {
+		if _, err := Hash(digest); err == nil {
+			t.Errorf("An invalid hash passed parsing: %q", digest)
+		}
+	}
+}
+
+func TestContent(t *testing.T) {
+	m := Content([]byte(`{"id": 1, "tags": ["a", "b"], "item": {"title": "x", "price": 1.5}}`))
+	tests := []struct {
+		body  string
+		match bool
+	}{
+		{`{"item":{"price":1.5,"title":"x"},"tags":["a","b"],"id":1}`, true},
+		{`{"id": 1.0, "tags": ["a", "b"], "item": {"title": "x", "price": 1.5}}`, true},
+		{`{"id": 1, "tags": ["b", "a"], "item": {"title": "x", "price": 1.5}}`, false},
+		{`{"id": 2, "tags": ["a", "b"], "item": {"title": "x", "price": 1.5}}`, false},
+		{`{"id": 1, "tags": ["a", "b"]}`, false},
+		{`not json`, false},
+	}
+	for _, test := range tests {
+		if m([]byte(test.body)) != test.match {
+			t.Errorf("Expected %s to match %v", test.body, test.match)
+		}
+	}
+
+	plain := Content([]byte("OK\n"))
+	if !plain([]byte("OK\n")) || plain([]byte("OK")) {
+		t.Errorf("Expected bodies which are not JSON to be compared byte by byte")
+	}
+}
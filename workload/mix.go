@@ -0,0 +1,34 @@
+package workload
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+
+	"github.com/mercadolibre/pla/boomer"
+	"github.com/valyala/fasthttp"
+)
+
+// Mix builds a request mix from spec, paths are resolved against the URI of
+// base, which is also the template for every request, and {id} is replaced
+// by a random id between 1 and ids.
+func Mix(spec []Entry, base *fasthttp.Request, ids uint) ([]*boomer.WeightedRequest, error) {
+	var mix []*boomer.WeightedRequest
+	for _, e := range spec {
+		if e.Stage != "" {
+			continue
+		}
+		w, uri := e.request(base)
+		if e.HasID() {
+			if ids == 0 {
+				return nil, fmt.Errorf("ids must be positive to replace %v in %v", IDPlaceholder, e.Path)
+			}
+			w.Prepare = func(req *fasthttp.Request) error {
+				req.SetRequestURI(withID(uri, strconv.Itoa(rand.Intn(int(ids))+1)))
+				return nil
+			}
+		}
+		mix = append(mix, w)
+	}
+	return mix, nil
+}
@@ -0,0 +1,37 @@
+package workload
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestMix(t *testing.T) {
+	entries, _ := ParseSpec(strings.NewReader(spec))
+	base := fasthttp.AcquireRequest()
+	base.SetRequestURI("http://example.org/")
+	mix, err := Mix(entries, base, 10)
+	if err != nil {
+		t.Fatalf("Unexpected error building mix: %v", err)
+	}
+	post := mix[1]
+	if post.Label != "POST /items" || string(post.Request.Body()) != `{"title": "item"}` {
+		t.Errorf("POST was not built correctly, label: %v, body: %s", post.Label, post.Request.Body())
+	}
+	if post.Prepare != nil {
+		t.Errorf("A path without placeholders should not need preparing")
+	}
+
+	req := fasthttp.AcquireRequest()
+	mix[0].Request.CopyTo(req)
+	mix[0].Prepare(req)
+	uri := req.URI().String()
+	if !strings.HasPrefix(uri, "http://example.org/items/") || strings.Contains(uri, IDPlaceholder) {
+		t.Errorf("Placeholder was not replaced, found %v", uri)
+	}
+
+	if _, err := Mix(entries, base, 0); err == nil {
+		t.Errorf("Expected an error replacing ids without a range")
+	}
+}
@@ -0,0 +1,375 @@
+package workload
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/mercadolibre/pla/boomer"
+	"github.com/valyala/fasthttp"
+	"gopkg.in/yaml.v2"
+)
+
+// OpenAPI is the part of an OpenAPI 3 document needed to build requests.
+type OpenAPI struct {
+	Servers []struct {
+		URL string `json:"url" yaml:"url"`
+	} `json:"servers" yaml:"servers"`
+	Paths      map[string]*PathItem `json:"paths" yaml:"paths"`
+	Components struct {
+		Parameters      map[string]*Parameter      `json:"parameters" yaml:"parameters"`
+		Schemas         map[string]*Schema         `json:"schemas" yaml:"schemas"`
+		SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes" yaml:"securitySchemes"`
+	} `json:"components" yaml:"components"`
+	Security []map[string][]string `json:"security" yaml:"security"`
+}
+
+// PathItem holds the operations of a path.
+type PathItem struct {
+	Parameters []*Parameter `json:"parameters" yaml:"parameters"`
+	Get        *Operation   `json:"get" yaml:"get"`
+	Put        *Operation   `json:"put" yaml:"put"`
+	Post       *Operation   `json:"post" yaml:"post"`
+	Delete     *Operation   `json:"delete" yaml:"delete"`
+	Patch      *Operation   `json:"patch" yaml:"patch"`
+	Head       *Operation   `json:"head" yaml:"head"`
+	Options    *Operation   `json:"options" yaml:"options"`
+}
+
+// Operation is a single API operation. Method, Path and the path level
+// parameters are filled in when looked up by OpenAPI.Operation.
+type Operation struct {
+	ID          string                `json:"operationId" yaml:"operationId"`
+	Parameters  []*Parameter          `json:"parameters" yaml:"parameters"`
+	RequestBody *RequestBody          `json:"requestBody" yaml:"requestBody"`
+	Security    []map[string][]string `json:"security" yaml:"security"`
+
+	Method string `json:"-" yaml:"-"`
+	Path   string `json:"-" yaml:"-"`
+	spec   *OpenAPI
+}
+
+// Parameter is a path, query or header parameter.
+type Parameter struct {
+	Ref      string      `json:"$ref" yaml:"$ref"`
+	Name     string      `json:"name" yaml:"name"`
+	In       string      `json:"in" yaml:"in"`
+	Required bool        `json:"required" yaml:"required"`
+	Example  interface{} `json:"example" yaml:"example"`
+	Schema   *Schema     `json:"schema" yaml:"schema"`
+}
+
+// RequestBody is the body of an operation, only JSON ones are built.
+type RequestBody struct {
+	Required bool `json:"required" yaml:"required"`
+	Content  map[string]struct {
+		Example interface{} `json:"example" yaml:"example"`
+		Schema  *Schema     `json:"schema" yaml:"schema"`
+	} `json:"content" yaml:"content"`
+}
+
+// Schema constrains the values of a parameter or body.
+type Schema struct {
+	Ref        string             `json:"$ref" yaml:"$ref"`
+	Type       string             `json:"type" yaml:"type"`
+	Example    interface{}        `json:"example" yaml:"example"`
+	Default    interface{}        `json:"default" yaml:"default"`
+	Enum       []interface{}      `json:"enum" yaml:"enum"`
+	Minimum    *float64           `json:"minimum" yaml:"minimum"`
+	Maximum    *float64           `json:"maximum" yaml:"maximum"`
+	MinLength  *int               `json:"minLength" yaml:"minLength"`
+	MaxLength  *int               `json:"maxLength" yaml:"maxLength"`
+	Properties map[string]*Schema `json:"properties" yaml:"properties"`
+	Required   []string           `json:"required" yaml:"required"`
+	Items      *Schema            `json:"items" yaml:"items"`
+}
+
+// SecurityScheme is how an API authenticates requests.
+type SecurityScheme struct {
+	Type   string `json:"type" yaml:"type"`
+	Scheme string `json:"scheme" yaml:"scheme"`
+	Name   string `json:"name" yaml:"name"`
+	In     string `json:"in" yaml:"in"`
+}
+
+// ParseOpenAPI parses an OpenAPI 3 document in JSON or YAML.
+func ParseOpenAPI(data []byte) (*OpenAPI, error) {
+	spec := &OpenAPI{}
+	var err error
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		err = json.Unmarshal(data, spec)
+	} else {
+		err = yaml.Unmarshal(data, spec)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not parse openapi document: %v", err)
+	}
+	return spec, nil
+}
+
+// Server returns the URL of the first server of the API, if any.
+func (o *OpenAPI) Server() string {
+	if len(o.Servers) == 0 {
+		return ""
+	}
+	return o.Servers[0].URL
+}
+
+// Operation looks up the operation with the given operationId.
+func (o *OpenAPI) Operation(id string) (*Operation, error) {
+	paths := make([]string, 0, len(o.Paths))
+	for path := range o.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		item := o.Paths[path]
+		for method, op := range map[string]*Operation{
+			"GET": item.Get, "PUT": item.Put, "POST": item.Post, "DELETE": item.Delete,
+			"PATCH": item.Patch, "HEAD": item.Head, "OPTIONS": item.Options,
+		} {
+			if op == nil || op.ID != id {
+				continue
+			}
+			found := *op
+			found.Method, found.Path, found.spec = method, path, o
+			found.Parameters = nil
+			// Operation parameters override the path ones with their name.
+			seen := make(map[string]bool)
+			for _, p := range append(op.Parameters, item.Parameters...) {
+				p, err := o.parameter(p)
+				if err != nil {
+					return nil, err
+				}
+				if !seen[p.In+" "+p.Name] {
+					seen[p.In+" "+p.Name] = true
+					found.Parameters = append(found.Parameters, p)
+				}
+			}
+			if found.Security == nil {
+				found.Security = o.Security
+			}
+			return &found, nil
+		}
+	}
+	return nil, fmt.Errorf("operation %q not found", id)
+}
+
+func (o *OpenAPI) parameter(p *Parameter) (*Parameter, error) {
+	if p.Ref == "" {
+		return p, nil
+	}
+	resolved, ok := o.Components.Parameters[strings.TrimPrefix(p.Ref, "#/components/parameters/")]
+	if !ok {
+		return nil, fmt.Errorf("parameter %v not found", p.Ref)
+	}
+	return resolved, nil
+}
+
+func (o *OpenAPI) schema(s *Schema) *Schema {
+	for depth := 0; s != nil && s.Ref != "" && depth < 10; depth++ {
+		s = o.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	return s
+}
+
+// Request builds the request of the operation on top of base, whose URI is
+// the server's. Required parameters and the body get their examples or,
+// lacking them, values valid for their schemas, and token authenticates it
+// when the operation uses bearer, OAuth or API key security. With fuzz,
+// parameters and body get new random values within their schemas on every
+// request.
+func (op *Operation) Request(base *fasthttp.Request, token string, fuzz bool) *boomer.WeightedRequest {
+	prefix := strings.TrimSuffix(string(base.URI().Scheme())+"://"+string(base.URI().Host())+string(base.URI().Path()), "/")
+	w := &boomer.WeightedRequest{
+		Request: fasthttp.AcquireRequest(),
+		Weight:  1,
+		Label:   op.ID,
+	}
+	base.CopyTo(w.Request)
+	w.Request.Header.SetMethod(op.Method)
+	op.build(w.Request, prefix, token, false)
+	if fuzz {
+		w.Prepare = func(req *fasthttp.Request) error {
+			op.build(req, prefix, token, true)
+			return nil
+		}
+	}
+	return w
+}
+
+func (op *Operation) build(req *fasthttp.Request, prefix, token string, fuzz bool) {
+	path := op.Path
+	query := url.Values{}
+	for _, p := range op.Parameters {
+		if !p.Required && p.In != "path" {
+			continue
+		}
+		v := fmt.Sprint(op.value(p.Example, p.Schema, fuzz, 0))
+		switch p.In {
+		case "path":
+			path = strings.Replace(path, "{"+p.Name+"}", url.PathEscape(v), -1)
+		case "query":
+			query.Set(p.Name, v)
+		case "header":
+			req.Header.Set(p.Name, v)
+		}
+	}
+	if token != "" {
+		op.authenticate(req, query, token)
+	}
+	uri := prefix + path
+	if len(query) > 0 {
+		uri += "?" + query.Encode()
+	}
+	req.SetRequestURI(uri)
+	if op.RequestBody != nil {
+		if content, ok := op.RequestBody.Content["application/json"]; ok {
+			body, _ := json.Marshal(op.value(content.Example, content.Schema, fuzz, 0))
+			req.Header.SetContentType("application/json")
+			req.SetBody(body)
+		}
+	}
+}
+
+// authenticate adds token as the first security requirement of the
+// operation asks for it.
+func (op *Operation) authenticate(req *fasthttp.Request, query url.Values, token string) {
+	if len(op.Security) == 0 {
+		return
+	}
+	for name := range op.Security[0] {
+		scheme, ok := op.spec.Components.SecuritySchemes[name]
+		if !ok {
+			continue
+		}
+		switch {
+		case scheme.Type == "apiKey" && scheme.In == "header":
+			req.Header.Set(scheme.Name, token)
+		case scheme.Type == "apiKey" && scheme.In == "query":
+			query.Set(scheme.Name, token)
+		case scheme.Type == "http" && strings.EqualFold(scheme.Scheme, "bearer"),
+			scheme.Type == "oauth2", scheme.Type == "openIdConnect":
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+}
+
+// value returns example when set, otherwise a value valid for s, random
+// within its constraints with fuzz. Recursive schemas are cut at depth:
+// past maxSchemaDepth objects only have their required properties and
+// arrays are empty, past twice that nothing is nested anymore.
+func (op *Operation) value(example interface{}, s *Schema, fuzz bool, depth int) interface{} {
+	if depth > 2*maxSchemaDepth {
+		return nil
+	}
+	s = op.spec.schema(s)
+	if !fuzz && example != nil {
+		return normalize(example)
+	}
+	if s == nil {
+		return "example"
+	}
+	if !fuzz {
+		switch {
+		case s.Example != nil:
+			return normalize(s.Example)
+		case s.Default != nil:
+			return normalize(s.Default)
+		}
+	}
+	if len(s.Enum) > 0 {
+		if fuzz {
+			return normalize(s.Enum[rand.Intn(len(s.Enum))])
+		}
+		return normalize(s.Enum[0])
+	}
+	switch s.Type {
+	case "integer", "number":
+		min, max := 1.0, 1000.0
+		if s.Minimum != nil {
+			min = *s.Minimum
+		}
+		if s.Maximum != nil {
+			max = *s.Maximum
+		}
+		if !fuzz || max <= min {
+			return int64(min)
+		}
+		if s.Type == "number" {
+			return min + rand.Float64()*(max-min)
+		}
+		return int64(min) + rand.Int63n(int64(max-min)+1)
+	case "boolean":
+		return !fuzz || rand.Intn(2) == 1
+	case "array":
+		if depth >= maxSchemaDepth {
+			return []interface{}{}
+		}
+		return []interface{}{op.value(nil, s.Items, fuzz, depth+1)}
+	case "object":
+		object := make(map[string]interface{})
+		required := s.Required
+		if len(required) == 0 && depth < maxSchemaDepth {
+			for name := range s.Properties {
+				required = append(required, name)
+			}
+		}
+		for _, name := range required {
+			if prop, ok := s.Properties[name]; ok {
+				object[name] = op.value(nil, prop, fuzz, depth+1)
+			}
+		}
+		return object
+	}
+	return randomString(s, fuzz)
+}
+
+// maxSchemaDepth is how deep schemas are followed before only their
+// required properties are.
+const maxSchemaDepth = 8
+
+const letters = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+func randomString(s *Schema, fuzz bool) string {
+	min, max := 1, 16
+	if s.MinLength != nil {
+		min = *s.MinLength
+	}
+	if s.MaxLength != nil {
+		max = *s.MaxLength
+	}
+	if max < min {
+		max = min
+	}
+	if !fuzz {
+		return strings.Repeat("a", min)
+	}
+	b := make([]byte, min+rand.Intn(max-min+1))
+	for i := range b {
+		b[i] = letters[rand.Intn(len(letters))]
+	}
+	return string(b)
+}
+
+// normalize turns the maps YAML decodes objects into into JSON friendly
+// ones.
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[fmt.Sprint(k)] = normalize(e)
+		}
+		return m
+	case []interface{}:
+		for i, e := range v {
+			v[i] = normalize(e)
+		}
+	}
+	return v
+}
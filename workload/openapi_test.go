@@ -0,0 +1,113 @@
+package workload
+
+import (
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+const openapi = `{
+  "openapi": "3.0.0",
+  "servers": [{"url": "http://api.example.com/v1"}],
+  "security": [{"bearer": []}],
+  "components": {
+    "securitySchemes": {"bearer": {"type": "http", "scheme": "bearer"}},
+    "schemas": {"Size": {"type": "integer", "minimum": 1, "maximum": 50}}
+  },
+  "paths": {
+    "/items/{id}": {
+      "parameters": [{"name": "id", "in": "path", "required": true, "example": 42}],
+      "get": {
+        "operationId": "getItem",
+        "parameters": [
+          {"name": "size", "in": "query", "required": true, "schema": {"$ref": "#/components/schemas/Size"}},
+          {"name": "sort", "in": "query", "schema": {"type": "string"}}
+        ]
+      }
+    }
+  }
+}`
+
+func TestOpenAPIOperation(t *testing.T) {
+	spec, err := ParseOpenAPI([]byte(openapi))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec.Server() != "http://api.example.com/v1" {
+		t.Errorf("Expected the first server, found %q", spec.Server())
+	}
+	op, err := spec.Operation("getItem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if op.Method != "GET" || op.Path != "/items/{id}" || len(op.Parameters) != 3 {
+		t.Errorf("Expected GET /items/{id} with its path parameter, found %s %s with %d parameters", op.Method, op.Path, len(op.Parameters))
+	}
+	if _, err := spec.Operation("deleteItem"); err == nil {
+		t.Errorf("Expected an unknown operation not to be found")
+	}
+
+	base := fasthttp.AcquireRequest()
+	base.SetRequestURI(spec.Server())
+	w := op.Request(base, "secret", false)
+	if uri := w.Request.URI().String(); uri != "http://api.example.com/v1/items/42?size=1" {
+		t.Errorf("Expected the example id and minimum size, found %s", uri)
+	}
+	if auth := string(w.Request.Header.Peek("Authorization")); auth != "Bearer secret" {
+		t.Errorf("Expected a bearer token, found %q", auth)
+	}
+}
+
+func TestOpenAPIFuzz(t *testing.T) {
+	spec, _ := ParseOpenAPI([]byte(openapi))
+	op, _ := spec.Operation("getItem")
+	size := op.Parameters[0].Schema
+	seen := make(map[int64]bool)
+	for i := 0; i < 1000; i++ {
+		v := op.value(nil, size, true, 0).(int64)
+		if v < 1 || v > 50 {
+			t.Fatalf("Expected fuzzed sizes within 1 and 50, found %d", v)
+		}
+		seen[v] = true
+	}
+	if len(seen) < 10 {
+		t.Errorf("Expected fuzzing to vary the size, found %d values", len(seen))
+	}
+}
+
+func TestOpenAPIRecursiveSchema(t *testing.T) {
+	spec, err := ParseOpenAPI([]byte(`{
+  "openapi": "3.0.0",
+  "components": {
+    "schemas": {
+      "Node": {
+        "type": "object",
+        "required": ["id", "parent"],
+        "properties": {
+          "id": {"type": "integer"},
+          "parent": {"$ref": "#/components/schemas/Node"},
+          "children": {"type": "array", "items": {"$ref": "#/components/schemas/Node"}}
+        }
+      }
+    }
+  },
+  "paths": {"/nodes": {"post": {"operationId": "addNode"}}}
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	op, _ := spec.Operation("addNode")
+	node := &Schema{Ref: "#/components/schemas/Node"}
+	v, ok := op.value(nil, node, false, 0).(map[string]interface{})
+	depth := 0
+	for ok {
+		if _, ok := v["id"]; !ok {
+			t.Fatalf("Expected required properties at depth %d, found %v", depth, v)
+		}
+		v, ok = v["parent"].(map[string]interface{})
+		depth++
+	}
+	if depth <= maxSchemaDepth || depth > 2*maxSchemaDepth+1 {
+		t.Errorf("Expected recursion to stop past the maximum depth, stopped at %d", depth)
+	}
+}
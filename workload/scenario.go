@@ -0,0 +1,319 @@
+package workload
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mercadolibre/pla/boomer"
+	"github.com/valyala/fasthttp"
+)
+
+// ParseScenario reads a scenario, where every line is a step of the form
+//
+//	[name:] [budget] [onFailure=abort|continue|retry[:N]] [save=pool[:field]] METHOD path [body]
+//
+// sent in order, where budget is the latency the step should stay within,
+// ex: 200ms, and onFailure is what the iteration does when the step fails:
+// send the next steps anyway, the default, end the iteration or send the
+// step again up to N times, once by default, ending the iteration when all
+// of them fail. save adds the id of the resources the step creates to a
+// pool of the store given to Scenarios, read as Workflow does from field,
+// id by default. Lines of the form
+//
+//	if status==code|status!=code then name [else name]
+//	goto name
+//
+// make the iteration follow the step before them with a later named step
+// of its scenario, or end when the name is end, by its response. Several
+// scenarios are mixed starting each of them with a line
+//
+//	scenario name [share]
+//
+// where share is its part of the iterations, ex: 80% or 80. Setup and
+// teardown lines are read as in ParseSpec, blank lines and lines starting
+// with # are ignored.
+func ParseScenario(r io.Reader) ([]Entry, error) {
+	var spec []Entry
+	var steps, unnamed bool
+	var name string
+	weight := uint(1)
+	names := make(map[string]bool)
+	var flow scenarioFlow
+	scanner := bufio.NewScanner(r)
+	var line int
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if strings.HasPrefix(text, "scenario ") {
+			if unnamed {
+				return nil, fmt.Errorf("steps must belong to a named scenario once there are several; line = %v", line)
+			}
+			if name != "" && !steps {
+				return nil, fmt.Errorf("scenario %v has no steps; line = %v", name, line)
+			}
+			if err := flow.resolve(spec); err != nil {
+				return nil, err
+			}
+			var err error
+			if name, weight, err = parseScenario(text); err != nil {
+				return nil, fmt.Errorf("%v; line = %v", err, line)
+			}
+			if names[name] {
+				return nil, fmt.Errorf("scenario %v is repeated; line = %v", name, line)
+			}
+			names[name] = true
+			steps = false
+			flow = scenarioFlow{}
+			continue
+		}
+		if strings.HasPrefix(text, "if ") || strings.HasPrefix(text, "goto ") {
+			if len(flow.steps) == 0 {
+				return nil, fmt.Errorf("branches must follow a step; line = %v", line)
+			}
+			if err := flow.parseBranch(text, line); err != nil {
+				return nil, fmt.Errorf("%v; line = %v", err, line)
+			}
+			continue
+		}
+		e := Entry{Weight: weight, Scenario: name}
+		fields := strings.SplitN(text, " ", 2)
+		if len(fields) == 2 {
+			switch fields[0] {
+			case StageSetup, StageTeardown:
+				e.Stage, e.Weight, e.Scenario = fields[0], 0, ""
+				text = fields[1]
+			default:
+				// Methods never end with a colon, nor names have the = of
+				// options.
+				if strings.HasSuffix(fields[0], ":") && !strings.Contains(fields[0], "=") {
+					e.Name = strings.TrimSuffix(fields[0], ":")
+					text = fields[1]
+				}
+				var err error
+				if text, err = e.parseOptions(text); err != nil {
+					return nil, fmt.Errorf("%v; line = %v", err, line)
+				}
+			}
+		}
+		if err := e.parseRequest(text); err != nil {
+			return nil, fmt.Errorf("%v; line = %v", err, line)
+		}
+		spec = append(spec, e)
+		if e.Stage == "" {
+			if err := flow.add(e.Name, len(spec)-1); err != nil {
+				return nil, fmt.Errorf("%v; line = %v", err, line)
+			}
+			steps = true
+			unnamed = unnamed || name == ""
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !steps {
+		return nil, fmt.Errorf("scenario %v has no steps", name)
+	}
+	if err := flow.resolve(spec); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// scenarioFlow holds the steps of the scenario being parsed, by name, and
+// their branches until the names they go to are known.
+type scenarioFlow struct {
+	steps    []int
+	names    map[string]int
+	branches []pendingBranch
+}
+
+type pendingBranch struct {
+	step, line int
+	next       string
+	branch     boomer.Branch
+}
+
+// add adds the step at index i of the spec, named name, if not empty.
+func (f *scenarioFlow) add(name string, i int) error {
+	f.steps = append(f.steps, i)
+	if name == "" {
+		return nil
+	}
+	if name == "end" {
+		return fmt.Errorf("end can't name a step")
+	}
+	if f.names == nil {
+		f.names = make(map[string]int)
+	}
+	if _, ok := f.names[name]; ok {
+		return fmt.Errorf("step %v is repeated", name)
+	}
+	f.names[name] = len(f.steps)
+	return nil
+}
+
+// parseBranch parses an if or goto line of the last step.
+func (f *scenarioFlow) parseBranch(text string, line int) error {
+	step := len(f.steps)
+	fields := strings.Fields(text)
+	if fields[0] == "goto" {
+		if len(fields) != 2 {
+			return fmt.Errorf("expected goto name")
+		}
+		f.branches = append(f.branches, pendingBranch{step: step, line: line, next: fields[1]})
+		return nil
+	}
+	if (len(fields) != 4 && len(fields) != 6) || fields[2] != "then" || (len(fields) == 6 && fields[4] != "else") {
+		return fmt.Errorf("expected if condition then name [else name]")
+	}
+	var br boomer.Branch
+	cond := strings.TrimPrefix(fields[1], "status")
+	switch {
+	case strings.HasPrefix(cond, "=="):
+	case strings.HasPrefix(cond, "!="):
+		br.Not = true
+	default:
+		return fmt.Errorf("condition must be status==code or status!=code")
+	}
+	status, err := strconv.Atoi(cond[2:])
+	if err != nil || status <= 0 {
+		return fmt.Errorf("status must be a positive integer")
+	}
+	br.Status = status
+	f.branches = append(f.branches, pendingBranch{step: step, line: line, next: fields[3], branch: br})
+	if len(fields) == 6 {
+		f.branches = append(f.branches, pendingBranch{step: step, line: line, next: fields[5]})
+	}
+	return nil
+}
+
+// resolve sets the branches of the steps in spec, once all the names of
+// the scenario are known. Branches only go forward, so iterations always
+// end.
+func (f *scenarioFlow) resolve(spec []Entry) error {
+	for _, p := range f.branches {
+		if p.next != "end" {
+			next, ok := f.names[p.next]
+			if !ok {
+				return fmt.Errorf("step %v not found; line = %v", p.next, p.line)
+			}
+			if next <= p.step {
+				return fmt.Errorf("branches must go to a later step; line = %v", p.line)
+			}
+			p.branch.Next = next
+		}
+		e := &spec[f.steps[p.step-1]]
+		e.Branches = append(e.Branches, p.branch)
+	}
+	return nil
+}
+
+// parseOptions parses the budget, onFailure and save options which may
+// precede the method of a step, returning the rest of text.
+func (e *Entry) parseOptions(text string) (string, error) {
+	for {
+		fields := strings.SplitN(text, " ", 2)
+		if len(fields) < 2 {
+			return text, nil
+		}
+		// Methods never parse as durations nor have =.
+		if strings.HasPrefix(fields[0], "onFailure=") {
+			if err := e.parseOnFailure(strings.TrimPrefix(fields[0], "onFailure=")); err != nil {
+				return "", err
+			}
+		} else if strings.HasPrefix(fields[0], "save=") {
+			save := strings.SplitN(strings.TrimPrefix(fields[0], "save="), ":", 2)
+			e.Save, e.SaveField = save[0], "id"
+			if len(save) == 2 {
+				e.SaveField = save[1]
+			}
+			if e.Save == "" || e.SaveField == "" {
+				return "", fmt.Errorf("expected save=pool[:field]")
+			}
+		} else if budget, err := time.ParseDuration(fields[0]); err == nil {
+			if budget <= 0 {
+				return "", fmt.Errorf("budget must be positive")
+			}
+			e.Budget = budget
+		} else {
+			return text, nil
+		}
+		text = fields[1]
+	}
+}
+
+// parseOnFailure parses abort, continue or retry[:N], retrying once when N
+// is missing.
+func (e *Entry) parseOnFailure(value string) error {
+	switch {
+	case value == "continue":
+		e.OnFailure = boomer.OnFailureContinue
+	case value == "abort":
+		e.OnFailure = boomer.OnFailureAbort
+	case value == "retry" || strings.HasPrefix(value, "retry:"):
+		e.OnFailure, e.Retries = boomer.OnFailureRetry, 1
+		if n := strings.TrimPrefix(value, "retry"); n != "" {
+			retries, err := strconv.ParseUint(n[1:], 10, 32)
+			if err != nil || retries == 0 {
+				return fmt.Errorf("retries must be a positive integer")
+			}
+			e.Retries = uint(retries)
+		}
+	default:
+		return fmt.Errorf("onFailure must be abort, continue or retry[:N]")
+	}
+	return nil
+}
+
+// parseScenario parses the name and share of a scenario line.
+func parseScenario(text string) (string, uint, error) {
+	fields := strings.Fields(text)
+	if len(fields) < 2 || len(fields) > 3 {
+		return "", 0, fmt.Errorf("expected scenario name and optional share")
+	}
+	if len(fields) == 2 {
+		return fields[1], 1, nil
+	}
+	share, err := strconv.ParseUint(strings.TrimSuffix(fields[2], "%"), 10, 32)
+	if err != nil || share == 0 {
+		return "", 0, fmt.Errorf("share must be a positive integer or percentage")
+	}
+	return fields[1], uint(share), nil
+}
+
+// Scenarios builds the scenarios of the steps of spec, in the order they
+// appear, as Mix builds its requests. Steps of named scenarios are labeled
+// with the name, the ones saving ids add them to the pools of store.
+func Scenarios(spec []Entry, base *fasthttp.Request, ids uint, store *Store) ([]*boomer.Scenario, error) {
+	steps, err := Mix(spec, base, ids)
+	if err != nil {
+		return nil, err
+	}
+	var scenarios []*boomer.Scenario
+	var i int
+	for _, e := range spec {
+		if e.Stage != "" {
+			continue
+		}
+		if len(scenarios) == 0 || scenarios[len(scenarios)-1].Name != e.Scenario {
+			scenarios = append(scenarios, &boomer.Scenario{Name: e.Scenario, Weight: e.Weight})
+		}
+		s := scenarios[len(scenarios)-1]
+		if e.Save != "" {
+			steps[i].Capture = captureID(store.Pool(e.Save), e.SaveField)
+		}
+		if s.Name != "" {
+			steps[i].Label = s.Name + ": " + steps[i].Label
+		}
+		s.Steps = append(s.Steps, steps[i])
+		i++
+	}
+	return scenarios, nil
+}
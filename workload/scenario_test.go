@@ -0,0 +1,185 @@
+package workload
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mercadolibre/pla/boomer"
+	"github.com/valyala/fasthttp"
+)
+
+const scenario = `
+setup POST /users {"name": "test"}
+200ms GET /items
+POST /cart {"item": 1}
+1s POST /checkout
+teardown DELETE /users/test
+`
+
+func TestParseScenario(t *testing.T) {
+	spec, err := ParseScenario(strings.NewReader(scenario))
+	if err != nil {
+		t.Fatalf("A valid scenario was not parsed correctly: %v", err)
+	}
+	if len(spec) != 5 || spec[0].Stage != StageSetup || spec[4].Stage != StageTeardown {
+		t.Fatalf("Stages were not parsed correctly: %v", spec)
+	}
+	items, cart, checkout := spec[1], spec[2], spec[3]
+	if items.Budget != 200*time.Millisecond || items.Method != "GET" || items.Path != "/items" {
+		t.Errorf("A step with budget was not parsed correctly: %v", items)
+	}
+	if cart.Budget != 0 || cart.Method != "POST" || cart.Body != `{"item": 1}` {
+		t.Errorf("A step without budget was not parsed correctly: %v", cart)
+	}
+	if checkout.Budget != time.Second {
+		t.Errorf("Budget was not parsed correctly: %v", checkout)
+	}
+	for _, s := range []string{"", "setup POST /users", "GET items", "0s GET /items", "200ms"} {
+		if _, err := ParseScenario(strings.NewReader(s)); err == nil {
+			t.Errorf("An invalid scenario passed parsing: %q", s)
+		}
+	}
+}
+
+const scenarios = `
+setup POST /users {"name": "test"}
+scenario browse 80%
+GET /items
+GET /items/1
+scenario checkout 20%
+POST /cart {"item": 1}
+500ms POST /checkout
+`
+
+func TestParseScenarios(t *testing.T) {
+	spec, err := ParseScenario(strings.NewReader(scenarios))
+	if err != nil {
+		t.Fatalf("Valid scenarios were not parsed correctly: %v", err)
+	}
+	if len(spec) != 5 || spec[0].Stage != StageSetup || spec[0].Scenario != "" {
+		t.Fatalf("Scenarios were not parsed correctly: %v", spec)
+	}
+	if spec[1].Scenario != "browse" || spec[1].Weight != 80 || spec[4].Scenario != "checkout" || spec[4].Weight != 20 || spec[4].Budget != 500*time.Millisecond {
+		t.Errorf("Steps were not assigned to their scenarios: %v", spec)
+	}
+
+	base := fasthttp.AcquireRequest()
+	base.SetRequestURI("http://example.org/")
+	built, err := Scenarios(spec, base, 10, &Store{})
+	if err != nil {
+		t.Fatalf("Scenarios were not built: %v", err)
+	}
+	if len(built) != 2 || built[0].Name != "browse" || built[0].Weight != 80 || len(built[0].Steps) != 2 || len(built[1].Steps) != 2 {
+		t.Fatalf("Scenarios were not built correctly: %+v", built)
+	}
+	if built[1].Steps[1].Label != "checkout: POST /checkout" || built[1].Steps[1].Budget != 500*time.Millisecond {
+		t.Errorf("Steps were not labeled with their scenario: %+v", built[1].Steps[1])
+	}
+
+	for _, s := range []string{
+		"scenario browse\n",
+		"scenario browse\nscenario checkout\nPOST /cart",
+		"GET /items\nscenario browse\nGET /items",
+		"scenario browse 0%\nGET /items",
+		"scenario browse\nGET /\nscenario browse\nGET /",
+	} {
+		if _, err := ParseScenario(strings.NewReader(s)); err == nil {
+			t.Errorf("Invalid scenarios passed parsing: %q", s)
+		}
+	}
+}
+
+func TestParseOnFailure(t *testing.T) {
+	spec, err := ParseScenario(strings.NewReader(`
+onFailure=abort POST /login
+200ms onFailure=retry:3 GET /items
+onFailure=retry POST /cart
+GET /checkout
+`))
+	if err != nil {
+		t.Fatalf("A valid scenario was not parsed correctly: %v", err)
+	}
+	if spec[0].OnFailure != boomer.OnFailureAbort || spec[0].Method != "POST" {
+		t.Errorf("Abort was not parsed correctly: %v", spec[0])
+	}
+	if spec[1].OnFailure != boomer.OnFailureRetry || spec[1].Retries != 3 || spec[1].Budget != 200*time.Millisecond {
+		t.Errorf("Retries with budget were not parsed correctly: %v", spec[1])
+	}
+	if spec[2].Retries != 1 || spec[3].OnFailure != boomer.OnFailureContinue {
+		t.Errorf("Defaults were not parsed correctly: %v", spec[2:])
+	}
+	for _, s := range []string{"onFailure=skip GET /items", "onFailure=retry:0 GET /items", "onFailure=abort"} {
+		if _, err := ParseScenario(strings.NewReader(s)); err == nil {
+			t.Errorf("An invalid scenario passed parsing: %q", s)
+		}
+	}
+}
+
+func TestParseBranches(t *testing.T) {
+	spec, err := ParseScenario(strings.NewReader(`
+GET /items/1
+if status==404 then populate else fetch
+populate: PUT /items/1 {"name": "test"}
+goto end
+fetch: 200ms GET /items/1/details
+if status!=200 then end
+GET /items/1/reviews
+`))
+	if err != nil {
+		t.Fatalf("A valid scenario was not parsed correctly: %v", err)
+	}
+	if len(spec) != 4 || spec[1].Name != "populate" || spec[2].Name != "fetch" || spec[2].Budget != 200*time.Millisecond {
+		t.Fatalf("Named steps were not parsed correctly: %v", spec)
+	}
+	expected := [][]boomer.Branch{
+		{{Status: 404, Next: 2}, {Next: 3}},
+		{{Next: 0}},
+		{{Status: 200, Not: true, Next: 0}},
+		nil,
+	}
+	for i, e := range spec {
+		if fmt.Sprint(e.Branches) != fmt.Sprint(expected[i]) {
+			t.Errorf("Expected branches %v of step %d, found %v", expected[i], i+1, e.Branches)
+		}
+	}
+	for _, s := range []string{
+		"if status==404 then end\nGET /items",
+		"GET /items\nif status==404 then missing",
+		"a: GET /items\nif status==404 then a",
+		"GET /items\nif status>404 then end",
+		"GET /items\nif status==404 end",
+		"GET /items\ngoto",
+		"a: GET /items\na: GET /items",
+		"scenario a\nb: GET /items\nscenario c\nGET /items\ngoto b",
+	} {
+		if _, err := ParseScenario(strings.NewReader(s)); err == nil {
+			t.Errorf("An invalid scenario passed parsing: %q", s)
+		}
+	}
+}
+
+func TestParseSave(t *testing.T) {
+	spec, err := ParseScenario(strings.NewReader("save=users POST /users\nsave=orders:data.id POST /orders\nGET /users"))
+	if err != nil {
+		t.Fatalf("A valid scenario was not parsed correctly: %v", err)
+	}
+	if spec[0].Save != "users" || spec[0].SaveField != "id" || spec[1].Save != "orders" || spec[1].SaveField != "data.id" || spec[2].Save != "" {
+		t.Errorf("Saves were not parsed correctly: %v", spec)
+	}
+	base := fasthttp.AcquireRequest()
+	base.SetRequestURI("http://example.org/")
+	built, err := Scenarios(spec, base, 0, &Store{})
+	if err != nil {
+		t.Fatalf("Scenarios were not built: %v", err)
+	}
+	if steps := built[0].Steps; steps[0].Capture == nil || steps[1].Capture == nil || steps[2].Capture != nil {
+		t.Errorf("Only steps saving ids should capture them")
+	}
+	for _, s := range []string{"save= POST /users", "save=users: POST /users"} {
+		if _, err := ParseScenario(strings.NewReader(s)); err == nil {
+			t.Errorf("An invalid scenario passed parsing: %q", s)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+// Package workload builds request mixes for Boomer from workload specs.
+package workload
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mercadolibre/pla/boomer"
+	"github.com/valyala/fasthttp"
+)
+
+// IDPlaceholder is replaced in paths by a resource id.
+const IDPlaceholder = "{id}"
+
+// Stages of the entries sent once around the test instead of as part of
+// the load.
+const (
+	StageSetup    = "setup"
+	StageTeardown = "teardown"
+)
+
+// Entry is a single line of a workload spec.
+type Entry struct {
+	// Stage is StageSetup or StageTeardown for entries sent once before or
+	// after the test, empty for the load ones.
+	Stage string
+
+	Weight uint
+	Method string
+	Path   string
+	Body   string
+
+	// Interval, when set, is the minimum time between two requests of the
+	// entry.
+	Interval time.Duration
+
+	// Budget, when set, is the latency the entry should stay within as a
+	// step of a scenario. Scenario is the name of the scenario of the step,
+	// whose share of the iterations is then Weight.
+	Budget   time.Duration
+	Scenario string
+
+	// OnFailure is what the iteration does when the entry fails as a step
+	// of a scenario, Retries how many times it is sent again when retried.
+	OnFailure boomer.OnFailure
+	Retries   uint
+
+	// Name names the entry as a step of a scenario, for Branches to pick
+	// which step follows it.
+	Name     string
+	Branches []boomer.Branch
+
+	// Save is the pool of the store the ids of the resources created by the
+	// entry, as a step of a scenario, are added to, read from SaveField of
+	// its JSON response.
+	Save      string
+	SaveField string
+}
+
+// ParseSpec reads a workload spec where every line has the form
+//
+//	weight [rate] METHOD path [body]
+//
+// where rate optionally caps how often the entry is sent, ex: 100/s or
+// 300/m. Lines of the form
+//
+//	setup|teardown METHOD path [body]
+//
+// are sent once, in order, before or after the test, to create and remove
+// its data. Blank lines and lines starting with # are ignored.
+func ParseSpec(r io.Reader) ([]Entry, error) {
+	var spec []Entry
+	var load bool
+	scanner := bufio.NewScanner(r)
+	var line int
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.SplitN(text, " ", 2)
+		if fields[0] == StageSetup || fields[0] == StageTeardown {
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("expected %v method and path; line = %v", fields[0], line)
+			}
+			e := Entry{Stage: fields[0]}
+			if err := e.parseRequest(fields[1]); err != nil {
+				return nil, fmt.Errorf("%v; line = %v", err, line)
+			}
+			spec = append(spec, e)
+			continue
+		}
+		weight, err := strconv.ParseUint(fields[0], 10, 32)
+		if err != nil || weight == 0 {
+			return nil, fmt.Errorf("weight must be a positive integer; line = %v", line)
+		}
+		e := Entry{Weight: uint(weight)}
+		if len(fields) == 2 {
+			// Methods never have slashes, so rates are told apart by them.
+			rate := strings.SplitN(fields[1], " ", 2)
+			if strings.Contains(rate[0], "/") && !strings.HasPrefix(rate[0], "/") {
+				count, per, err := boomer.ParseRate(rate[0])
+				if err != nil {
+					return nil, fmt.Errorf("%v; line = %v", err, line)
+				}
+				e.Interval = time.Duration(float64(per) / count)
+				fields = rate
+			}
+			fields = strings.SplitN(fields[len(fields)-1], " ", 3)
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("expected weight, method and path; line = %v", line)
+		}
+		if err := e.parseRequest(strings.Join(fields, " ")); err != nil {
+			return nil, fmt.Errorf("%v; line = %v", err, line)
+		}
+		spec = append(spec, e)
+		load = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !load {
+		return nil, fmt.Errorf("workload spec is empty")
+	}
+	return spec, nil
+}
+
+// Stage returns the requests of the entries of spec in stage, in order,
+// built using base as template.
+func Stage(spec []Entry, base *fasthttp.Request, stage string) []*fasthttp.Request {
+	var reqs []*fasthttp.Request
+	for _, e := range spec {
+		if e.Stage == stage {
+			w, uri := e.request(base)
+			w.Request.SetRequestURI(uri)
+			reqs = append(reqs, w.Request)
+		}
+	}
+	return reqs
+}
+
+// parseRequest parses the method, path and optional body of an entry.
+func (e *Entry) parseRequest(text string) error {
+	fields := strings.SplitN(text, " ", 3)
+	if len(fields) < 2 {
+		return fmt.Errorf("expected method and path")
+	}
+	e.Method = strings.ToUpper(fields[0])
+	e.Path = fields[1]
+	if !strings.HasPrefix(e.Path, "/") {
+		return fmt.Errorf("path must start with /")
+	}
+	if len(fields) == 3 {
+		e.Body = fields[2]
+	}
+	return nil
+}
+
+// HasID reports whether the path of e has an id placeholder.
+func (e Entry) HasID() bool {
+	return strings.Contains(e.Path, IDPlaceholder)
+}
+
+// request builds the request of e using base as template, along with the
+// absolute uri of its path, which is relative to the path of base.
+func (e Entry) request(base *fasthttp.Request) (*boomer.WeightedRequest, string) {
+	req := fasthttp.AcquireRequest()
+	base.CopyTo(req)
+	req.Header.SetMethod(e.Method)
+	req.SetBodyString(e.Body)
+	req.Header.SetContentLength(len(req.Body()))
+
+	prefix := strings.TrimSuffix(string(base.URI().Scheme())+"://"+string(base.URI().Host())+string(base.URI().Path()), "/")
+	uri := prefix + e.Path
+	if !e.HasID() {
+		req.SetRequestURI(uri)
+	}
+	return &boomer.WeightedRequest{
+		Request:      req,
+		Weight:       e.Weight,
+		Label:        e.Method + " " + e.Path,
+		RateInterval: e.Interval,
+		Budget:       e.Budget,
+		OnFailure:    e.OnFailure,
+		StepRetries:  e.Retries,
+		Branches:     e.Branches,
+	}, uri
+}
+
+func withID(uri, id string) string {
+	return strings.Replace(uri, IDPlaceholder, id, -1)
+}
@@ -0,0 +1,93 @@
+package workload
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+const spec = `
+# read heavy mix
+80 GET /items/{id}
+15 post /items {"title": "item"}
+5 DELETE /items/{id}
+`
+
+func TestParseSpec(t *testing.T) {
+	entries, err := ParseSpec(strings.NewReader(spec))
+	if err != nil {
+		t.Fatalf("A valid spec was not parsed correctly: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, found %d", len(entries))
+	}
+	if entries[0].Weight != 80 || entries[1].Weight != 15 || entries[2].Weight != 5 {
+		t.Errorf("Weights were not parsed correctly: %v", entries)
+	}
+	post := entries[1]
+	if post.Method != "POST" || post.Path != "/items" || post.Body != `{"title": "item"}` {
+		t.Errorf("POST was not parsed correctly: %v", post)
+	}
+	if !entries[0].HasID() || post.HasID() {
+		t.Errorf("Placeholders were not detected correctly")
+	}
+}
+
+func TestParseSpecRate(t *testing.T) {
+	entries, err := ParseSpec(strings.NewReader("10 100/s GET /search q=1\n1 300/m POST /checkout"))
+	if err != nil {
+		t.Fatalf("A spec with rates was not parsed correctly: %v", err)
+	}
+	search, checkout := entries[0], entries[1]
+	if search.Interval != 10*time.Millisecond || search.Method != "GET" || search.Path != "/search" || search.Body != "q=1" {
+		t.Errorf("Search was not parsed correctly: %v", search)
+	}
+	if checkout.Interval != 200*time.Millisecond || checkout.Method != "POST" || checkout.Path != "/checkout" {
+		t.Errorf("Checkout was not parsed correctly: %v", checkout)
+	}
+}
+
+func TestParseInvalidSpec(t *testing.T) {
+	for _, spec := range []string{"", "GET /items", "0 GET /items", "10 GET items", "x GET /items", "10 0/s GET /items", "10 100/s"} {
+		if _, err := ParseSpec(strings.NewReader(spec)); err == nil {
+			t.Errorf("An invalid spec passed parsing: %q", spec)
+		}
+	}
+}
+
+func TestParseSpecStages(t *testing.T) {
+	entries, err := ParseSpec(strings.NewReader("setup POST /users {\"name\": \"test\"}\n10 GET /users/test\nteardown delete /users/test"))
+	if err != nil {
+		t.Fatalf("A spec with stages was not parsed correctly: %v", err)
+	}
+	setup, teardown := entries[0], entries[2]
+	if setup.Stage != StageSetup || setup.Method != "POST" || setup.Path != "/users" || setup.Body != `{"name": "test"}` {
+		t.Errorf("Setup was not parsed correctly: %v", setup)
+	}
+	if teardown.Stage != StageTeardown || teardown.Method != "DELETE" || entries[1].Stage != "" {
+		t.Errorf("Stages were not parsed correctly: %v", entries)
+	}
+	for _, spec := range []string{"setup POST /users", "setup /users\n1 GET /", "teardown DELETE users\n1 GET /"} {
+		if _, err := ParseSpec(strings.NewReader(spec)); err == nil {
+			t.Errorf("An invalid spec passed parsing: %q", spec)
+		}
+	}
+}
+
+func TestEntryRequestBasePath(t *testing.T) {
+	for base, want := range map[string]string{
+		"http://example.com":      "http://example.com/items/1",
+		"http://example.com/":     "http://example.com/items/1",
+		"http://example.com/v1":   "http://example.com/v1/items/1",
+		"http://example.com/v1/?": "http://example.com/v1/items/1",
+	} {
+		req := fasthttp.AcquireRequest()
+		req.SetRequestURI(base)
+		w, uri := Entry{Weight: 1, Method: "GET", Path: "/items/1"}.request(req)
+		if uri != want || w.Request.URI().String() != want {
+			t.Errorf("Expected %v relative to %v to be %v, found %v", "/items/1", base, want, uri)
+		}
+	}
+}
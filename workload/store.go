@@ -0,0 +1,28 @@
+package workload
+
+import (
+	"sync"
+)
+
+// Store is a concurrency safe set of named pools, shared by every worker,
+// so ones can produce values others consume, ex: the ids of the resources
+// some create for others to delete.
+type Store struct {
+	lock  sync.Mutex
+	pools map[string]*Pool
+}
+
+// Pool returns the pool named name, empty the first time.
+func (s *Store) Pool(name string) *Pool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	p, ok := s.pools[name]
+	if !ok {
+		if s.pools == nil {
+			s.pools = make(map[string]*Pool)
+		}
+		p = &Pool{}
+		s.pools[name] = p
+	}
+	return p
+}
@@ -0,0 +1,67 @@
+package workload
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mercadolibre/pla/boomer"
+)
+
+// ParseTrace reads a CSV rate trace where every line has the form
+//
+//	timestamp,rps
+//
+// where timestamp is in seconds, ex: a unix timestamp, or RFC 3339, and is
+// taken relative to the first line. A header line and lines starting with
+// # are ignored.
+func ParseTrace(r io.Reader) ([]boomer.TracePoint, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+	var points []boomer.TracePoint
+	var first time.Time
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		rps, err := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+		if err != nil {
+			if line == 1 {
+				// Header.
+				continue
+			}
+			return nil, fmt.Errorf("rps must be a number; line = %v", line)
+		}
+		at, err := parseTimestamp(strings.TrimSpace(record[0]))
+		if err != nil {
+			return nil, fmt.Errorf("%v; line = %v", err, line)
+		}
+		if points == nil {
+			first = at
+		}
+		points = append(points, boomer.TracePoint{At: at.Sub(first), RPS: rps})
+	}
+	if err := boomer.ValidateTrace(points); err != nil {
+		return nil, err
+	}
+	return points, nil
+}
+
+func parseTimestamp(s string) (time.Time, error) {
+	if secs, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Unix(0, int64(secs*float64(time.Second))), nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("timestamp must be in seconds or RFC 3339; input = %v", s)
+}
@@ -0,0 +1,48 @@
+package workload
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const trace = `timestamp,rps
+# morning ramp
+1483228800,10
+1483228810,50
+1483228870,50
+`
+
+func TestParseTrace(t *testing.T) {
+	points, err := ParseTrace(strings.NewReader(trace))
+	if err != nil {
+		t.Fatalf("A valid trace was not parsed correctly: %v", err)
+	}
+	if len(points) != 3 {
+		t.Fatalf("Expected 3 points, found %d", len(points))
+	}
+	if points[0].At != 0 || points[1].At != 10*time.Second || points[2].At != 70*time.Second {
+		t.Errorf("Timestamps were not made relative to the first one: %v", points)
+	}
+	if points[0].RPS != 10 || points[2].RPS != 50 {
+		t.Errorf("Rates were not parsed correctly: %v", points)
+	}
+}
+
+func TestParseTraceRFC3339(t *testing.T) {
+	points, err := ParseTrace(strings.NewReader("2017-01-01T00:00:00Z,1\n2017-01-01T00:01:00Z,2"))
+	if err != nil {
+		t.Fatalf("A trace with RFC 3339 timestamps was not parsed correctly: %v", err)
+	}
+	if points[1].At != time.Minute {
+		t.Errorf("Expected the second point a minute later, found %v", points[1].At)
+	}
+}
+
+func TestParseInvalidTrace(t *testing.T) {
+	for _, trace := range []string{"", "0,10", "0,10\n0,20", "10,10\n0,20", "0,10\n5,-1", "0,10\n5,x", "0,10\nyesterday,5", "0,10,3\n5,5"} {
+		if _, err := ParseTrace(strings.NewReader(trace)); err == nil {
+			t.Errorf("An invalid trace passed parsing: %q", trace)
+		}
+	}
+}
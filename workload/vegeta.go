@@ -0,0 +1,127 @@
+package workload
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/mercadolibre/pla/boomer"
+	"github.com/valyala/fasthttp"
+)
+
+// Target is a request of a vegeta targets file.
+type Target struct {
+	Method string
+	URL    string
+	Header [][2]string
+
+	// BodyFile holds the body, it is read the first time the target is
+	// sent, but must be readable when building the requests.
+	BodyFile string
+}
+
+// ParseVegeta reads a targets file in vegeta's http format, where every
+// target is a line with the form
+//
+//	METHOD url
+//
+// followed by header lines, Name: value, and optionally by a line with
+// @path to a file holding its body. Lines starting with # are ignored.
+func ParseVegeta(r io.Reader) ([]Target, error) {
+	var targets []Target
+	scanner := bufio.NewScanner(r)
+	var line int
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		switch {
+		case text == "" || strings.HasPrefix(text, "#"):
+		case strings.HasPrefix(text, "@"):
+			if len(targets) == 0 || targets[len(targets)-1].BodyFile != "" {
+				return nil, fmt.Errorf("body without a target; line = %v", line)
+			}
+			targets[len(targets)-1].BodyFile = text[1:]
+		case len(targets) > 0 && targets[len(targets)-1].BodyFile == "" && isHeader(text):
+			parts := strings.SplitN(text, ":", 2)
+			t := &targets[len(targets)-1]
+			t.Header = append(t.Header, [2]string{strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])})
+		default:
+			fields := strings.Fields(text)
+			if len(fields) != 2 || !strings.Contains(fields[1], "://") {
+				return nil, fmt.Errorf("expected method and url; line = %v", line)
+			}
+			targets = append(targets, Target{Method: strings.ToUpper(fields[0]), URL: fields[1]})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("targets file is empty")
+	}
+	return targets, nil
+}
+
+// isHeader tells header lines apart from target ones, whose method has no
+// colon.
+func isHeader(text string) bool {
+	i := strings.Index(text, ":")
+	return i > 0 && !strings.Contains(text[:i], " ")
+}
+
+// Targets builds an evenly weighted request mix from targets, using base as
+// template. Every target must be on the host of base, where requests are
+// sent.
+func Targets(targets []Target, base *fasthttp.Request) ([]*boomer.WeightedRequest, error) {
+	var mix []*boomer.WeightedRequest
+	for _, t := range targets {
+		req := fasthttp.AcquireRequest()
+		base.CopyTo(req)
+		req.Header.SetMethod(t.Method)
+		req.SetRequestURI(t.URL)
+		if host := string(req.URI().Host()); host != string(base.URI().Host()) {
+			return nil, fmt.Errorf("target %v is not on host %s", t.URL, base.URI().Host())
+		}
+		for _, h := range t.Header {
+			req.Header.Set(h[0], h[1])
+		}
+		w := &boomer.WeightedRequest{
+			Request: req,
+			Weight:  1,
+			Label:   t.Method + " " + string(req.URI().Path()),
+		}
+		if t.BodyFile != "" {
+			// Fail now rather than on every request.
+			file, err := os.Open(t.BodyFile)
+			if err != nil {
+				return nil, fmt.Errorf("target %v: %v", t.URL, err)
+			}
+			file.Close()
+			w.Prepare = lazyBody(t.BodyFile)
+		}
+		mix = append(mix, w)
+	}
+	return mix, nil
+}
+
+// lazyBody sets the content of path as body, reading it once, the first
+// time it is needed.
+func lazyBody(path string) func(req *fasthttp.Request) error {
+	var once sync.Once
+	var body []byte
+	var err error
+	return func(req *fasthttp.Request) error {
+		once.Do(func() {
+			body, err = ioutil.ReadFile(path)
+		})
+		if err != nil {
+			return err
+		}
+		req.SetBody(body)
+		return nil
+	}
+}
@@ -0,0 +1,69 @@
+package workload
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+const targets = `# Items
+GET http://example.com/items?page=1
+X-Account-ID: 8675309
+
+POST http://example.com/items
+Content-Type: application/json
+@item.json
+DELETE http://example.com/items/1
+`
+
+func TestParseVegeta(t *testing.T) {
+	parsed, err := ParseVegeta(strings.NewReader(targets))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed) != 3 {
+		t.Fatalf("Expected 3 targets, found %d", len(parsed))
+	}
+	if parsed[0].Method != "GET" || parsed[0].URL != "http://example.com/items?page=1" || len(parsed[0].Header) != 1 || parsed[0].Header[0] != [2]string{"X-Account-ID", "8675309"} {
+		t.Errorf("The first target was not parsed correctly: %+v", parsed[0])
+	}
+	if parsed[1].BodyFile != "item.json" || len(parsed[1].Header) != 1 {
+		t.Errorf("The body of the second target was not parsed correctly: %+v", parsed[1])
+	}
+	if parsed[2].Method != "DELETE" || len(parsed[2].Header) != 0 {
+		t.Errorf("A target right after a body was not parsed correctly: %+v", parsed[2])
+	}
+	for _, input := range []string{"", "GET /items", "@body.json", "GET http://example.com/\n@a\n@b"} {
+		if _, err := ParseVegeta(strings.NewReader(input)); err == nil {
+			t.Errorf("Invalid targets passed parsing: %q", input)
+		}
+	}
+}
+
+func TestTargetsBodyFile(t *testing.T) {
+	base := fasthttp.AcquireRequest()
+	base.SetRequestURI("http://example.com/")
+	file, err := ioutil.TempFile("", "body")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString(`{"name": "item"}`)
+	file.Close()
+
+	mix, err := Targets([]Target{{Method: "POST", URL: "http://example.com/items", BodyFile: file.Name()}}, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := fasthttp.AcquireRequest()
+	if err := mix[0].Prepare(req); err != nil || string(req.Body()) != `{"name": "item"}` {
+		t.Errorf("Expected the body to be read from the file, found %q and %v", req.Body(), err)
+	}
+
+	if _, err := Targets([]Target{{Method: "POST", URL: "http://example.com/items", BodyFile: file.Name() + ".missing"}}, base); err == nil {
+		t.Errorf("Expected a missing body file to fail building the targets")
+	}
+}
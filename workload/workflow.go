@@ -0,0 +1,145 @@
+package workload
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+
+	"github.com/mercadolibre/pla/boomer"
+	"github.com/valyala/fasthttp"
+)
+
+// Pool is a concurrency safe set of resource ids.
+type Pool struct {
+	lock sync.Mutex
+	ids  []string
+}
+
+// Add adds id to the pool.
+func (p *Pool) Add(id string) {
+	p.lock.Lock()
+	p.ids = append(p.ids, id)
+	p.lock.Unlock()
+}
+
+// Get returns a random id, leaving it in the pool.
+func (p *Pool) Get() (string, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if len(p.ids) == 0 {
+		return "", false
+	}
+	return p.ids[rand.Intn(len(p.ids))], true
+}
+
+// Take removes a random id from the pool and returns it.
+func (p *Pool) Take() (string, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if len(p.ids) == 0 {
+		return "", false
+	}
+	i := rand.Intn(len(p.ids))
+	id := p.ids[i]
+	p.ids[i] = p.ids[len(p.ids)-1]
+	p.ids = p.ids[:len(p.ids)-1]
+	return id, true
+}
+
+// Len returns the amount of ids in the pool.
+func (p *Pool) Len() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return len(p.ids)
+}
+
+// Workflow builds a stateful request mix from spec. The ids of resources
+// created by POST requests without placeholders are captured into pool,
+// read from idField of their JSON response (dots separating nested fields)
+// or else from the last segment of their Location header. Requests with an
+// {id} placeholder are only sent once the pool has ids, and fail unsent if
+// it was drained meanwhile. DELETE requests remove the id they use from the
+// pool.
+func Workflow(spec []Entry, base *fasthttp.Request, pool *Pool, idField string) ([]*boomer.WeightedRequest, error) {
+	var mix []*boomer.WeightedRequest
+	var creates bool
+	for _, e := range spec {
+		if e.Stage != "" {
+			continue
+		}
+		w, uri := e.request(base)
+		switch {
+		case e.HasID():
+			get := pool.Get
+			if e.Method == "DELETE" {
+				get = pool.Take
+			}
+			w.Ready = func() bool {
+				return pool.Len() > 0
+			}
+			w.Prepare = func(req *fasthttp.Request) error {
+				// The pool may have been drained since this request was
+				// picked, without an id it would hit the collection.
+				id, ok := get()
+				if !ok {
+					return fmt.Errorf("pool of created ids is empty")
+				}
+				req.SetRequestURI(withID(uri, id))
+				return nil
+			}
+		case e.Method == "POST":
+			creates = true
+			w.Capture = captureID(pool, idField)
+		}
+		mix = append(mix, w)
+	}
+	if !creates {
+		return nil, fmt.Errorf("workflow needs a POST request without %v to create resources", IDPlaceholder)
+	}
+	return mix, nil
+}
+
+// captureID returns a capture adding the ids of the resources created by
+// successful requests to pool.
+func captureID(pool *Pool, field string) func(*fasthttp.Request, *fasthttp.Response) {
+	return func(req *fasthttp.Request, resp *fasthttp.Response) {
+		if code := resp.StatusCode(); code < 200 || code >= 300 {
+			return
+		}
+		if id := extractID(resp, field); id != "" {
+			pool.Add(id)
+		}
+	}
+}
+
+func extractID(resp *fasthttp.Response, field string) string {
+	if body, err := boomer.ResponseBody(resp); err == nil && len(body) > 0 {
+		dec := json.NewDecoder(bytes.NewReader(body))
+		dec.UseNumber()
+		var value interface{}
+		if dec.Decode(&value) == nil {
+			for _, key := range strings.Split(field, ".") {
+				obj, ok := value.(map[string]interface{})
+				if !ok {
+					value = nil
+					break
+				}
+				value = obj[key]
+			}
+			switch v := value.(type) {
+			case string:
+				return v
+			case json.Number:
+				return v.String()
+			}
+		}
+	}
+	location := strings.TrimRight(string(resp.Header.Peek("Location")), "/")
+	if i := strings.LastIndex(location, "/"); i >= 0 {
+		return location[i+1:]
+	}
+	return location
+}
@@ -0,0 +1,83 @@
+package workload
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mercadolibre/pla/boomer"
+	"github.com/valyala/fasthttp"
+)
+
+func TestWorkflow(t *testing.T) {
+	entries, _ := ParseSpec(strings.NewReader(spec))
+	base := fasthttp.AcquireRequest()
+	base.SetRequestURI("http://example.org/")
+	pool := &Pool{}
+	mix, err := Workflow(entries, base, pool, "data.id")
+	if err != nil {
+		t.Fatalf("Unexpected error building workflow: %v", err)
+	}
+	get, post, del := mix[0], mix[1], mix[2]
+	if get.Ready() || del.Ready() {
+		t.Errorf("Requests with ids should not be ready before resources are created")
+	}
+
+	resp := fasthttp.AcquireResponse()
+	resp.SetStatusCode(201)
+	resp.SetBodyString(`{"data": {"id": 42}}`)
+	post.Capture(post.Request, resp)
+	if !get.Ready() || pool.Len() != 1 {
+		t.Fatalf("Created id was not captured, pool has %d ids", pool.Len())
+	}
+
+	req := fasthttp.AcquireRequest()
+	get.Prepare(req)
+	if uri := req.URI().String(); uri != "http://example.org/items/42" {
+		t.Errorf("Expected GET to use the created id, found %v", uri)
+	}
+	del.Prepare(req)
+	if uri := req.URI().String(); uri != "http://example.org/items/42" {
+		t.Errorf("Expected DELETE to use the created id, found %v", uri)
+	}
+	if pool.Len() != 0 {
+		t.Errorf("Expected DELETE to remove the id from the pool")
+	}
+
+	// Requests picked before the pool was drained fail unsent.
+	for _, w := range []*boomer.WeightedRequest{get, del} {
+		req.SetRequestURI("http://example.org/")
+		if err := w.Prepare(req); err == nil {
+			t.Errorf("Expected an error preparing %s with an empty pool, found %v", w.Request.Header.Method(), req.URI())
+		}
+	}
+}
+
+func TestWorkflowWithoutCreate(t *testing.T) {
+	entries, _ := ParseSpec(strings.NewReader("1 GET /items/{id}"))
+	base := fasthttp.AcquireRequest()
+	base.SetRequestURI("http://example.org/")
+	if _, err := Workflow(entries, base, &Pool{}, "id"); err == nil {
+		t.Errorf("Expected an error for a workflow which creates no resources")
+	}
+}
+
+func TestExtractID(t *testing.T) {
+	resp := fasthttp.AcquireResponse()
+	resp.SetBodyString(`{"id": "abc"}`)
+	if id := extractID(resp, "id"); id != "abc" {
+		t.Errorf("Expected id abc, found %v", id)
+	}
+	resp.Reset()
+	resp.Header.Set("Location", "/items/7/")
+	if id := extractID(resp, "id"); id != "7" {
+		t.Errorf("Expected id from Location to be 7, found %v", id)
+	}
+}
+
+func TestStore(t *testing.T) {
+	var store Store
+	store.Pool("users").Add("1")
+	if store.Pool("users").Len() != 1 || store.Pool("orders").Len() != 0 {
+		t.Errorf("Pools of the store are not kept apart")
+	}
+}